@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/service"
+	"github.com/sergey/work-track-backend/internal/storage"
+)
+
+// backup takes a backup of the database and prunes the old ones, like the
+// scheduled backups of the server
+func backup(ctx context.Context, args []string) error {
+	flags := newFlagSet("backup", "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	store, err := backupStore(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close(db)
+
+	backupService := service.NewBackupService(repository.NewSchemaRepository(db), repository.NewUserRepository(db), store, cfg.Backup)
+	created, err := backupService.Backup(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stored %s (%d bytes) in %s\n", created.Key, created.Size, store)
+
+	return nil
+}
+
+// restore replaces the database file with a backup, or restores a missing
+// database file from the replica. Stop the server before restoring over an
+// existing database.
+func restore(ctx context.Context, args []string) error {
+	flags := newFlagSet("restore", "")
+	key := flags.String("key", "", "key of the backup to restore (default: the newest backup)")
+	list := flags.Bool("list", false, "list the stored backups instead of restoring")
+	force := flags.Bool("force", false, "replace an existing database file")
+	replica := flags.Bool("replica", false, "restore from the continuous replica instead of a backup")
+	at := flags.String("time", "", "with -replica, the RFC 3339 point in time to restore (default: the latest)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	store, err := backupStore(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *list:
+		backups, err := service.ListStoredBackups(ctx, store)
+		if err != nil {
+			return fmt.Errorf("failed to list backups: %w", err)
+		}
+		for _, backup := range backups {
+			fmt.Printf("%s\t%d\t%s\n", backup.Key, backup.Size, backup.CreatedAt.Format("2006-01-02 15:04:05Z"))
+		}
+
+	case *replica:
+		var target time.Time
+		if *at != "" {
+			if target, err = time.Parse(time.RFC3339, *at); err != nil {
+				return errors.New("invalid -time, use RFC 3339, e.g. 2024-01-20T10:00:00Z")
+			}
+		}
+		restoredAt, err := service.RestoreReplica(ctx, store, cfg.Backup.Passphrase, cfg.Database.Path, target)
+		if err != nil {
+			return fmt.Errorf("failed to restore replica: %w", err)
+		}
+		fmt.Printf("Restored the replica from %s to %s as of %s\n", store, cfg.Database.Path, restoredAt.Format(time.RFC3339))
+
+	default:
+		restored, err := service.RestoreBackup(ctx, store, cfg.Backup, *key, cfg.Database.Path, *force)
+		if err != nil {
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+		fmt.Printf("Restored %s from %s to %s\n", restored.Key, store, cfg.Database.Path)
+	}
+
+	return nil
+}
+
+// backupStore returns the store backups and the replica are kept in
+func backupStore(cfg *config.Config) (storage.Store, error) {
+	store, err := storage.New(cfg.S3, cfg.Backup.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up backup storage: %w", err)
+	}
+	if store == nil {
+		return nil, errors.New("backups are not configured, set S3_BUCKET or BACKUP_DIR")
+	}
+
+	return store, nil
+}
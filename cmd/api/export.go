@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/events"
+	"github.com/sergey/work-track-backend/internal/export"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// exportTimesheet writes the timesheet of a user for one month as an Excel
+// workbook, a PDF or the track items as CSV, like the export endpoints
+func exportTimesheet(ctx context.Context, args []string) error {
+	flags := newFlagSet("export", "")
+	login := flags.String("user", "", "login of the user (required)")
+	month := flags.String("month", "", "month to export as YYYY-MM (required)")
+	format := flags.String("format", "xlsx", "xlsx, pdf or csv")
+	tz := flags.String("tz", "", "IANA time zone of the dates (default: the user's)")
+	output := flags.String("o", "", `file to write, "-" for stdout (default: timesheet_<user>_<month>.<format>)`)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *login == "" || *month == "" {
+		flags.Usage()
+		return errors.New("-user and -month are required")
+	}
+	first, err := time.Parse("2006-01", *month)
+	if err != nil {
+		return errors.New("invalid -month, use YYYY-MM")
+	}
+	startDate, endDate := first.Format("2006-01-02"), first.AddDate(0, 1, -1).Format("2006-01-02")
+	if *format != "xlsx" && *format != "pdf" && *format != "csv" {
+		return fmt.Errorf("unknown format %q, use xlsx, pdf or csv", *format)
+	}
+
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close(db)
+
+	account, err := repository.NewUserRepository(db).FindByLogin(ctx, *login)
+	if err != nil {
+		return err
+	}
+	trackItemService := newTrackItemService(db, cfg)
+
+	buf := &bytes.Buffer{}
+	switch *format {
+	case "csv":
+		dr, err := trackItemService.ResolveDateRange(ctx, account.ID, startDate, endDate, *tz)
+		if err != nil {
+			return err
+		}
+		opts, err := export.NewCSVOptions("", "", "", "", false, dr.Location)
+		if err != nil {
+			return err
+		}
+		cw, err := export.NewCSVWriter(buf, opts)
+		if err != nil {
+			return err
+		}
+		err = trackItemService.StreamTrackItems(ctx, account.ID, dr, func(item *models.TrackItem) error {
+			return cw.Write(item)
+		})
+		if err != nil {
+			return err
+		}
+		if err := cw.Close(); err != nil {
+			return err
+		}
+
+	default:
+		timesheet, err := trackItemService.GetTimesheet(ctx, account.ID, startDate, endDate, *tz)
+		if err != nil {
+			return err
+		}
+		timesheets := []models.Timesheet{*timesheet}
+
+		if *format == "xlsx" {
+			xlsx, err := export.WriteTimesheetXLSX(timesheets)
+			if err != nil {
+				return err
+			}
+			buf = xlsx
+			break
+		}
+
+		opts := &export.PDFOptions{
+			GeneratedAt: time.Now(),
+			Avatars:     make(map[int]*export.Image),
+		}
+		if account.Avatar != "" {
+			// A missing avatar is replaced by initials rather than failing the export
+			if avatar, err := export.NewAvatarLoader(cfg.Avatar.Hosts).Load(ctx, account.Avatar); err != nil {
+				slog.Warn("PDF export: avatar skipped", "err", err)
+			} else {
+				opts.Avatars[account.ID] = avatar
+			}
+		}
+		if err := export.WriteTimesheetPDF(buf, timesheets, opts); err != nil {
+			return err
+		}
+	}
+
+	if *output == "-" {
+		_, err := buf.WriteTo(os.Stdout)
+		return err
+	}
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("timesheet_%s_%s.%s", account.Login, *month, *format)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+
+	return nil
+}
+
+// newTrackItemService creates the track item service the way the server
+// does, so changes made by commands queue webhook deliveries as well, which
+// the server sends
+func newTrackItemService(db *sql.DB, cfg *config.Config) *service.TrackItemService {
+	userRepo := repository.NewUserRepository(db)
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(db), userRepo, service.NewWebhookClient(cfg.Webhook), cfg.Webhook)
+	eventService := service.NewEventService(webhookService, events.NewHub(1000), userRepo)
+
+	return service.NewTrackItemService(repository.NewTrackItemRepository(db), userRepo, eventService)
+}
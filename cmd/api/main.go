@@ -1,118 +1,124 @@
+// Command api runs the WorkTrack server and the tools that administer its
+// database. Without a command it serves, so existing deployments keep
+// working.
 package main
 
 import (
 	"context"
-	"log"
-	"net/http"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
-	"time"
+	_ "time/tzdata" // Embed the time zone database, the Alpine image has none
 
-	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
 	"github.com/sergey/work-track-backend/internal/config"
 	"github.com/sergey/work-track-backend/internal/database"
-	"github.com/sergey/work-track-backend/internal/handler"
-	"github.com/sergey/work-track-backend/internal/middleware"
-	"github.com/sergey/work-track-backend/internal/repository"
-	"github.com/sergey/work-track-backend/internal/service"
 )
 
+// commands maps the command names to the functions running them
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"serve":   serve,
+	"migrate": migrate,
+	"user":    user,
+	"backup":  backup,
+	"restore": restore,
+	"export":  exportTimesheet,
+	"seed":    seed,
+}
+
+const usage = `Usage: api [command] [flags]
+
+Commands:
+  serve                                  Run the HTTP server (the default)
+  migrate up|down [N]|status|force V     Apply, revert or inspect database migrations
+  user create|reset-password|set-role|disable|enable
+                                         Manage user accounts
+  backup                                 Take a database backup
+  restore                                Restore a backup or the replica
+  export -user LOGIN -month YYYY-MM      Export a user's timesheet
+  seed -users N -months M                Generate fake users and track items
+
+Run "api <command> -h" for the flags of a command.
+`
+
 func main() {
 	// Load .env file if it exists (for local development)
 	_ = godotenv.Load()
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		fmt.Print(usage)
+		return
+	}
+	run, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
 	}
 
-	// Connect to database
-	db, err := database.NewSQLiteDB(cfg.Database.ConnectionString())
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := run(ctx, args)
+	stop()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
-	defer database.Close(db)
-
-	log.Println("Successfully connected to database")
-
-	// Initialize repositories
-	userRepo := repository.NewUserRepository(db)
-	trackItemRepo := repository.NewTrackItemRepository(db)
-
-	// Initialize services
-	authService := service.NewAuthService(userRepo, cfg.JWT.Secret)
-	trackItemService := service.NewTrackItemService(trackItemRepo)
-
-	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authService)
-	trackItemHandler := handler.NewTrackItemHandler(trackItemService)
-
-	// Setup router
-	r := chi.NewRouter()
-
-	// Global middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.CORS(cfg.CORS.AllowedOrigins))
-
-	// Health check endpoint
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
-
-	// API routes
-	r.Route("/api", func(r chi.Router) {
-		// Auth routes (public)
-		r.Route("/auth", func(r chi.Router) {
-			r.Post("/register", authHandler.Register)
-			r.Post("/login", authHandler.Login)
-		})
-
-		// Track item routes (protected)
-		r.Route("/track-items", func(r chi.Router) {
-			r.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
-			r.Get("/", trackItemHandler.ListTrackItems)
-			r.Post("/", trackItemHandler.CreateTrackItem)
-			r.Get("/{id}", trackItemHandler.GetTrackItem)
-			r.Put("/{id}", trackItemHandler.UpdateTrackItem)
-			r.Delete("/{id}", trackItemHandler.DeleteTrackItem)
-		})
-	})
+}
 
-	// Create HTTP server
-	srv := &http.Server{
-		Addr:         ":" + cfg.Server.Port,
-		Handler:      r,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+// newFlagSet creates the flag set of a command. Parse errors are returned
+// instead of exiting, after the usage has been printed.
+func newFlagSet(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), strings.TrimSpace("Usage: api "+name+" [flags] "+args))
+		flags.PrintDefaults()
 	}
+	return flags
+}
 
-	// Start server in a goroutine
-	go func() {
-		log.Printf("Server starting on port %s (environment: %s)", cfg.Server.Port, cfg.Server.Env)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
-		}
-	}()
+// loadConfig reads the configuration and sets up logging. Only the server
+// needs a JWT secret.
+func loadConfig(requireJWT bool) (*config.Config, error) {
+	load := config.LoadWithoutJWT
+	if requireJWT {
+		load = config.Load
+	}
+	cfg, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	slog.SetDefault(newLogger(cfg.Log))
 
-	// Wait for interrupt signal for graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	return cfg, nil
+}
 
-	log.Println("Server is shutting down...")
+// openDatabase connects to the configured database
+func openDatabase(cfg *config.Config) (*sql.DB, error) {
+	db, err := database.NewSQLiteDB(cfg.Database.ConnectionString())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
 
-	// Graceful shutdown with timeout
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer shutdownCancel()
+	return db, nil
+}
 
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+// newLogger creates the logger described by cfg
+func newLogger(cfg config.LogConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	if cfg.Format == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
 	}
-
-	log.Println("Server exited properly")
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
 }
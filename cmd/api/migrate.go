@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// migrate applies, reverts or reports the migrations in the migrations
+// directory
+func migrate(ctx context.Context, args []string) error {
+	flags := newFlagSet("migrate", "up | down [N] | status | force VERSION")
+	dir := flags.String("dir", "migrations", "directory holding the migration scripts")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("missing migrate command")
+	}
+
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	migrations, err := database.LoadMigrations(*dir)
+	if err != nil {
+		return err
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close(db)
+
+	migrationService := service.NewMigrationService(repository.NewSchemaRepository(db), migrations)
+
+	switch command := flags.Arg(0); command {
+	case "up":
+		applied, err := migrationService.Up(ctx)
+		for _, migration := range applied {
+			fmt.Println("Applied", migration.String())
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			if steps, err = strconv.Atoi(flags.Arg(1)); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", flags.Arg(1))
+			}
+		}
+		reverted, err := migrationService.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Println("Reverted", migration.String())
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+		return nil
+
+	case "status":
+		version, dirty, err := migrationService.Version(ctx)
+		if err != nil {
+			return err
+		}
+		state := ""
+		if dirty {
+			state = " (dirty)"
+		}
+		fmt.Printf("Schema version %d%s, the server requires %d\n", version, state, database.SchemaVersion)
+		for _, migration := range migrationService.Migrations() {
+			mark := " "
+			if migration.Version <= version {
+				mark = "x"
+			}
+			fmt.Printf("[%s] %s\n", mark, migration.String())
+		}
+		return nil
+
+	case "force":
+		if flags.NArg() < 2 {
+			return errors.New("missing version to force")
+		}
+		version, err := strconv.Atoi(flags.Arg(1))
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", flags.Arg(1))
+		}
+		if err := migrationService.Force(ctx, version); err != nil {
+			return err
+		}
+		fmt.Println("Schema version set to", version)
+		return nil
+
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+}
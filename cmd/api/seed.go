@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+var (
+	seedFirstNames = []string{"Anna", "Olga", "Maria", "Elena", "Irina", "Natalia", "Svetlana", "Tatiana", "Ivan", "Sergey", "Dmitry", "Alexei", "Pavel", "Nikolai", "Laura", "James", "Sofia", "Daniel"}
+	seedLastNames  = []string{"Ivanova", "Petrova", "Smirnova", "Kuznetsova", "Popova", "Sokolova", "Volkov", "Morozov", "Novikov", "Fedorov", "Miller", "Garcia", "Schmidt", "Rossi", "Dubois", "Jensen"}
+	seedTimeZones  = []string{"UTC", "Europe/Moscow", "Europe/Berlin", "Europe/London", "America/New_York"}
+)
+
+// seed generates users with track items over the past months, for
+// development and load testing. Everything is created through the services,
+// as if the users had entered it.
+func seed(ctx context.Context, args []string) error {
+	flags := newFlagSet("seed", "")
+	users := flags.Int("users", 10, "number of users to create")
+	months := flags.Int("months", 3, "number of months of track items, up to today")
+	password := flags.String("password", "password", "password of the created users")
+	randSeed := flags.Uint64("seed", 1, "seed of the random generator, the same seed gives the same data")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *users < 1 || *months < 1 {
+		return errors.New("-users and -months must be at least 1")
+	}
+
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close(db)
+
+	userAdminService := service.NewUserAdminService(repository.NewUserRepository(db), repository.NewDataExportRepository(db), repository.NewAuditRepository(db))
+	trackItemService := newTrackItemService(db, cfg)
+	rng := rand.New(rand.NewPCG(*randSeed, *randSeed))
+
+	created, items := 0, 0
+	for n := 1; created < *users; n++ {
+		first := seedFirstNames[rng.IntN(len(seedFirstNames))]
+		last := seedLastNames[rng.IntN(len(seedLastNames))]
+		role := models.RoleUser
+		if created%10 == 9 {
+			role = models.RoleSupervisor
+		}
+
+		user, err := userAdminService.CreateUser(ctx, nil, &models.UserRegistration{
+			FirstName: first,
+			LastName:  last,
+			Login:     fmt.Sprintf("%s.%s%d", strings.ToLower(first), strings.ToLower(last), n),
+			Password:  *password,
+			TimeZone:  seedTimeZones[rng.IntN(len(seedTimeZones))],
+		}, role)
+		if errors.Is(err, service.ErrEmailAlreadyExists) {
+			// Left by an earlier run
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		created++
+
+		count, err := seedTrackItems(ctx, trackItemService, user, *months, rng)
+		if err != nil {
+			return fmt.Errorf("failed to create track items of %s: %w", user.Login, err)
+		}
+		items += count
+		fmt.Printf("Created %s with %d track items\n", user.Login, count)
+	}
+	fmt.Printf("Created %d users with %d track items, their password is %q\n", created, items, *password)
+
+	return nil
+}
+
+// seedTrackItems creates a shift schedule for user over the past months: day
+// and night shifts on about five days a week, with the odd overtime shift and
+// emergency call
+func seedTrackItems(ctx context.Context, trackItemService *service.TrackItemService, user *models.User, months int, rng *rand.Rand) (int, error) {
+	loc, err := time.LoadLocation(user.TimeZone)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc).AddDate(0, 1-months, 0)
+
+	var ops []models.BatchOperation
+	for day := first; day.Before(today); day = day.AddDate(0, 0, 1) {
+		if rng.IntN(7) >= 5 {
+			continue
+		}
+
+		item := &models.CreateTrackItemRequest{
+			Type:          "regular",
+			WorkingHours:  8,
+			WorkingShifts: 1,
+			Date:          day.Add(8 * time.Hour).Format(time.RFC3339),
+		}
+		switch r := rng.IntN(20); {
+		case r < 5:
+			item.Type = "night"
+			item.WorkingHours = 12
+			item.Date = day.Add(20 * time.Hour).Format(time.RFC3339)
+		case r < 7:
+			item.Type = "overtime"
+			item.WorkingHours = float64(2 + rng.IntN(5))
+			item.WorkingShifts = 0.5
+			item.Date = day.Add(16 * time.Hour).Format(time.RFC3339)
+		}
+		item.EmergencyCall = rng.IntN(25) == 0
+		item.HolidayCall = day.Weekday() == time.Sunday && rng.IntN(4) == 0
+		ops = append(ops, models.BatchOperation{Op: models.BatchOpCreate, Item: item})
+	}
+
+	for start := 0; start < len(ops); start += service.MaxBatchOperations {
+		end := min(start+service.MaxBatchOperations, len(ops))
+		_, err := trackItemService.BatchTrackItems(ctx, user.ID, &models.BatchTrackItemsRequest{
+			Mode:       models.BatchModeAtomic,
+			Operations: ops[start:end],
+		})
+		if err != nil {
+			return start, err
+		}
+	}
+
+	return len(ops), nil
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/events"
+	"github.com/sergey/work-track-backend/internal/export"
+	"github.com/sergey/work-track-backend/internal/handler"
+	"github.com/sergey/work-track-backend/internal/metrics"
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/service"
+	"github.com/sergey/work-track-backend/internal/storage"
+	"github.com/sergey/work-track-backend/internal/tracing"
+)
+
+// serve runs the HTTP server until ctx is cancelled
+func serve(ctx context.Context, args []string) error {
+	flags := newFlagSet("serve", "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := loadConfig(true)
+	if err != nil {
+		return err
+	}
+
+	// Set up tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	// Backups and the replica go to S3 when a bucket is configured, otherwise
+	// to BACKUP_DIR
+	backupStore, err := storage.New(cfg.S3, cfg.Backup.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to set up backup storage: %w", err)
+	}
+	replicating := backupStore != nil && cfg.Replication.Interval > 0
+
+	// Restore a missing database file from the replica
+	if _, err := os.Stat(cfg.Database.Path); replicating && errors.Is(err, fs.ErrNotExist) {
+		restoredAt, err := service.RestoreReplica(context.Background(), backupStore, cfg.Backup.Passphrase, cfg.Database.Path, cfg.Replication.RestoreTime)
+		switch {
+		case errors.Is(err, service.ErrReplicaNotFound) && cfg.Replication.RestoreTime.IsZero():
+			slog.Info("No replica to restore, starting with a new database")
+		case err != nil:
+			return fmt.Errorf("failed to restore database from replica: %w", err)
+		default:
+			slog.Info("Restored database from replica", "restored_to", restoredAt, "store", backupStore.String())
+		}
+	}
+
+	// Connect to database
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close(db)
+
+	slog.Info("Successfully connected to database")
+
+	// Initialize repositories
+	userRepo := repository.NewUserRepository(db)
+	trackItemRepo := repository.NewTrackItemRepository(db)
+	shiftTemplateRepo := repository.NewShiftTemplateRepository(db)
+	calendarTokenRepo := repository.NewCalendarTokenRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	approvalRepo := repository.NewTimesheetApprovalRepository(db)
+	timerRepo := repository.NewTimerRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	schemaRepo := repository.NewSchemaRepository(db)
+	replicationRepo := repository.NewReplicationRepository(db)
+
+	// Initialize services
+	avatars := export.NewAvatarLoader(cfg.Avatar.Hosts)
+	authService := service.NewAuthService(userRepo, cfg.JWT.Secret)
+	userService := service.NewUserService(userRepo)
+	webhookService := service.NewWebhookService(webhookRepo, userRepo, service.NewWebhookClient(cfg.Webhook), cfg.Webhook)
+	eventHub := events.NewHub(1000)
+	eventService := service.NewEventService(webhookService, eventHub, userRepo)
+	trackItemService := service.NewTrackItemService(trackItemRepo, userRepo, eventService)
+	shiftTemplateService := service.NewShiftTemplateService(shiftTemplateRepo, trackItemRepo, userRepo, eventService)
+	approvalService := service.NewTimesheetApprovalService(approvalRepo, trackItemRepo, userRepo, eventService)
+	timerService := service.NewTimerService(timerRepo, trackItemRepo, eventService)
+	calendarService := service.NewCalendarService(calendarTokenRepo, trackItemRepo, userRepo)
+	accountService := service.NewAccountService(userRepo, trackItemRepo, shiftTemplateRepo, auditRepo, dataExportRepo, avatars, cfg.Account)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, cfg.Idempotency)
+	healthService := service.NewHealthService(schemaRepo, cfg.Database.Path, backupStore, cfg.Account.ExportDir, cfg.Health)
+	userAdminService := service.NewUserAdminService(userRepo, dataExportRepo, auditRepo)
+	impersonationService := service.NewImpersonationService(userRepo, auditRepo, cfg.JWT.Secret, cfg.JWT.ImpersonationTTL)
+	backupService := service.NewBackupService(schemaRepo, userRepo, backupStore, cfg.Backup)
+	replicationService := service.NewReplicationService(replicationRepo, cfg.Database.Path, backupStore, cfg.Backup.Passphrase, cfg.Replication)
+
+	// Initialize handlers
+	authHandler := handler.NewAuthHandler(authService)
+	userHandler := handler.NewUserHandler(userService)
+	trackItemHandler := handler.NewTrackItemHandler(trackItemService)
+	shiftTemplateHandler := handler.NewShiftTemplateHandler(shiftTemplateService)
+	approvalHandler := handler.NewTimesheetApprovalHandler(approvalService)
+	timerHandler := handler.NewTimerHandler(timerService)
+	exportHandler := handler.NewExportHandler(trackItemService, avatars)
+	calendarHandler := handler.NewCalendarHandler(calendarService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	eventHandler := handler.NewEventHandler(eventService)
+	idempotency := handler.NewIdempotencyMiddleware(idempotencyService)
+	healthHandler := handler.NewHealthHandler(healthService)
+	backupHandler := handler.NewBackupHandler(backupService)
+	userAdminHandler := handler.NewUserAdminHandler(userAdminService)
+	impersonationHandler := handler.NewImpersonationHandler(impersonationService)
+
+	// Setup router
+	r := chi.NewRouter()
+
+	// Global middleware
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Tracing)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Metrics)
+	r.Use(middleware.CORS(cfg.CORS.AllowedOrigins))
+	r.NotFound(middleware.NotFound)
+	r.MethodNotAllowed(middleware.MethodNotAllowed)
+
+	// Health check endpoints. /health is kept for existing probes and only
+	// reports liveness.
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+	r.Get("/livez", healthHandler.Livez)
+	r.Get("/readyz", healthHandler.Readyz)
+
+	// Metrics are served on their own address when one is configured, so they
+	// can be kept off the public network, otherwise behind basic auth
+	metricsHandler := metrics.Handler(metrics.NewRegistry(db))
+	var metricsSrv *http.Server
+	switch {
+	case cfg.Metrics.Addr != "":
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metricsHandler)
+		metricsSrv = &http.Server{
+			Addr:        cfg.Metrics.Addr,
+			Handler:     mux,
+			ReadTimeout: 15 * time.Second,
+			ErrorLog:    slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		}
+	case cfg.Metrics.Password != "":
+		r.With(middleware.BasicAuth("metrics", cfg.Metrics.Username, cfg.Metrics.Password)).Handle("/metrics", metricsHandler)
+	default:
+		slog.Info("Metrics endpoint disabled, set METRICS_ADDR or METRICS_PASSWORD to enable it")
+	}
+
+	// Authentication of the protected routes, which also rejects the tokens of
+	// disabled and deleted accounts and audits impersonated requests
+	auth := middleware.AuthMiddleware(cfg.JWT.Secret, userService, impersonationService)
+
+	// API routes
+	r.Route("/api", func(r chi.Router) {
+		// Auth routes (public)
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/register", authHandler.Register)
+			r.Post("/login", authHandler.Login)
+			r.Post("/password", authHandler.ChangePassword)
+		})
+
+		// Profile routes (protected)
+		r.Route("/me", func(r chi.Router) {
+			r.Use(auth)
+			r.Use(idempotency.Handler)
+			r.Get("/", userHandler.GetProfile)
+			r.Put("/", userHandler.UpdateProfile)
+			r.Delete("/", accountHandler.DeleteAccount)
+			r.Delete("/deletion", accountHandler.CancelDeletion)
+			r.Post("/export", accountHandler.RequestExport)
+			r.Get("/export/{id}", accountHandler.GetExport)
+			r.Get("/calendar-token", calendarHandler.GetToken)
+			r.Post("/calendar-token", calendarHandler.RegenerateToken)
+			r.Delete("/calendar-token", calendarHandler.RevokeToken)
+		})
+
+		// Calendar feed (public, authenticated by the secret token in the URL)
+		r.Get("/calendar/{token}.ics", calendarHandler.Feed)
+
+		// Live event stream (protected)
+		r.With(auth).Get("/events", eventHandler.Stream)
+
+		// Data export download (public, authenticated by the secret token in the URL)
+		r.Get("/exports/{token}.zip", accountHandler.DownloadExport)
+
+		// Track item routes (protected)
+		r.Route("/track-items", func(r chi.Router) {
+			r.Use(auth)
+			r.Use(idempotency.Handler)
+			r.Get("/", trackItemHandler.ListTrackItems)
+			r.Post("/", trackItemHandler.CreateTrackItem)
+			r.Post("/batch", trackItemHandler.BatchTrackItems)
+			r.Post("/copy", trackItemHandler.CopyTrackItems)
+			r.Post("/import", trackItemHandler.ImportTrackItems)
+			r.Get("/summary", exportHandler.Summary)
+			r.Get("/export.csv", exportHandler.ExportCSV)
+			r.Get("/export.xlsx", exportHandler.ExportXLSX)
+			r.Get("/export.pdf", exportHandler.ExportPDF)
+			r.Get("/{id}", trackItemHandler.GetTrackItem)
+			r.Put("/{id}", trackItemHandler.UpdateTrackItem)
+			r.Delete("/{id}", trackItemHandler.DeleteTrackItem)
+		})
+
+		// Offline sync routes (protected)
+		r.Route("/sync", func(r chi.Router) {
+			r.Use(auth)
+			r.Use(idempotency.Handler)
+			r.Get("/", trackItemHandler.GetSyncChanges)
+			r.Post("/", trackItemHandler.PushSyncChanges)
+		})
+
+		// Timer routes (protected)
+		r.Route("/timer", func(r chi.Router) {
+			r.Use(auth)
+			r.Use(idempotency.Handler)
+			r.Get("/", timerHandler.GetTimer)
+			r.Post("/", timerHandler.StartTimer)
+			r.Post("/stop", timerHandler.StopTimer)
+		})
+
+		// Shift template routes (protected)
+		r.Route("/shift-templates", func(r chi.Router) {
+			r.Use(auth)
+			r.Use(idempotency.Handler)
+			r.Get("/", shiftTemplateHandler.ListTemplates)
+			r.Post("/", shiftTemplateHandler.CreateTemplate)
+			r.Get("/{id}", shiftTemplateHandler.GetTemplate)
+			r.Put("/{id}", shiftTemplateHandler.UpdateTemplate)
+			r.Delete("/{id}", shiftTemplateHandler.DeleteTemplate)
+			r.Post("/{id}/apply", shiftTemplateHandler.ApplyTemplate)
+		})
+
+		// Timesheet approval routes (protected)
+		r.Route("/timesheet-approvals", func(r chi.Router) {
+			r.Use(auth)
+			r.Use(idempotency.Handler)
+			r.Get("/", approvalHandler.ListApprovals)
+			r.Post("/", approvalHandler.ApproveTimesheet)
+		})
+
+		// Webhook routes (protected)
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(auth)
+			r.Use(idempotency.Handler)
+			r.Get("/", webhookHandler.ListWebhooks)
+			r.Post("/", webhookHandler.CreateWebhook)
+			r.Get("/{id}", webhookHandler.GetWebhook)
+			r.Put("/{id}", webhookHandler.UpdateWebhook)
+			r.Delete("/{id}", webhookHandler.DeleteWebhook)
+			r.Get("/{id}/deliveries", webhookHandler.ListDeliveries)
+			r.Post("/{id}/deliveries/{deliveryID}/retry", webhookHandler.RetryDelivery)
+		})
+
+		// Admin routes (protected)
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(auth)
+
+			r.Get("/backups", backupHandler.ListBackups)
+			r.Post("/backups", backupHandler.CreateBackup)
+
+			r.Get("/users", userAdminHandler.ListUsers)
+			r.Get("/users/{id}", userAdminHandler.GetUser)
+			r.Delete("/users/{id}", userAdminHandler.DeleteUser)
+			r.Post("/users/{id}/disable", userAdminHandler.DisableUser)
+			r.Post("/users/{id}/enable", userAdminHandler.EnableUser)
+			r.Post("/users/{id}/password-reset", userAdminHandler.ResetPassword)
+			r.Put("/users/{id}/role", userAdminHandler.SetRole)
+			r.Post("/users/{id}/impersonate", impersonationHandler.Impersonate)
+		})
+	})
+
+	// Create HTTP server
+	srv := &http.Server{
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      r,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+		ErrorLog:     slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	// Event streams never go idle; end them so Shutdown does not wait for them
+	srv.RegisterOnShutdown(eventHub.Close)
+
+	// Clean up expired exports and delete accounts whose grace period has ended,
+	// send queued webhook deliveries, purge expired idempotency keys, take
+	// scheduled backups and replicate the write-ahead log
+	maintenanceCtx, stopMaintenance := context.WithCancel(context.Background())
+	defer stopMaintenance()
+	go accountService.Run(maintenanceCtx)
+	go webhookService.Run(maintenanceCtx)
+	go idempotencyService.Run(maintenanceCtx)
+	go backupService.Run(maintenanceCtx)
+	go replicationService.Run(maintenanceCtx)
+
+	// Start server in a goroutine
+	go func() {
+		slog.Info("Server starting", "port", cfg.Server.Port, "environment", cfg.Server.Env)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Server failed to start", "err", err)
+			os.Exit(1)
+		}
+	}()
+	if metricsSrv != nil {
+		go func() {
+			slog.Info("Metrics server starting", "addr", cfg.Metrics.Addr)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("Metrics server failed to start", "err", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Wait for interrupt signal for graceful shutdown
+	<-ctx.Done()
+
+	slog.Info("Server is shutting down")
+
+	// Fail readiness first, so load balancers stop sending requests before
+	// the listener closes
+	healthService.Drain()
+	if cfg.Health.DrainDelay > 0 {
+		slog.Info("Draining traffic", "delay", cfg.Health.DrainDelay)
+		time.Sleep(cfg.Health.DrainDelay)
+	}
+
+	// Graceful shutdown with timeout
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(shutdownCtx)
+	}
+
+	// Let exports that are being built finish writing and replicate the last
+	// changes
+	stopMaintenance()
+	accountService.Wait()
+	replicationService.Wait()
+
+	// Send the spans that are still buffered
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "err", err)
+	}
+
+	slog.Info("Server exited properly")
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/service"
+	"golang.org/x/term"
+)
+
+const userUsage = `Usage: api user <command> [flags] LOGIN
+
+Commands:
+  create LOGIN              Create an account, the password is read from the terminal or stdin
+  reset-password LOGIN      Set a new password, read from the terminal or stdin
+  set-role LOGIN ROLE       Change the role to user, supervisor or admin
+  disable LOGIN             Disable the account, it can no longer log in
+  enable LOGIN              Enable a disabled account again
+`
+
+// user manages user accounts. Changes are written to the audit log without
+// an actor.
+func user(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" {
+		fmt.Fprint(os.Stderr, userUsage)
+		if len(args) == 0 {
+			return errors.New("missing user command")
+		}
+		return nil
+	}
+	command, args := args[0], args[1:]
+
+	flags := newFlagSet("user "+command, "LOGIN")
+	var req models.UserRegistration
+	role := models.RoleUser
+	switch command {
+	case "create":
+		flags.StringVar(&req.FirstName, "first-name", "", "first name (required)")
+		flags.StringVar(&req.LastName, "last-name", "", "last name (required)")
+		flags.StringVar(&req.TimeZone, "time-zone", "UTC", "IANA time zone, e.g. Europe/Moscow")
+		flags.StringVar(&role, "role", models.RoleUser, "user, supervisor or admin")
+	case "set-role":
+		flags = newFlagSet("user set-role", "LOGIN ROLE")
+	case "reset-password", "disable", "enable":
+	default:
+		fmt.Fprint(os.Stderr, userUsage)
+		return fmt.Errorf("unknown user command %q", command)
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	want := 1
+	if command == "set-role" {
+		want = 2
+	}
+	if flags.NArg() != want {
+		flags.Usage()
+		return errors.New("wrong number of arguments")
+	}
+	login := flags.Arg(0)
+
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close(db)
+
+	userAdminService := service.NewUserAdminService(repository.NewUserRepository(db), repository.NewDataExportRepository(db), repository.NewAuditRepository(db))
+
+	if command == "create" {
+		req.Login = login
+		if req.Password, err = readPassword(); err != nil {
+			return err
+		}
+		created, err := userAdminService.CreateUser(ctx, nil, &req, role)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created user %s with id %d and role %s\n", created.Login, created.ID, created.Role)
+		return nil
+	}
+
+	account, err := userAdminService.GetUserByLogin(ctx, nil, login)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "reset-password":
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		if _, err := userAdminService.ResetPassword(ctx, nil, account.ID, password); err != nil {
+			return err
+		}
+		fmt.Printf("Reset the password of %s\n", account.Login)
+
+	case "set-role":
+		if _, err := userAdminService.SetRole(ctx, nil, account.ID, flags.Arg(1)); err != nil {
+			return err
+		}
+		fmt.Printf("Set the role of %s to %s\n", account.Login, flags.Arg(1))
+
+	case "disable":
+		if _, err := userAdminService.SetDisabled(ctx, nil, account.ID, true); err != nil {
+			return err
+		}
+		fmt.Printf("Disabled %s\n", account.Login)
+
+	case "enable":
+		if _, err := userAdminService.SetDisabled(ctx, nil, account.ID, false); err != nil {
+			return err
+		}
+		fmt.Printf("Enabled %s\n", account.Login)
+	}
+
+	return nil
+}
+
+// readPassword prompts for a password twice on a terminal, or reads the
+// first line of stdin when it is not a terminal
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	repeated, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if string(password) != string(repeated) {
+		return "", errors.New("passwords do not match")
+	}
+
+	return string(password), nil
+}
@@ -0,0 +1,48 @@
+// Command normalize-dates rewrites track item dates stored with a client
+// offset (e.g. "2024-01-20 23:30:00+03:00") into UTC, so that date range
+// queries compare instants correctly. It is safe to run more than once.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+func main() {
+	// Load .env file if it exists (for local development)
+	_ = godotenv.Load()
+
+	defaultPath := os.Getenv("DB_PATH")
+	if defaultPath == "" {
+		defaultPath = "./worktrack.db"
+	}
+
+	dbPath := flag.String("db", defaultPath, "SQLite database file path")
+	dryRun := flag.Bool("dry-run", false, "only report how many rows would change")
+	flag.Parse()
+
+	db, err := database.NewSQLiteDB(*dbPath)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close(db)
+
+	trackItemRepo := repository.NewTrackItemRepository(db)
+
+	count, err := trackItemRepo.NormalizeDates(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatalf("Failed to normalize dates: %v", err)
+	}
+
+	if *dryRun {
+		log.Printf("%d track item dates would be normalized to UTC", count)
+		return
+	}
+	log.Printf("Normalized %d track item dates to UTC", count)
+}
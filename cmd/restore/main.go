@@ -0,0 +1,64 @@
+// Command restore replaces the SQLite database with a backup taken by the
+// server, from the S3 bucket or the BACKUP_DIR directory it is configured
+// with. Stop the server before restoring over an existing database.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/service"
+	"github.com/sergey/work-track-backend/internal/storage"
+)
+
+func main() {
+	// Load .env file if it exists (for local development)
+	_ = godotenv.Load()
+
+	defaultPath := os.Getenv("DB_PATH")
+	if defaultPath == "" {
+		defaultPath = "./worktrack.db"
+	}
+
+	dbPath := flag.String("db", defaultPath, "SQLite database file path to restore to")
+	key := flag.String("key", "", "key of the backup to restore (default: the newest backup)")
+	list := flag.Bool("list", false, "list the stored backups instead of restoring")
+	force := flag.Bool("force", false, "replace an existing database file")
+	flag.Parse()
+
+	cfg, err := config.LoadWithoutJWT()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	store, err := storage.New(cfg.S3, cfg.Backup.Dir)
+	if err != nil {
+		log.Fatalf("Failed to set up backup storage: %v", err)
+	}
+	if store == nil {
+		log.Fatalf("Backups are not configured, set S3_BUCKET or BACKUP_DIR")
+	}
+
+	ctx := context.Background()
+	if *list {
+		backups, err := service.ListStoredBackups(ctx, store)
+		if err != nil {
+			log.Fatalf("Failed to list backups: %v", err)
+		}
+		for _, backup := range backups {
+			fmt.Printf("%s\t%d\t%s\n", backup.Key, backup.Size, backup.CreatedAt.Format("2006-01-02 15:04:05Z"))
+		}
+		return
+	}
+
+	backup, err := service.RestoreBackup(ctx, store, cfg.Backup, *key, *dbPath, *force)
+	if err != nil {
+		log.Fatalf("Failed to restore backup: %v", err)
+	}
+	log.Printf("Restored %s from %s to %s", backup.Key, store, *dbPath)
+}
@@ -2,17 +2,30 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	CORS     CORSConfig
-	S3       S3Config
+	Server      ServerConfig
+	Database    DatabaseConfig
+	JWT         JWTConfig
+	CORS        CORSConfig
+	S3          S3Config
+	Account     AccountConfig
+	Avatar      AvatarConfig
+	Webhook     WebhookConfig
+	Idempotency IdempotencyConfig
+	Log         LogConfig
+	Metrics     MetricsConfig
+	Tracing     TracingConfig
+	Health      HealthConfig
+	Backup      BackupConfig
+	Replication ReplicationConfig
 }
 
 // ServerConfig holds server-related configuration
@@ -28,7 +41,8 @@ type DatabaseConfig struct {
 
 // JWTConfig holds JWT-related configuration
 type JWTConfig struct {
-	Secret string
+	Secret           string
+	ImpersonationTTL time.Duration // How long an impersonation token is valid
 }
 
 // CORSConfig holds CORS-related configuration
@@ -46,8 +60,98 @@ type S3Config struct {
 	Prefix    string
 }
 
+// AccountConfig holds configuration of account data exports and deletion
+type AccountConfig struct {
+	ExportDir     string        // Directory export archives are written to
+	ExportTTL     time.Duration // How long a finished export can be downloaded
+	DeletionGrace time.Duration // Delay between a deletion request and the removal of the account
+}
+
+// AvatarConfig holds configuration of the avatars embedded in documents
+type AvatarConfig struct {
+	Hosts []string // Hosts avatar URLs may point at; data URLs are always accepted
+}
+
+// WebhookConfig holds configuration of outgoing webhooks
+type WebhookConfig struct {
+	AllowHTTP    bool          // Accept plain http:// endpoints, for local development
+	AllowPrivate bool          // Accept endpoints on loopback and private addresses, for local development
+	Timeout      time.Duration // Timeout of one delivery attempt
+}
+
+// IdempotencyConfig holds configuration of Idempotency-Key handling
+type IdempotencyConfig struct {
+	TTL time.Duration // How long a key and its stored response are kept
+}
+
+// LogConfig holds logging configuration
+type LogConfig struct {
+	Level  slog.Level // Minimum level logged
+	Format string     // "json" or "text"
+}
+
+// MetricsConfig holds configuration of the Prometheus metrics endpoint. It is
+// served on Addr when set, otherwise on the API port behind basic auth when
+// Password is set, and not at all when neither is.
+type MetricsConfig struct {
+	Addr     string // Separate listen address such as ":9090"
+	Username string
+	Password string
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Exporter     string  // "none", "otlp" or "stdout"
+	OTLPEndpoint string  // OTLP/HTTP collector URL such as "http://localhost:4318"
+	ServiceName  string  // service.name reported with every span
+	SampleRatio  float64 // Fraction of new traces recorded, from 0 to 1
+}
+
+// HealthConfig holds configuration of the readiness checks
+type HealthConfig struct {
+	CheckTimeout time.Duration // Timeout of each readiness check
+	MinFreeDisk  uint64        // Free bytes required in the database directory
+	DrainDelay   time.Duration // Time between failing readiness and shutting down
+}
+
+// BackupConfig holds configuration of database backups. Backups are stored
+// in the S3 bucket when one is configured, otherwise in Dir.
+type BackupConfig struct {
+	Dir         string        // Local directory backups are stored in without S3
+	Interval    time.Duration // Time between scheduled backups, 0 disables them
+	Passphrase  string        // Encrypts backups when set
+	KeepDaily   int           // Number of days to keep the newest backup of
+	KeepWeekly  int           // Number of weeks to keep the newest backup of
+	KeepMonthly int           // Number of months to keep the newest backup of
+}
+
+// ReplicationConfig holds configuration of continuous replication of the
+// write-ahead log to the backup store
+type ReplicationConfig struct {
+	Interval         time.Duration // Time between copies of new WAL frames, 0 disables replication
+	SnapshotInterval time.Duration // Time between snapshots that start a new generation
+	Retention        time.Duration // How far back in time the replica can be restored
+	RestoreTime      time.Time     // Point in time restored on startup, zero for the latest
+}
+
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
+	config, err := LoadWithoutJWT()
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate required fields
+	if config.JWT.Secret == "" {
+		return nil, fmt.Errorf("JWT_SECRET is required")
+	}
+
+	return config, nil
+}
+
+// LoadWithoutJWT reads configuration like Load but does not require a JWT
+// secret, for commands that neither issue nor verify tokens
+func LoadWithoutJWT() (*Config, error) {
 	allowedOrigins := strings.Split(getEnv("ALLOWED_ORIGINS", "http://localhost:3000"), ",")
 	for i := range allowedOrigins {
 		allowedOrigins[i] = strings.TrimSpace(allowedOrigins[i])
@@ -75,11 +179,118 @@ func Load() (*Config, error) {
 			Region:    getEnv("S3_REGION", ""),
 			Prefix:    getEnv("S3_PREFIX", ""),
 		},
+		Account: AccountConfig{
+			ExportDir: getEnv("EXPORT_DIR", "./data/exports"),
+		},
 	}
 
-	// Validate required fields
-	if config.JWT.Secret == "" {
-		return nil, fmt.Errorf("JWT_SECRET is required")
+	var err error
+	if config.JWT.ImpersonationTTL, err = getDuration("IMPERSONATION_TTL", time.Hour); err != nil {
+		return nil, err
+	}
+	if config.JWT.ImpersonationTTL <= 0 {
+		return nil, fmt.Errorf("IMPERSONATION_TTL must be positive")
+	}
+
+	if config.Account.ExportTTL, err = getDuration("EXPORT_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if config.Account.DeletionGrace, err = getDuration("ACCOUNT_DELETION_GRACE", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	config.Avatar.Hosts = getList("AVATAR_HOSTS")
+
+	config.Webhook.AllowHTTP = getEnv("WEBHOOK_ALLOW_HTTP", "false") == "true"
+	config.Webhook.AllowPrivate = getEnv("WEBHOOK_ALLOW_PRIVATE", "false") == "true"
+	if config.Webhook.Timeout, err = getDuration("WEBHOOK_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+
+	if config.Idempotency.TTL, err = getDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	if err := config.Log.Level.UnmarshalText([]byte(getEnv("LOG_LEVEL", "info"))); err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error: %w", err)
+	}
+	defaultLogFormat := "text"
+	if config.Server.Env == "production" {
+		defaultLogFormat = "json"
+	}
+	config.Log.Format = getEnv("LOG_FORMAT", defaultLogFormat)
+	if config.Log.Format != "json" && config.Log.Format != "text" {
+		return nil, fmt.Errorf("LOG_FORMAT must be json or text")
+	}
+
+	config.Metrics = MetricsConfig{
+		Addr:     getEnv("METRICS_ADDR", ""),
+		Username: getEnv("METRICS_USERNAME", "metrics"),
+		Password: getEnv("METRICS_PASSWORD", ""),
+	}
+
+	config.Tracing = TracingConfig{
+		Exporter:     getEnv("TRACING_EXPORTER", "none"),
+		OTLPEndpoint: getEnv("TRACING_OTLP_ENDPOINT", ""),
+		ServiceName:  getEnv("TRACING_SERVICE_NAME", "work-track-backend"),
+	}
+	switch config.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	default:
+		return nil, fmt.Errorf("TRACING_EXPORTER must be none, otlp or stdout")
+	}
+	if config.Tracing.SampleRatio, err = getFloat("TRACING_SAMPLE_RATIO", 1); err != nil {
+		return nil, err
+	}
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		return nil, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	if config.Health.CheckTimeout, err = getDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second); err != nil {
+		return nil, err
+	}
+	minFreeDiskMB, err := getFloat("HEALTH_MIN_FREE_DISK_MB", 100)
+	if err != nil {
+		return nil, err
+	}
+	config.Health.MinFreeDisk = uint64(minFreeDiskMB * (1 << 20))
+	// Give load balancers time to notice the failing readiness in production
+	defaultDrainDelay := time.Duration(0)
+	if config.Server.Env == "production" {
+		defaultDrainDelay = 5 * time.Second
+	}
+	if config.Health.DrainDelay, err = getDuration("SHUTDOWN_DRAIN_DELAY", defaultDrainDelay); err != nil {
+		return nil, err
+	}
+
+	config.Backup.Dir = getEnv("BACKUP_DIR", "")
+	config.Backup.Passphrase = getEnv("BACKUP_ENCRYPTION_PASSPHRASE", "")
+	if config.Backup.Interval, err = getDuration("BACKUP_INTERVAL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if config.Backup.KeepDaily, err = getInt("BACKUP_KEEP_DAILY", 7); err != nil {
+		return nil, err
+	}
+	if config.Backup.KeepWeekly, err = getInt("BACKUP_KEEP_WEEKLY", 4); err != nil {
+		return nil, err
+	}
+	if config.Backup.KeepMonthly, err = getInt("BACKUP_KEEP_MONTHLY", 12); err != nil {
+		return nil, err
+	}
+
+	if config.Replication.Interval, err = getDuration("REPLICATION_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	if config.Replication.SnapshotInterval, err = getDuration("REPLICATION_SNAPSHOT_INTERVAL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if config.Replication.Retention, err = getDuration("REPLICATION_RETENTION", 72*time.Hour); err != nil {
+		return nil, err
+	}
+	if value := os.Getenv("REPLICATION_RESTORE_TIME"); value != "" {
+		if config.Replication.RestoreTime, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, fmt.Errorf("REPLICATION_RESTORE_TIME must be a time such as 2024-01-15T09:30:00Z: %w", err)
+		}
 	}
 
 	return config, nil
@@ -93,6 +304,66 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getList retrieves a comma-separated list from an environment variable,
+// without empty entries
+func getList(key string) []string {
+	var list []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// getDuration retrieves a duration such as "24h" from an environment variable
+// or returns a default value
+func getDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration such as 24h: %w", key, err)
+	}
+
+	return d, nil
+}
+
+// getInt retrieves a non-negative integer from an environment variable or
+// returns a default value
+func getInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+
+	return n, nil
+}
+
+// getFloat retrieves a number from an environment variable or returns a
+// default value
+func getFloat(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number: %w", key, err)
+	}
+
+	return f, nil
+}
+
 // ConnectionString returns the SQLite connection string
 func (c *DatabaseConfig) ConnectionString() string {
 	return c.Path
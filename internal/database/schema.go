@@ -0,0 +1,5 @@
+package database
+
+// SchemaVersion is the number of the newest migration in migrations/, the
+// schema version this build expects the database to have
+const SchemaVersion = 20
@@ -0,0 +1,20 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSchemaVersionIsNewestMigration(t *testing.T) {
+	migrations, err := LoadMigrations(filepath.Join("..", "..", "migrations"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations found")
+	}
+
+	if newest := migrations[len(migrations)-1].Version; SchemaVersion != newest {
+		t.Errorf("SchemaVersion is %d, the newest migration is %d", SchemaVersion, newest)
+	}
+}
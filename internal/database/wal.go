@@ -0,0 +1,178 @@
+package database
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// ErrNoWAL is returned when the WAL file does not exist or holds no frames
+var ErrNoWAL = errors.New("no write-ahead log")
+
+const (
+	// WALHeaderSize is the size of the header at the start of a WAL file
+	WALHeaderSize = 32
+	// walFrameHeaderSize is the size of the header before each page in a frame
+	walFrameHeaderSize = 24
+
+	walMagicLittleEndian = 0x377f0682
+	walMagicBigEndian    = 0x377f0683
+)
+
+// WALPath returns the write-ahead log file of the database at dbPath
+func WALPath(dbPath string) string {
+	return dbPath + "-wal"
+}
+
+// WALHeader is the header of a WAL file. Every restart of the log, after it
+// has been checkpointed, writes a new header with Salt1 incremented.
+type WALHeader struct {
+	PageSize uint32
+	Salt1    uint32
+	Salt2    uint32
+	Checksum [2]uint32 // Checksum of the header, the start of the frame checksum chain
+	bigEnd   bool      // Checksums are computed on big-endian words
+}
+
+// FrameSize returns the size of one frame in the log
+func (h *WALHeader) FrameSize() int64 {
+	return walFrameHeaderSize + int64(h.PageSize)
+}
+
+// SameLog reports whether h and other are headers of the same log, written
+// between the same two restarts
+func (h *WALHeader) SameLog(other *WALHeader) bool {
+	return h.Salt1 == other.Salt1 && h.Salt2 == other.Salt2
+}
+
+// ReadWALHeader reads and verifies the header of the WAL file at path
+func ReadWALHeader(path string) (*WALHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNoWAL
+		}
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, WALHeaderSize)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNoWAL
+		}
+		return nil, fmt.Errorf("failed to read WAL header: %w", err)
+	}
+
+	return ParseWALHeader(buf)
+}
+
+// ReadWALFrames reads the frames of the log described by hdr from offset, up
+// to and including the last frame that commits a transaction. sum is the
+// checksum of the frame before offset. At offset 0 the header is read too,
+// and the checksum chain starts from its checksum. Frames are verified
+// against the header salts and the checksum chain, which ends at the first
+// frame that was not written in this log. It returns the bytes read, the
+// offset after them and the checksum of the last frame; the frames of a
+// transaction still being written are left for later.
+func ReadWALFrames(path string, hdr *WALHeader, offset int64, sum [2]uint32) ([]byte, int64, [2]uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, offset, sum, fmt.Errorf("failed to open WAL: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, sum, fmt.Errorf("failed to read WAL: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, offset, sum, fmt.Errorf("failed to read WAL: %w", err)
+	}
+
+	start := 0
+	if offset == 0 {
+		fileHdr, err := ParseWALHeader(data)
+		if err != nil || !fileHdr.SameLog(hdr) {
+			return nil, offset, sum, nil
+		}
+		start = WALHeaderSize
+		sum = hdr.Checksum
+	}
+
+	frameSize := int(hdr.FrameSize())
+	committed := 0
+	committedSum := sum
+	for pos := start; pos+frameSize <= len(data); pos += frameSize {
+		frame := data[pos : pos+frameSize]
+		if binary.BigEndian.Uint32(frame[8:]) != hdr.Salt1 || binary.BigEndian.Uint32(frame[12:]) != hdr.Salt2 {
+			break
+		}
+		sum = walChecksum(hdr.bigEnd, sum, frame[:8])
+		sum = walChecksum(hdr.bigEnd, sum, frame[walFrameHeaderSize:])
+		if sum[0] != binary.BigEndian.Uint32(frame[16:]) || sum[1] != binary.BigEndian.Uint32(frame[20:]) {
+			break
+		}
+
+		// A non-zero database size marks the last frame of a transaction
+		if binary.BigEndian.Uint32(frame[4:]) != 0 {
+			committed = pos + frameSize
+			committedSum = sum
+		}
+	}
+
+	return data[:committed], offset + int64(committed), committedSum, nil
+}
+
+// ParseWALHeader parses and verifies the WAL header at the start of buf
+func ParseWALHeader(buf []byte) (*WALHeader, error) {
+	if len(buf) < WALHeaderSize {
+		return nil, ErrNoWAL
+	}
+
+	hdr := &WALHeader{
+		PageSize: binary.BigEndian.Uint32(buf[8:]),
+		Salt1:    binary.BigEndian.Uint32(buf[16:]),
+		Salt2:    binary.BigEndian.Uint32(buf[20:]),
+		Checksum: [2]uint32{binary.BigEndian.Uint32(buf[24:]), binary.BigEndian.Uint32(buf[28:])},
+	}
+
+	switch binary.BigEndian.Uint32(buf) {
+	case walMagicLittleEndian:
+	case walMagicBigEndian:
+		hdr.bigEnd = true
+	default:
+		return nil, ErrNoWAL
+	}
+	// A page size of 65536 is stored as 1
+	if hdr.PageSize == 1 {
+		hdr.PageSize = 65536
+	}
+	if hdr.PageSize < 512 || hdr.PageSize&(hdr.PageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid WAL page size %d", hdr.PageSize)
+	}
+	if walChecksum(hdr.bigEnd, [2]uint32{}, buf[:24]) != hdr.Checksum {
+		return nil, ErrNoWAL
+	}
+
+	return hdr, nil
+}
+
+// walChecksum continues the WAL checksum s over b, whose length is a multiple
+// of 8, as described in the SQLite file format documentation
+func walChecksum(bigEnd bool, s [2]uint32, b []byte) [2]uint32 {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEnd {
+		order = binary.BigEndian
+	}
+
+	for i := 0; i+8 <= len(b); i += 8 {
+		s[0] += order.Uint32(b[i:]) + s[1]
+		s[1] += order.Uint32(b[i+4:]) + s[0]
+	}
+
+	return s
+}
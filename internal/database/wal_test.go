@@ -0,0 +1,250 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openWALTestDB opens a database in WAL mode with a table to write to. The
+// connection is kept open, so the log is not checkpointed on close.
+func openWALTestDB(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "wal.db")
+	db, err := NewSQLiteDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	return db, path
+}
+
+// insertItems writes one transaction with n rows
+func insertItems(t *testing.T, db *sql.DB, n int) {
+	t.Helper()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range n {
+		if _, err := tx.Exec("INSERT INTO items (name) VALUES (?)", "item"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadWALHeaderNoWAL(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty-wal")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	garbage := filepath.Join(dir, "garbage-wal")
+	if err := os.WriteFile(garbage, make([]byte, WALHeaderSize), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing-wal"), empty, garbage} {
+		if _, err := ReadWALHeader(path); !errors.Is(err, ErrNoWAL) {
+			t.Errorf("%s: got error %v, want %v", filepath.Base(path), err, ErrNoWAL)
+		}
+	}
+}
+
+func TestReadWALFrames(t *testing.T) {
+	db, path := openWALTestDB(t)
+	walPath := WALPath(path)
+
+	hdr, err := ReadWALHeader(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.PageSize != 4096 {
+		t.Errorf("got page size %d, want 4096", hdr.PageSize)
+	}
+
+	frames, end, sum, err := ReadWALFrames(walPath, hdr, 0, [2]uint32{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(frames)) != end || end != walFileSize(t, walPath) {
+		t.Fatalf("read %d bytes up to %d, want the whole log of %d bytes", len(frames), end, walFileSize(t, walPath))
+	}
+	if (end-WALHeaderSize)%hdr.FrameSize() != 0 {
+		t.Errorf("read up to %d, not a frame boundary", end)
+	}
+
+	// Frames written later continue the checksum chain
+	insertItems(t, db, 10)
+	more, next, nextSum, err := ReadWALFrames(walPath, hdr, end, sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(more) == 0 || next != end+int64(len(more)) || next != walFileSize(t, walPath) {
+		t.Fatalf("read %d bytes up to %d after %d, want up to the end of the log at %d", len(more), next, end, walFileSize(t, walPath))
+	}
+
+	_, whole, wholeSum, err := ReadWALFrames(walPath, hdr, 0, [2]uint32{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if whole != next || wholeSum != nextSum {
+		t.Errorf("reading the log at once ended at %d with checksum %x, want %d and %x", whole, wholeSum, next, nextSum)
+	}
+
+	rest, at, _, err := ReadWALFrames(walPath, hdr, next, nextSum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 || at != next {
+		t.Errorf("read %d bytes up to %d at the end of the log, want none", len(rest), at)
+	}
+}
+
+func TestReadWALFramesTornWrites(t *testing.T) {
+	db, path := openWALTestDB(t)
+	walPath := WALPath(path)
+
+	hdr, err := ReadWALHeader(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, committed, _, err := ReadWALFrames(walPath, hdr, 0, [2]uint32{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItems(t, db, 10)
+	data, err := os.ReadFile(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastFrame := len(data) - int(hdr.FrameSize())
+
+	tests := []struct {
+		name   string
+		change func(wal []byte) []byte
+	}{
+		{
+			name:   "partly written frame",
+			change: func(wal []byte) []byte { return wal[:len(wal)-100] },
+		},
+		{
+			name: "page that does not match the checksum",
+			change: func(wal []byte) []byte {
+				wal[lastFrame+walFrameHeaderSize+100] ^= 0xff
+				return wal
+			},
+		},
+		{
+			name: "frame header that does not match the checksum",
+			change: func(wal []byte) []byte {
+				binary.BigEndian.PutUint32(wal[lastFrame+4:], 0)
+				return wal
+			},
+		},
+		{
+			name: "frame of an earlier log",
+			change: func(wal []byte) []byte {
+				binary.BigEndian.PutUint32(wal[lastFrame+8:], hdr.Salt1-1)
+				return wal
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torn := filepath.Join(t.TempDir(), "torn-wal")
+			if err := os.WriteFile(torn, tt.change(append([]byte(nil), data...)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			// The transaction of the damaged frame is not read at all
+			frames, end, _, err := ReadWALFrames(torn, hdr, 0, [2]uint32{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if end != committed || int64(len(frames)) != committed {
+				t.Errorf("read %d bytes up to %d, want the first transaction up to %d", len(frames), end, committed)
+			}
+		})
+	}
+
+	t.Run("damaged header", func(t *testing.T) {
+		torn := filepath.Join(t.TempDir(), "torn-wal")
+		wal := append([]byte(nil), data...)
+		wal[17] ^= 0xff
+		if err := os.WriteFile(torn, wal, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadWALHeader(torn); !errors.Is(err, ErrNoWAL) {
+			t.Errorf("got error %v, want %v", err, ErrNoWAL)
+		}
+	})
+}
+
+func TestReadWALFramesRestart(t *testing.T) {
+	db, path := openWALTestDB(t)
+	walPath := WALPath(path)
+
+	before, err := ReadWALHeader(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, end, _, err := ReadWALFrames(walPath, before, 0, [2]uint32{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once every frame is checkpointed, the next write restarts the log
+	if _, err := db.Exec("PRAGMA wal_checkpoint(RESTART)"); err != nil {
+		t.Fatal(err)
+	}
+	insertItems(t, db, 1)
+
+	after, err := ReadWALHeader(walPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.SameLog(before) || after.Salt1 != before.Salt1+1 {
+		t.Fatalf("got salts %d/%d after the restart, want salt 1 incremented from %d/%d", after.Salt1, after.Salt2, before.Salt1, before.Salt2)
+	}
+
+	// The frames of the new log do not belong to the old one
+	frames, at, _, err := ReadWALFrames(walPath, before, 0, [2]uint32{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 0 || at != 0 {
+		t.Errorf("read %d bytes of the restarted log as the old one", len(frames))
+	}
+	frames, at, _, err = ReadWALFrames(walPath, after, 0, [2]uint32{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) == 0 || at > end {
+		t.Errorf("read %d bytes up to %d of the restarted log, want its first transaction before %d", len(frames), at, end)
+	}
+}
+
+func walFileSize(t *testing.T, path string) int64 {
+	t.Helper()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
@@ -0,0 +1,167 @@
+// Package events provides an in-process publish/subscribe hub for pushing
+// changes to connected clients.
+package events
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a change pushed to subscribers
+type Event struct {
+	ID     string          // Assigned by Publish; resumable with Hub.Subscribe
+	Type   string          // Such as "track_item.created"
+	UserID int             // User the event is about
+	Data   json.RawMessage // JSON body sent to clients
+}
+
+// subscriptionBuffer is the number of events a subscriber can fall behind by
+// before it is dropped
+const subscriptionBuffer = 64
+
+// Hub fans events out to subscribers and keeps the most recent ones, so
+// clients that reconnect can catch up. It is safe for concurrent use.
+//
+// Event IDs are "<epoch>-<sequence>", where the epoch identifies the process:
+// after a restart, IDs issued earlier are recognised as unknown rather than
+// mistaken for recent ones.
+type Hub struct {
+	mu      sync.Mutex
+	epoch   string
+	seq     uint64
+	history []Event // Ring buffer of the most recent events
+	next    int     // Position of the next event in history
+	subs    map[*Subscription]struct{}
+	closed  bool
+}
+
+// NewHub creates a hub that keeps the last historySize events for replay
+func NewHub(historySize int) *Hub {
+	return &Hub{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		history: make([]Event, 0, historySize),
+		subs:    make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the events accepted by its filter
+type Subscription struct {
+	// C is closed when the subscription ends: when it is closed, when the hub
+	// shuts down, or when the subscriber fell too far behind
+	C <-chan Event
+
+	ch     chan Event
+	filter func(Event) bool
+	hub    *Hub
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Publish assigns the event an ID and sends it to every subscriber that
+// accepts it. Subscribers whose buffer is full are dropped rather than block
+// the publisher; they can resume from the last event they received.
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.seq++
+	event.ID = h.epoch + "-" + strconv.FormatUint(h.seq, 10)
+	if len(h.history) < cap(h.history) {
+		h.history = append(h.history, event)
+	} else if cap(h.history) > 0 {
+		h.history[h.next] = event
+		h.next = (h.next + 1) % cap(h.history)
+	}
+
+	for sub := range h.subs {
+		if !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribe starts a subscription to the events accepted by filter. When
+// lastEventID is set, the events published after it are returned for replay.
+// resumed is false when lastEventID is unknown or too old to replay, in which
+// case the client has missed events and should reload its state.
+func (h *Hub) Subscribe(lastEventID string, filter func(Event) bool) (sub *Subscription, replay []Event, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, subscriptionBuffer)
+	sub = &Subscription{C: ch, ch: ch, filter: filter, hub: h}
+	if h.closed {
+		close(ch)
+		return sub, nil, false
+	}
+	h.subs[sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+
+	seq, ok := h.parseID(lastEventID)
+	if !ok || seq > h.seq {
+		return sub, nil, false
+	}
+	// The oldest event kept must directly follow the last one seen
+	oldest := h.seq - uint64(len(h.history)) + 1
+	if seq+1 < oldest {
+		return sub, nil, false
+	}
+
+	for i := range h.history {
+		event := h.history[(h.next+i)%len(h.history)]
+		if h.seq-uint64(len(h.history))+uint64(i)+1 > seq && filter(event) {
+			replay = append(replay, event)
+		}
+	}
+
+	return sub, replay, true
+}
+
+// Close ends all subscriptions and stops accepting new events
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// remove ends a subscription. The caller must hold h.mu.
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// parseID returns the sequence number of an event ID issued by this process
+func (h *Hub) parseID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != h.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// AccountArchive is everything stored about a user, as exported on request
+type AccountArchive struct {
+	User           *models.User
+	TrackItems     []models.TrackItem
+	ShiftTemplates []models.ShiftTemplate
+	AuditLog       []models.AuditEntry
+	Avatar         *Image // Nil when the avatar could not be fetched
+	Location       *time.Location
+	GeneratedAt    time.Time
+}
+
+// allColumns lists every CSV column, for exports meant to be complete
+var allColumns = []string{ColumnID, ColumnDate, ColumnTime, ColumnType, ColumnWorkingHours, ColumnWorkingShifts, ColumnEmergencyCall, ColumnHolidayCall, ColumnCreatedAt, ColumnUpdatedAt}
+
+// WriteAccountArchive writes a ZIP archive with the user's profile, track
+// items (as JSON and CSV), shift templates, audit log and avatar
+func WriteAccountArchive(w io.Writer, archive *AccountArchive) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", archive.User},
+		{"track_items.json", emptyIfNil(archive.TrackItems)},
+		{"shift_templates.json", emptyIfNil(archive.ShiftTemplates)},
+		{"audit_log.json", emptyIfNil(archive.AuditLog)},
+	}
+	for _, file := range files {
+		f, err := createArchiveFile(zw, file.name, archive.GeneratedAt)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.value); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	f, err := createArchiveFile(zw, "track_items.csv", archive.GeneratedAt)
+	if err != nil {
+		return err
+	}
+	cw, err := NewCSVWriter(f, &CSVOptions{Columns: allColumns, Delimiter: ',', Decimal: '.', Location: archive.Location})
+	if err != nil {
+		return fmt.Errorf("failed to write track_items.csv: %w", err)
+	}
+	for i := range archive.TrackItems {
+		if err := cw.Write(&archive.TrackItems[i]); err != nil {
+			return fmt.Errorf("failed to write track_items.csv: %w", err)
+		}
+	}
+	if err := cw.Close(); err != nil {
+		return fmt.Errorf("failed to write track_items.csv: %w", err)
+	}
+
+	if archive.Avatar != nil {
+		f, err := createArchiveFile(zw, "avatar."+archive.Avatar.Type, archive.GeneratedAt)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(archive.Avatar.Data); err != nil {
+			return fmt.Errorf("failed to write avatar: %w", err)
+		}
+	}
+
+	return zw.Close()
+}
+
+func createArchiveFile(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// emptyIfNil makes nil slices encode as [] rather than null
+func emptyIfNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
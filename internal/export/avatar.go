@@ -0,0 +1,153 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/util"
+)
+
+// maxAvatarSize limits the size of an avatar image embedded in a document
+const maxAvatarSize = 2 << 20
+
+// Image is an encoded image ready to be embedded in a document
+type Image struct {
+	Data []byte
+	Type string // "png", "jpeg" or "gif"
+}
+
+const (
+	// avatarTimeout bounds fetching one avatar
+	avatarTimeout = 5 * time.Second
+	// AvatarBudget bounds loading all avatars of a document, so that a team
+	// export stays well within the server's write timeout
+	AvatarBudget = 5 * time.Second
+	// avatarConcurrency is the number of avatars fetched at once
+	avatarConcurrency = 8
+)
+
+// AvatarLoader loads the images users' Avatar fields point at. To keep users
+// from making the server request internal URLs, images are only fetched from
+// the allowed hosts, and never from addresses that are not public.
+type AvatarLoader struct {
+	hosts  []string
+	client *http.Client
+}
+
+// NewAvatarLoader creates an avatar loader that fetches images from hosts.
+// Without hosts only data URLs are loaded.
+func NewAvatarLoader(hosts []string) *AvatarLoader {
+	return &AvatarLoader{
+		hosts: hosts,
+		client: &http.Client{
+			Timeout:   avatarTimeout,
+			Transport: util.NewPublicTransport(avatarTimeout),
+			// A redirect could lead to a host that is not allowed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// LoadAll loads the avatars of several users concurrently, within
+// AvatarBudget in total. Avatars that cannot be loaded are left out of images
+// and reported in errs, both keyed by user ID.
+func (l *AvatarLoader) LoadAll(ctx context.Context, refs map[int]string) (images map[int]*Image, errs map[int]error) {
+	ctx, cancel := context.WithTimeout(ctx, AvatarBudget)
+	defer cancel()
+
+	images = make(map[int]*Image)
+	errs = make(map[int]error)
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, avatarConcurrency)
+	)
+	for userID, ref := range refs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			image, err := l.Load(ctx, ref)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[userID] = err
+				return
+			}
+			images[userID] = image
+		}()
+	}
+	wg.Wait()
+
+	return images, errs
+}
+
+// Load loads the image a user's Avatar field points at: a base64 data URL or
+// an http(s) URL on an allowed host. Other references, such as paths served by
+// the frontend, cannot be resolved here and return an error.
+func (l *AvatarLoader) Load(ctx context.Context, ref string) (*Image, error) {
+	var data []byte
+	switch {
+	case strings.HasPrefix(ref, "data:"):
+		meta, payload, ok := strings.Cut(strings.TrimPrefix(ref, "data:"), ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil, errors.New("avatar data URL must be base64 encoded")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode avatar: %w", err)
+		}
+		data = decoded
+	case strings.HasPrefix(ref, "http://"), strings.HasPrefix(ref, "https://"):
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid avatar URL: %w", err)
+		}
+		if !slices.Contains(l.hosts, req.URL.Hostname()) {
+			return nil, fmt.Errorf("avatar host %q is not allowed", req.URL.Hostname())
+		}
+		resp, err := l.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch avatar: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch avatar: status %d", resp.StatusCode)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxAvatarSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read avatar: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported avatar reference %q", ref)
+	}
+
+	if len(data) > maxAvatarSize {
+		return nil, errors.New("avatar is too large")
+	}
+
+	// Only embed images that actually decode, whatever the server claimed
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported avatar image: %w", err)
+	}
+
+	return &Image{Data: data, Type: format}, nil
+}
@@ -0,0 +1,147 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/util"
+)
+
+// avatarServer serves testAvatar after delay and counts the requests it gets
+func avatarServer(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var hits atomic.Int32
+	data := testAvatar(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &hits
+}
+
+func TestAvatarLoaderLoad(t *testing.T) {
+	srv, hits := avatarServer(t, 0)
+	host := mustHostname(t, srv.URL)
+
+	t.Run("data URL", func(t *testing.T) {
+		img, err := NewAvatarLoader(nil).Load(context.Background(), testAvatarURL(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Type != "png" || !bytes.Equal(img.Data, testAvatar(t)) {
+			t.Errorf("got %s image of %d bytes, want the test PNG", img.Type, len(img.Data))
+		}
+	})
+
+	t.Run("host not allowed", func(t *testing.T) {
+		if _, err := NewAvatarLoader(nil).Load(context.Background(), srv.URL+"/avatar.png"); err == nil {
+			t.Fatal("loaded an avatar from a host that is not allowed")
+		}
+		if hits.Load() != 0 {
+			t.Errorf("the server got %d requests, want none", hits.Load())
+		}
+	})
+
+	t.Run("allowed host on a loopback address", func(t *testing.T) {
+		// Allowing a host must not open the server's own network, e.g. when
+		// its DNS record is changed to point there
+		_, err := NewAvatarLoader([]string{host}).Load(context.Background(), srv.URL+"/avatar.png")
+		if !errors.Is(err, util.ErrNonPublicAddress) {
+			t.Fatalf("got error %v, want %v", err, util.ErrNonPublicAddress)
+		}
+		if hits.Load() != 0 {
+			t.Errorf("the server got %d requests, want none", hits.Load())
+		}
+	})
+
+	t.Run("allowed host", func(t *testing.T) {
+		loader := NewAvatarLoader([]string{host})
+		loader.client = srv.Client()
+		img, err := loader.Load(context.Background(), srv.URL+"/avatar.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Type != "png" {
+			t.Errorf("got %s image, want png", img.Type)
+		}
+	})
+
+	t.Run("not an image", func(t *testing.T) {
+		if _, err := NewAvatarLoader(nil).Load(context.Background(), "data:image/png;base64,bm90IGFuIGltYWdl"); err == nil {
+			t.Fatal("loaded an avatar that is not an image")
+		}
+	})
+}
+
+func TestAvatarLoaderLoadAll(t *testing.T) {
+	const delay = 300 * time.Millisecond
+	srv, _ := avatarServer(t, delay)
+	loader := NewAvatarLoader([]string{mustHostname(t, srv.URL)})
+	loader.client = srv.Client()
+
+	refs := map[int]string{
+		1: srv.URL + "/1.png",
+		2: srv.URL + "/2.png",
+		3: srv.URL + "/3.png",
+		4: testAvatarURL(t),
+		5: "/avatars/5.png",
+	}
+	start := time.Now()
+	images, errs := loader.LoadAll(context.Background(), refs)
+	elapsed := time.Since(start)
+
+	if len(images) != 4 {
+		t.Errorf("loaded %d avatars, want 4", len(images))
+	}
+	if len(errs) != 1 || errs[5] == nil {
+		t.Errorf("got errors %v, want one for user 5", errs)
+	}
+	// Fetched one after another they would take three times the delay
+	if elapsed > 2*delay {
+		t.Errorf("loading took %v, want the avatars fetched concurrently", elapsed)
+	}
+}
+
+func TestAvatarLoaderLoadAllBudget(t *testing.T) {
+	srv, _ := avatarServer(t, time.Minute)
+	loader := NewAvatarLoader([]string{mustHostname(t, srv.URL)})
+	loader.client = srv.Client()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	images, errs := loader.LoadAll(ctx, map[int]string{1: srv.URL + "/1.png", 2: srv.URL + "/2.png"})
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("loading took %v, want it to stop at the deadline", elapsed)
+	}
+	if len(images) != 0 || len(errs) != 2 {
+		t.Errorf("got %d avatars and %d errors, want 0 and 2", len(images), len(errs))
+	}
+}
+
+func mustHostname(t *testing.T, rawURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,228 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// Columns available in exports
+const (
+	ColumnID            = "id"
+	ColumnDate          = "date"
+	ColumnTime          = "time"
+	ColumnType          = "type"
+	ColumnWorkingHours  = "working_hours"
+	ColumnWorkingShifts = "working_shifts"
+	ColumnEmergencyCall = "emergency_call"
+	ColumnHolidayCall   = "holiday_call"
+	ColumnCreatedAt     = "created_at"
+	ColumnUpdatedAt     = "updated_at"
+)
+
+// DefaultColumns are exported when no columns are requested
+var DefaultColumns = []string{ColumnDate, ColumnType, ColumnWorkingHours, ColumnWorkingShifts, ColumnEmergencyCall, ColumnHolidayCall}
+
+var knownColumns = map[string]bool{
+	ColumnID:            true,
+	ColumnDate:          true,
+	ColumnTime:          true,
+	ColumnType:          true,
+	ColumnWorkingHours:  true,
+	ColumnWorkingShifts: true,
+	ColumnEmergencyCall: true,
+	ColumnHolidayCall:   true,
+	ColumnCreatedAt:     true,
+	ColumnUpdatedAt:     true,
+}
+
+// commaDecimalLocales lists languages that write decimals with a comma. Their
+// spreadsheets expect ";" as the CSV delimiter.
+var commaDecimalLocales = map[string]bool{
+	"bg": true, "cs": true, "da": true, "de": true, "es": true, "fi": true,
+	"fr": true, "hr": true, "hu": true, "it": true, "lt": true, "lv": true,
+	"nb": true, "nl": true, "pl": true, "pt": true, "ro": true, "ru": true,
+	"sk": true, "sl": true, "sr": true, "sv": true, "tr": true, "uk": true,
+}
+
+// CSVOptions controls the layout of a CSV export
+type CSVOptions struct {
+	Columns   []string
+	Delimiter rune
+	Decimal   rune // '.' or ','
+	BOM       bool // Prepend a UTF-8 byte order mark so Excel detects the encoding
+	Location  *time.Location
+}
+
+// NewCSVOptions builds CSV options from request parameters. locale (e.g. "de"
+// or "en-US") picks the decimal separator and delimiter; delimiter ("," ";"
+// "|" or "tab") and decimal ("." or ",") override it. columns is a comma-separated
+// list of column names.
+func NewCSVOptions(columns, locale, delimiter, decimal string, bom bool, loc *time.Location) (*CSVOptions, error) {
+	opts := &CSVOptions{
+		Columns:   DefaultColumns,
+		Delimiter: ',',
+		Decimal:   '.',
+		BOM:       bom,
+		Location:  loc,
+	}
+
+	if columns != "" {
+		opts.Columns = nil
+		for _, column := range strings.Split(columns, ",") {
+			column = strings.TrimSpace(column)
+			if !knownColumns[column] {
+				return nil, fmt.Errorf("unknown column %q", column)
+			}
+			opts.Columns = append(opts.Columns, column)
+		}
+	}
+
+	language, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	language, _, _ = strings.Cut(language, "_")
+	if commaDecimalLocales[language] {
+		opts.Delimiter = ';'
+		opts.Decimal = ','
+	}
+
+	switch delimiter {
+	case "":
+	case ",", ";", "|":
+		opts.Delimiter = rune(delimiter[0])
+	case "tab", "\t":
+		opts.Delimiter = '\t'
+	default:
+		return nil, fmt.Errorf("unsupported delimiter %q, use \",\", \";\", \"|\" or \"tab\"", delimiter)
+	}
+
+	switch decimal {
+	case "":
+	case ".", ",":
+		opts.Decimal = rune(decimal[0])
+	default:
+		return nil, fmt.Errorf("unsupported decimal separator %q, use \".\" or \",\"", decimal)
+	}
+
+	if opts.Delimiter == opts.Decimal {
+		return nil, errors.New("delimiter and decimal separator must differ")
+	}
+
+	return opts, nil
+}
+
+// CSVWriter writes track items as CSV rows and keeps running totals for the footer
+type CSVWriter struct {
+	w       *csv.Writer
+	opts    *CSVOptions
+	summary models.TrackItemSummary
+}
+
+// NewCSVWriter writes the optional BOM and the header row to w
+func NewCSVWriter(w io.Writer, opts *CSVOptions) (*CSVWriter, error) {
+	if opts.BOM {
+		if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+			return nil, err
+		}
+	}
+
+	cw := &CSVWriter{w: csv.NewWriter(w), opts: opts}
+	cw.w.Comma = opts.Delimiter
+
+	if err := cw.w.Write(opts.Columns); err != nil {
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+// Write writes a single track item row
+func (cw *CSVWriter) Write(item *models.TrackItem) error {
+	cw.summary.Add(item)
+
+	date := item.Date.In(cw.opts.Location)
+	record := make([]string, len(cw.opts.Columns))
+	for i, column := range cw.opts.Columns {
+		switch column {
+		case ColumnID:
+			record[i] = strconv.Itoa(item.ID)
+		case ColumnDate:
+			record[i] = date.Format("2006-01-02")
+		case ColumnTime:
+			record[i] = date.Format("15:04")
+		case ColumnType:
+			record[i] = item.Type
+		case ColumnWorkingHours:
+			record[i] = cw.number(item.WorkingHours)
+		case ColumnWorkingShifts:
+			record[i] = cw.number(item.WorkingShifts)
+		case ColumnEmergencyCall:
+			record[i] = yesNo(item.EmergencyCall)
+		case ColumnHolidayCall:
+			record[i] = yesNo(item.HolidayCall)
+		case ColumnCreatedAt:
+			record[i] = item.CreatedAt.In(cw.opts.Location).Format(time.RFC3339)
+		case ColumnUpdatedAt:
+			record[i] = item.UpdatedAt.In(cw.opts.Location).Format(time.RFC3339)
+		}
+	}
+
+	return cw.w.Write(record)
+}
+
+// Close writes the totals footer and flushes the output. Hours and shifts are
+// summed, emergency and holiday calls are counted.
+func (cw *CSVWriter) Close() error {
+	record := make([]string, len(cw.opts.Columns))
+	labelled := false
+	for i, column := range cw.opts.Columns {
+		switch column {
+		case ColumnWorkingHours:
+			record[i] = cw.number(cw.summary.WorkingHours)
+		case ColumnWorkingShifts:
+			record[i] = cw.number(cw.summary.WorkingShifts)
+		case ColumnEmergencyCall:
+			record[i] = strconv.Itoa(cw.summary.EmergencyCalls)
+		case ColumnHolidayCall:
+			record[i] = strconv.Itoa(cw.summary.HolidayCalls)
+		default:
+			if !labelled {
+				record[i] = "Total"
+				labelled = true
+			}
+		}
+	}
+
+	if err := cw.w.Write(record); err != nil {
+		return err
+	}
+
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// Summary returns the totals of the rows written so far
+func (cw *CSVWriter) Summary() models.TrackItemSummary {
+	return cw.summary
+}
+
+// number formats a value with two decimals and the configured separator
+func (cw *CSVWriter) number(value float64) string {
+	s := strconv.FormatFloat(value, 'f', 2, 64)
+	if cw.opts.Decimal != '.' {
+		s = strings.Replace(s, ".", string(cw.opts.Decimal), 1)
+	}
+	return s
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,109 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testTimesheet returns a timesheet of March 2024 in Berlin with a regular
+// shift on weekdays of the first week, an emergency call on Saturday and a
+// holiday call on the 31st, when daylight saving time starts
+func testTimesheet(user models.User) models.Timesheet {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		panic(err)
+	}
+
+	ts := models.Timesheet{
+		User:      user,
+		StartDate: time.Date(2024, time.March, 1, 0, 0, 0, 0, loc),
+		EndDate:   time.Date(2024, time.March, 31, 0, 0, 0, 0, loc),
+		TimeZone:  loc.String(),
+	}
+	for day := ts.StartDate; !day.After(ts.EndDate); day = day.AddDate(0, 0, 1) {
+		tsDay := models.TimesheetDay{Date: day}
+		var items []models.TrackItem
+		switch {
+		case day.Day() <= 7 && day.Weekday() != time.Saturday && day.Weekday() != time.Sunday:
+			items = append(items, models.TrackItem{Type: "regular", WorkingHours: 8, WorkingShifts: 1})
+		case day.Day() == 2:
+			items = append(items, models.TrackItem{Type: "on-call", WorkingHours: 3.5, WorkingShifts: 0.5, EmergencyCall: true})
+		case day.Day() == 31:
+			items = append(items,
+				models.TrackItem{Type: "regular", WorkingHours: 12, WorkingShifts: 1, HolidayCall: true},
+				models.TrackItem{Type: "overtime", WorkingHours: 1.25},
+			)
+		}
+		for i := range items {
+			items[i].UserID = user.ID
+			items[i].Date = day.Add(8 * time.Hour)
+			tsDay.Items = append(tsDay.Items, items[i])
+			tsDay.Add(&items[i])
+			ts.Totals.Add(&items[i])
+		}
+		ts.Days = append(ts.Days, tsDay)
+	}
+
+	return ts
+}
+
+// testAvatar returns a small PNG image
+func testAvatar(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for x := range 8 {
+		for y := range 8 {
+			img.Set(x, y, color.RGBA{uint8(x * 32), uint8(y * 32), 160, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testAvatarURL returns testAvatar as a data URL
+func testAvatarURL(t *testing.T) string {
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(testAvatar(t))
+}
+
+// checkGolden compares got with the golden file testdata/name, or rewrites
+// the file when the tests run with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		failed := filepath.Join(t.TempDir(), name)
+		os.WriteFile(failed, got, 0o644)
+		t.Errorf("output differs from %s; got %d bytes, want %d, written to %s (run the tests with -update if the change is intended)", path, len(got), len(want), failed)
+	}
+}
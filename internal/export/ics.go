@@ -0,0 +1,114 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// ICSOptions controls the calendar written by WriteICS
+type ICSOptions struct {
+	Name     string         // Calendar name shown by calendar apps
+	Location *time.Location // Zone of all-day events
+}
+
+const (
+	icsDateTime = "20060102T150405Z"
+	// icsUIDDomain is the domain part of event UIDs. It is fixed rather than
+	// taken from the request, so UIDs stay stable behind any host name.
+	icsUIDDomain = "work-track"
+)
+
+// WriteICS writes track items as an iCalendar (RFC 5545) feed. Items with
+// working hours become timed events of that length; others become all-day
+// events on their local day. UIDs are derived from item IDs, so calendar apps
+// update events in place when the feed changes.
+func WriteICS(w io.Writer, items []models.TrackItem, opts *ICSOptions) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeICSLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Work Track//Shifts//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escapeICSText(opts.Name))
+	line("X-WR-TIMEZONE", opts.Location.String())
+	// Ask clients to refresh hourly
+	line("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	line("X-PUBLISHED-TTL", "PT1H")
+
+	for _, item := range items {
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("track-item-%d@%s", item.ID, icsUIDDomain))
+		line("DTSTAMP", item.UpdatedAt.UTC().Format(icsDateTime))
+		line("LAST-MODIFIED", item.UpdatedAt.UTC().Format(icsDateTime))
+
+		if item.WorkingHours > 0 {
+			end := item.Date.Add(time.Duration(item.WorkingHours * float64(time.Hour)))
+			line("DTSTART", item.Date.UTC().Format(icsDateTime))
+			line("DTEND", end.UTC().Format(icsDateTime))
+		} else {
+			day := item.Date.In(opts.Location)
+			line("DTSTART;VALUE=DATE", day.Format("20060102"))
+			line("DTEND;VALUE=DATE", day.AddDate(0, 0, 1).Format("20060102"))
+		}
+
+		line("SUMMARY", escapeICSText(icsSummary(&item)))
+		line("DESCRIPTION", escapeICSText(icsDescription(&item)))
+		line("CATEGORIES", escapeICSText(item.Type))
+		line("TRANSP", "OPAQUE")
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+
+	return bw.Flush()
+}
+
+func icsSummary(item *models.TrackItem) string {
+	summary := item.Type
+	if item.EmergencyCall {
+		summary += " (emergency call)"
+	}
+	if item.HolidayCall {
+		summary += " (holiday call)"
+	}
+	return summary
+}
+
+func icsDescription(item *models.TrackItem) string {
+	return fmt.Sprintf("Working hours: %.2f\nWorking shifts: %.2f\nEmergency call: %s\nHoliday call: %s",
+		item.WorkingHours, item.WorkingShifts, yesNo(item.EmergencyCall), yesNo(item.HolidayCall))
+}
+
+// escapeICSText escapes a TEXT value
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine writes a content line, folded so no line exceeds 75 octets
+// without splitting a UTF-8 character
+func writeICSLine(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards the limit
+		limit = 74
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
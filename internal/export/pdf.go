@@ -0,0 +1,261 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// PDF layout in millimetres on an A4 portrait page
+const (
+	pdfMargin     = 15.0
+	pdfRowHeight  = 5.5
+	pdfAvatarSize = 20.0
+	pdfFont       = "Go"
+)
+
+// pdfColumns are the timesheet table columns and their widths; they add up to
+// the printable width of the page
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Date", 24, "L"},
+	{"Day", 14, "L"},
+	{"Type", 62, "L"},
+	{"Hours", 18, "R"},
+	{"Shifts", 18, "R"},
+	{"Emergency", 22, "C"},
+	{"Holiday", 22, "C"},
+}
+
+// PDFOptions controls the rendering of a PDF timesheet
+type PDFOptions struct {
+	// GeneratedAt is printed in the footer and stored as the document date.
+	// Rendering the same timesheets with the same time gives identical bytes.
+	GeneratedAt time.Time
+	// Avatars holds the avatar images by user ID; users without one get their initials
+	Avatars map[int]*Image
+}
+
+// WriteTimesheetPDF renders printable timesheets, each starting on a new page,
+// with a day-by-day table, totals and signature lines for the employee and the
+// supervisor. Fonts are embedded, so names in any Latin, Greek or Cyrillic
+// script print correctly.
+func WriteTimesheetPDF(w io.Writer, timesheets []models.Timesheet, opts *PDFOptions) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.SetCreationDate(opts.GeneratedAt)
+	pdf.SetModificationDate(opts.GeneratedAt)
+	pdf.SetCatalogSort(true)
+	pdf.SetTitle("Timesheet", true)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", gobold.TTF)
+
+	pdf.SetFooterFunc(func() {
+		_, pageHeight := pdf.GetPageSize()
+		pdf.SetXY(pdfMargin, pageHeight-pdfMargin+4)
+		pdf.SetFont(pdfFont, "", 8)
+		pdf.SetTextColor(110, 110, 110)
+		pdf.CellFormat(90, 4, "Generated "+opts.GeneratedAt.UTC().Format("2006-01-02 15:04")+" UTC", "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+
+	for i := range timesheets {
+		writeTimesheetPages(pdf, &timesheets[i], opts.Avatars[timesheets[i].User.ID])
+	}
+	if len(timesheets) == 0 {
+		pdf.AddPage()
+	}
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("failed to render PDF: %w", err)
+	}
+
+	return pdf.Output(w)
+}
+
+// writeTimesheetPages renders one timesheet, continuing the table on further
+// pages when the range is longer than fits on one
+func writeTimesheetPages(pdf *fpdf.Fpdf, ts *models.Timesheet, avatar *Image) {
+	_, pageHeight := pdf.GetPageSize()
+	// Space kept free below the table on the last page for totals and signatures
+	const closingHeight = pdfRowHeight + 40
+
+	pdf.AddPage()
+	writePDFHeader(pdf, ts, avatar)
+	writePDFTableHeader(pdf)
+
+	for i := range ts.Days {
+		if pdf.GetY()+pdfRowHeight > pageHeight-pdfMargin {
+			pdf.AddPage()
+			writePDFTableHeader(pdf)
+		}
+
+		day := &ts.Days[i]
+		weekend := day.Date.Weekday() == time.Saturday || day.Date.Weekday() == time.Sunday
+		writePDFRow(pdf, weekend, "",
+			day.Date.Format("2006-01-02"),
+			day.Date.Format("Mon"),
+			strings.Join(day.Types(), ", "),
+			pdfNumber(day.WorkingHours),
+			pdfNumber(day.WorkingShifts),
+			pdfCount(day.EmergencyCalls),
+			pdfCount(day.HolidayCalls),
+		)
+	}
+
+	if pdf.GetY()+closingHeight > pageHeight-pdfMargin {
+		pdf.AddPage()
+	}
+	writePDFRow(pdf, false, "B",
+		"Total", "", "",
+		fmt.Sprintf("%.2f", ts.Totals.WorkingHours),
+		fmt.Sprintf("%.2f", ts.Totals.WorkingShifts),
+		fmt.Sprintf("%d", ts.Totals.EmergencyCalls),
+		fmt.Sprintf("%d", ts.Totals.HolidayCalls),
+	)
+
+	writePDFSignatures(pdf, &ts.User)
+}
+
+// writePDFHeader renders the avatar, the user's name and the period
+func writePDFHeader(pdf *fpdf.Fpdf, ts *models.Timesheet, avatar *Image) {
+	x, y := pdfMargin, pdfMargin
+
+	if avatar != nil {
+		name := fmt.Sprintf("avatar-%d", ts.User.ID)
+		pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: avatar.Type}, bytes.NewReader(avatar.Data))
+		pdf.ImageOptions(name, x, y, pdfAvatarSize, pdfAvatarSize, false, fpdf.ImageOptions{ImageType: avatar.Type}, 0, "")
+	}
+	if avatar == nil || pdf.Error() != nil {
+		// An image that fails to decode must not spoil the whole document
+		pdf.ClearError()
+		writePDFInitials(pdf, &ts.User, x, y)
+	}
+
+	textX := x + pdfAvatarSize + 5
+	pdf.SetXY(textX, y)
+	pdf.SetFont(pdfFont, "B", 16)
+	pdf.CellFormat(0, 8, strings.TrimSpace(ts.User.FirstName+" "+ts.User.LastName), "", 2, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.CellFormat(0, 5, "Timesheet "+pdfPeriod(ts), "", 2, "L", false, 0, "")
+	pdf.CellFormat(0, 5, "Time zone: "+ts.TimeZone, "", 2, "L", false, 0, "")
+
+	pdf.SetXY(pdfMargin, y+pdfAvatarSize+6)
+}
+
+// writePDFInitials draws a circle with the user's initials in place of an avatar
+func writePDFInitials(pdf *fpdf.Fpdf, user *models.User, x, y float64) {
+	radius := pdfAvatarSize / 2
+	pdf.SetFillColor(217, 225, 242)
+	pdf.Circle(x+radius, y+radius, radius, "F")
+
+	initials := firstRune(user.FirstName) + firstRune(user.LastName)
+	if initials == "" {
+		initials = firstRune(user.Login)
+	}
+	pdf.SetFont(pdfFont, "B", 14)
+	pdf.SetXY(x, y+radius-3)
+	pdf.CellFormat(pdfAvatarSize, 6, strings.ToUpper(initials), "", 0, "C", false, 0, "")
+}
+
+// writePDFTableHeader renders the column titles
+func writePDFTableHeader(pdf *fpdf.Fpdf) {
+	pdf.SetFont(pdfFont, "B", 9)
+	pdf.SetFillColor(217, 225, 242)
+	for _, column := range pdfColumns {
+		pdf.CellFormat(column.width, pdfRowHeight+1, column.title, "1", 0, column.align, true, 0, "")
+	}
+	pdf.Ln(-1)
+}
+
+// writePDFRow renders one table row; weekend rows are shaded
+func writePDFRow(pdf *fpdf.Fpdf, weekend bool, style string, values ...string) {
+	pdf.SetFont(pdfFont, style, 9)
+	pdf.SetFillColor(231, 230, 230)
+	for i, column := range pdfColumns {
+		pdf.CellFormat(column.width, pdfRowHeight, fitText(pdf, values[i], column.width-2), "1", 0, column.align, weekend, 0, "")
+	}
+	pdf.Ln(-1)
+}
+
+// writePDFSignatures renders the signature lines for the employee and the supervisor
+func writePDFSignatures(pdf *fpdf.Fpdf, user *models.User) {
+	const width = 80.0
+	y := pdf.GetY() + 20
+	pageWidth, _ := pdf.GetPageSize()
+
+	pdf.SetFont(pdfFont, "", 9)
+	for i, label := range []string{"Employee", "Supervisor"} {
+		x := pdfMargin
+		if i == 1 {
+			x = pageWidth - pdfMargin - width
+		}
+
+		pdf.SetDrawColor(0, 0, 0)
+		pdf.Line(x, y, x+width, y)
+		pdf.SetXY(x, y+1)
+		caption := label + " signature"
+		if i == 0 {
+			caption += ": " + strings.TrimSpace(user.FirstName+" "+user.LastName)
+		}
+		pdf.CellFormat(width, 4, caption, "", 2, "L", false, 0, "")
+		pdf.CellFormat(width, 6, "Date: ____________________", "", 0, "L", false, 0, "")
+	}
+}
+
+// pdfPeriod describes the timesheet range, naming the month when it covers exactly one
+func pdfPeriod(ts *models.Timesheet) string {
+	start, end := ts.StartDate, ts.EndDate
+	if start.Day() == 1 && end.AddDate(0, 0, 1).Day() == 1 && start.Month() == end.Month() && start.Year() == end.Year() {
+		return start.Format("January 2006")
+	}
+	return start.Format("2006-01-02") + " to " + end.Format("2006-01-02")
+}
+
+// fitText shortens text with an ellipsis so it fits in width
+func fitText(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+func pdfNumber(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+func pdfCount(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", value)
+}
+
+func firstRune(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || r == utf8.RuneError {
+		return ""
+	}
+	return string(r)
+}
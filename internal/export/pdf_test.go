@@ -0,0 +1,168 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+func TestWriteTimesheetPDF(t *testing.T) {
+	generatedAt := time.Date(2024, time.April, 2, 9, 30, 0, 0, time.UTC)
+	anna := models.User{ID: 1, Login: "anna", FirstName: "Anna", LastName: "Schmidt"}
+	oleg := models.User{ID: 2, Login: "oleg", FirstName: "Олег", LastName: "Иванов"}
+	avatar := &Image{Data: testAvatar(t), Type: "png"}
+
+	tests := []struct {
+		name       string
+		golden     string
+		timesheets []models.Timesheet
+		avatars    map[int]*Image
+	}{
+		{
+			name:       "with avatar",
+			golden:     "timesheet.pdf",
+			timesheets: []models.Timesheet{testTimesheet(anna)},
+			avatars:    map[int]*Image{anna.ID: avatar},
+		},
+		{
+			name:       "team with initials and Cyrillic names",
+			golden:     "timesheet_team.pdf",
+			timesheets: []models.Timesheet{testTimesheet(anna), testTimesheet(oleg)},
+			avatars:    map[int]*Image{anna.ID: avatar},
+		},
+		{
+			name:       "broken avatar falls back to initials",
+			golden:     "timesheet_broken_avatar.pdf",
+			timesheets: []models.Timesheet{testTimesheet(anna)},
+			avatars:    map[int]*Image{anna.ID: {Data: []byte("not a png"), Type: "png"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			render := func() []byte {
+				var buf bytes.Buffer
+				if err := WriteTimesheetPDF(&buf, tt.timesheets, &PDFOptions{GeneratedAt: generatedAt, Avatars: tt.avatars}); err != nil {
+					t.Fatal(err)
+				}
+				return buf.Bytes()
+			}
+
+			got := render()
+			if !bytes.Equal(got, render()) {
+				t.Fatal("rendering the same timesheets twice gave different bytes")
+			}
+			checkGolden(t, tt.golden, got)
+		})
+	}
+}
+
+func TestWriteTimesheetPDFTotals(t *testing.T) {
+	timesheets := []models.Timesheet{
+		testTimesheet(models.User{ID: 1, Login: "anna", FirstName: "Anna", LastName: "Schmidt"}),
+		testTimesheet(models.User{ID: 2, Login: "tom", FirstName: "Tom", LastName: "Becker"}),
+	}
+	// The second timesheet is cut short, so the pages have different totals
+	timesheets[1].Totals = models.TrackItemSummary{}
+	timesheets[1].Days = timesheets[1].Days[:3]
+	for i := range timesheets[1].Days {
+		for j := range timesheets[1].Days[i].Items {
+			timesheets[1].Totals.Add(&timesheets[1].Days[i].Items[j])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTimesheetPDF(&buf, timesheets, &PDFOptions{GeneratedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	pages := pdfPageWords(t, buf.Bytes())
+	if len(pages) != len(timesheets) {
+		t.Fatalf("got %d pages, want one per timesheet", len(pages))
+	}
+
+	for i, words := range pages {
+		totals := timesheets[i].Totals
+		want := []string{
+			fmt.Sprintf("%.2f", totals.WorkingHours),
+			fmt.Sprintf("%.2f", totals.WorkingShifts),
+			strconv.Itoa(totals.EmergencyCalls),
+			strconv.Itoa(totals.HolidayCalls),
+		}
+
+		// Each day row has the date, the weekday and the types, followed by
+		// the hours and shifts when there are any
+		var hours, shifts float64
+		var total []string
+		for j, word := range words {
+			if pdfDate.MatchString(word) {
+				var numbers []float64
+				for _, next := range words[min(j+2, len(words)):] {
+					if pdfDate.MatchString(next) || next == "Total" {
+						break
+					}
+					if n, err := strconv.ParseFloat(next, 64); err == nil && pdfDecimal.MatchString(next) {
+						numbers = append(numbers, n)
+					}
+				}
+				if len(numbers) == 2 {
+					hours += numbers[0]
+					shifts += numbers[1]
+				}
+			}
+			if word == "Total" && j+len(want) < len(words) {
+				total = words[j+1 : j+1+len(want)]
+			}
+		}
+
+		if fmt.Sprint(total) != fmt.Sprint(want) {
+			t.Errorf("page %d: got totals %v, want %v", i+1, total, want)
+		}
+		if got := fmt.Sprintf("%.2f", hours); got != want[0] {
+			t.Errorf("page %d: the days add up to %s hours, want %s", i+1, got, want[0])
+		}
+		if got := fmt.Sprintf("%.2f", shifts); got != want[1] {
+			t.Errorf("page %d: the days add up to %s shifts, want %s", i+1, got, want[1])
+		}
+	}
+}
+
+var (
+	pdfDate    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	pdfDecimal = regexp.MustCompile(`^\d+\.\d{2}$`)
+)
+
+// pdfPageWords extracts the text of each page of a PDF as a list of words
+func pdfPageWords(t *testing.T, data []byte) [][]string {
+	t.Helper()
+
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pages [][]string
+	for i := 1; i <= r.NumPage(); i++ {
+		rows, err := r.Page(i).GetTextByRow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var words []string
+		for _, row := range rows {
+			for _, word := range row.Content {
+				if word.S != "" {
+					words = append(words, word.S)
+				}
+			}
+		}
+		pages = append(pages, words)
+	}
+
+	return pages
+}
@@ -0,0 +1,247 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// Timesheet layout: title and period rows, a blank row, the header row, one row
+// per day and the totals row
+const (
+	xlsxHeaderRow   = 4
+	xlsxFirstDayRow = xlsxHeaderRow + 1
+	xlsxLastColumn  = "G"
+)
+
+var xlsxHeaders = []string{"Date", "Day", "Type", "Hours", "Shifts", "Emergency", "Holiday"}
+
+// Number formats. Call cells hold the number of calls but display as a check
+// mark, so the totals formulas can still sum them.
+const (
+	xlsxDateFormat   = "yyyy-mm-dd"
+	xlsxNumberFormat = "0.00"
+	xlsxMarkerFormat = `"✓"`
+)
+
+// xlsxStyles holds the style IDs of a workbook
+type xlsxStyles struct {
+	title, header, total, totalNumber int
+	// Day cell styles indexed by column, for weekdays and weekends
+	weekday, weekend [7]int
+}
+
+// WriteTimesheetXLSX writes timesheets as an Excel workbook with one sheet per
+// timesheet. Totals are spreadsheet formulas, so edits in Excel update them.
+func WriteTimesheetXLSX(timesheets []models.Timesheet) (*bytes.Buffer, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	styles, err := newXLSXStyles(f)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	for i := range timesheets {
+		name := sheetName(&timesheets[i].User, used)
+		if i == 0 {
+			err = f.SetSheetName(f.GetSheetName(0), name)
+		} else {
+			_, err = f.NewSheet(name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create sheet: %w", err)
+		}
+
+		if err := writeTimesheetSheet(f, name, &timesheets[i], styles); err != nil {
+			return nil, fmt.Errorf("failed to write sheet %q: %w", name, err)
+		}
+	}
+
+	return f.WriteToBuffer()
+}
+
+// writeTimesheetSheet fills one sheet with a timesheet
+func writeTimesheetSheet(f *excelize.File, sheet string, ts *models.Timesheet, styles *xlsxStyles) error {
+	title := fmt.Sprintf("Timesheet: %s %s", ts.User.FirstName, ts.User.LastName)
+	period := fmt.Sprintf("Period: %s to %s (%s)", ts.StartDate.Format("2006-01-02"), ts.EndDate.Format("2006-01-02"), ts.TimeZone)
+
+	if err := f.SetCellValue(sheet, "A1", title); err != nil {
+		return err
+	}
+	if err := f.SetCellValue(sheet, "A2", period); err != nil {
+		return err
+	}
+	if err := f.MergeCell(sheet, "A1", xlsxLastColumn+"1"); err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, "A1", "A1", styles.title); err != nil {
+		return err
+	}
+
+	header := cell("A", xlsxHeaderRow)
+	if err := f.SetSheetRow(sheet, header, &xlsxHeaders); err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, header, cell(xlsxLastColumn, xlsxHeaderRow), styles.header); err != nil {
+		return err
+	}
+
+	row := xlsxFirstDayRow
+	for i := range ts.Days {
+		day := &ts.Days[i]
+		date := day.Date
+		values := []interface{}{
+			// Excel dates have no time zone; store the local calendar day
+			time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC),
+			date.Format("Mon"),
+			strings.Join(day.Types(), ", "),
+			blankZero(day.WorkingHours),
+			blankZero(day.WorkingShifts),
+			blankZero(float64(day.EmergencyCalls)),
+			blankZero(float64(day.HolidayCalls)),
+		}
+		if err := f.SetSheetRow(sheet, cell("A", row), &values); err != nil {
+			return err
+		}
+
+		rowStyles := styles.weekday
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			rowStyles = styles.weekend
+		}
+		for col, style := range rowStyles {
+			name := cell(string(rune('A'+col)), row)
+			if err := f.SetCellStyle(sheet, name, name, style); err != nil {
+				return err
+			}
+		}
+		row++
+	}
+
+	// Totals row: hours and shifts are summed, calls are counted
+	lastDayRow := row - 1
+	if err := f.SetCellValue(sheet, cell("A", row), "Total"); err != nil {
+		return err
+	}
+	for _, col := range []string{"D", "E", "F", "G"} {
+		formula := fmt.Sprintf("SUM(%s:%s)", cell(col, xlsxFirstDayRow), cell(col, lastDayRow))
+		if err := f.SetCellFormula(sheet, cell(col, row), formula); err != nil {
+			return err
+		}
+	}
+	if err := f.SetCellStyle(sheet, cell("A", row), cell("C", row), styles.total); err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, cell("D", row), cell(xlsxLastColumn, row), styles.totalNumber); err != nil {
+		return err
+	}
+
+	for col, width := range map[string]float64{"A": 12, "B": 6, "C": 24, "D": 8, "E": 8, "F": 11, "G": 9} {
+		if err := f.SetColWidth(sheet, col, col, width); err != nil {
+			return err
+		}
+	}
+
+	// Keep the header visible while scrolling through the days
+	return f.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      xlsxHeaderRow,
+		TopLeftCell: cell("A", xlsxFirstDayRow),
+		ActivePane:  "bottomLeft",
+	})
+}
+
+// newXLSXStyles registers the styles used by timesheets
+func newXLSXStyles(f *excelize.File) (*xlsxStyles, error) {
+	styles := &xlsxStyles{}
+	border := []excelize.Border{{Type: "top", Color: "000000", Style: 1}}
+	weekendFill := excelize.Fill{Type: "pattern", Color: []string{"E7E6E6"}, Pattern: 1}
+	dateFormat, numberFormat, markerFormat := xlsxDateFormat, xlsxNumberFormat, xlsxMarkerFormat
+	centered := &excelize.Alignment{Horizontal: "center"}
+
+	var err error
+	define := func(target *int, style *excelize.Style) {
+		if err == nil {
+			*target, err = f.NewStyle(style)
+		}
+	}
+
+	define(&styles.title, &excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	define(&styles.header, &excelize.Style{
+		Font:   &excelize.Font{Bold: true},
+		Fill:   excelize.Fill{Type: "pattern", Color: []string{"D9E1F2"}, Pattern: 1},
+		Border: []excelize.Border{{Type: "bottom", Color: "000000", Style: 1}},
+	})
+	define(&styles.total, &excelize.Style{Font: &excelize.Font{Bold: true}, Border: border})
+	define(&styles.totalNumber, &excelize.Style{Font: &excelize.Font{Bold: true}, Border: border, CustomNumFmt: &numberFormat})
+
+	for i, fill := range []excelize.Fill{{}, weekendFill} {
+		target := &styles.weekday
+		if i == 1 {
+			target = &styles.weekend
+		}
+		define(&target[0], &excelize.Style{Fill: fill, CustomNumFmt: &dateFormat})
+		define(&target[1], &excelize.Style{Fill: fill})
+		define(&target[2], &excelize.Style{Fill: fill})
+		define(&target[3], &excelize.Style{Fill: fill, CustomNumFmt: &numberFormat})
+		define(&target[4], &excelize.Style{Fill: fill, CustomNumFmt: &numberFormat})
+		define(&target[5], &excelize.Style{Fill: fill, CustomNumFmt: &markerFormat, Alignment: centered})
+		define(&target[6], &excelize.Style{Fill: fill, CustomNumFmt: &markerFormat, Alignment: centered})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create styles: %w", err)
+	}
+
+	return styles, nil
+}
+
+// sheetName returns a unique sheet name for a user. Excel limits names to 31
+// characters and forbids some punctuation.
+func sheetName(user *models.User, used map[string]bool) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = user.Login
+	}
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\'`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	base := truncateRunes(name, 31)
+	name = base
+	for n := 2; used[name]; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		name = truncateRunes(base, 31-len(suffix)) + suffix
+	}
+	used[name] = true
+
+	return name
+}
+
+// blankZero leaves zero values empty so working days stand out
+func blankZero(value float64) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+func cell(col string, row int) string {
+	return fmt.Sprintf("%s%d", col, row)
+}
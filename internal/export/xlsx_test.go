@@ -0,0 +1,78 @@
+package export
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+func TestWriteTimesheetXLSX(t *testing.T) {
+	timesheets := []models.Timesheet{
+		testTimesheet(models.User{ID: 1, Login: "anna", FirstName: "Anna", LastName: "Schmidt"}),
+		testTimesheet(models.User{ID: 2, Login: "oleg", FirstName: "Олег", LastName: "Иванов"}),
+	}
+	buf, err := WriteTimesheetXLSX(timesheets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) != len(timesheets) {
+		t.Fatalf("got sheets %v, want one per timesheet", sheets)
+	}
+
+	for i, sheet := range sheets {
+		ts := &timesheets[i]
+		totalRow := xlsxFirstDayRow + len(ts.Days)
+		if label, _ := f.GetCellValue(sheet, cell("A", totalRow)); label != "Total" {
+			t.Fatalf("%s: row %d is %q, want the totals row", sheet, totalRow, label)
+		}
+
+		want := map[string]float64{
+			"D": ts.Totals.WorkingHours,
+			"E": ts.Totals.WorkingShifts,
+			"F": float64(ts.Totals.EmergencyCalls),
+			"G": float64(ts.Totals.HolidayCalls),
+		}
+		for col, total := range want {
+			// The days add up to the total, and so does the formula below them
+			sum := 0.0
+			for row := xlsxFirstDayRow; row < totalRow; row++ {
+				sum += xlsxNumber(t, f, sheet, cell(col, row), (*excelize.File).GetCellValue)
+			}
+			if sum != total {
+				t.Errorf("%s: the days of column %s add up to %v, want %v", sheet, col, sum, total)
+			}
+			if got := xlsxNumber(t, f, sheet, cell(col, totalRow), (*excelize.File).CalcCellValue); got != total {
+				t.Errorf("%s: total of column %s is %v, want %v", sheet, col, got, total)
+			}
+		}
+	}
+}
+
+// xlsxNumber reads a numeric cell with get; blank cells are 0
+func xlsxNumber(t *testing.T, f *excelize.File, sheet, name string, get func(*excelize.File, string, string, ...excelize.Options) (string, error)) float64 {
+	t.Helper()
+
+	value, err := get(f, sheet, name, excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		t.Fatalf("%s!%s: %v", sheet, name, err)
+	}
+	return n
+}
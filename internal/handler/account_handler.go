@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// AccountHandler handles account data export and deletion endpoints
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+	}
+}
+
+// RequestExport starts preparing an archive of all of the authenticated user's data
+func (h *AccountHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	job, token, err := h.accountService.RequestExport(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, models.DataExportResponse{
+		DataExport:  *job,
+		DownloadURL: fmt.Sprintf("%s/api/exports/%s.zip", baseURL(r), token),
+	})
+}
+
+// GetExport reports the status of one of the authenticated user's exports
+func (h *AccountHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid export ID")
+		return
+	}
+
+	job, err := h.accountService.GetExport(r.Context(), userID, id)
+	if err != nil {
+		// Another user's export is reported as missing, not forbidden
+		if errors.Is(err, service.ErrUnauthorized) {
+			err = repository.ErrDataExportNotFound
+		}
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// DownloadExport serves a finished export archive. It is public: the secret
+// token in the URL identifies the export, so the link works in a browser.
+func (h *AccountHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	job, f, err := h.accountService.OpenExport(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="work-track-export-%d.zip"`, job.ID))
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(w, r, "", *job.CompletedAt, f)
+}
+
+// DeleteAccount schedules the authenticated user's account for deletion after
+// confirming the password
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Password == "" {
+		respondWithError(w, r, http.StatusBadRequest, "password is required")
+		return
+	}
+
+	at, err := h.accountService.ScheduleDeletion(r.Context(), userID, req.Password)
+	if err != nil {
+		// The user is signed in, so a wrong password is not a failed login
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			respondWithError(w, r, http.StatusForbidden, "Incorrect password")
+			return
+		}
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, models.DeleteAccountResponse{DeletionScheduledAt: at})
+}
+
+// CancelDeletion cancels the scheduled deletion of the authenticated user's account
+func (h *AccountHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.accountService.CancelDeletion(r.Context(), userID); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// baseURL returns the scheme and host the request was made to, for building
+// links that are opened outside the API client
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -2,7 +2,6 @@ package handler
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 
 	"github.com/sergey/work-track-backend/internal/models"
@@ -25,17 +24,13 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req models.UserRegistration
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	resp, err := h.authService.Register(r.Context(), &req)
 	if err != nil {
-		if errors.Is(err, service.ErrEmailAlreadyExists) {
-			respondWithError(w, http.StatusConflict, "Email already exists")
-			return
-		}
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithServiceError(w, r, err)
 		return
 	}
 
@@ -46,17 +41,31 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.UserLogin
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	resp, err := h.authService.Login(r.Context(), &req)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidCredentials) {
-			respondWithError(w, http.StatusUnauthorized, "Invalid credentials")
-			return
-		}
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// ChangePassword handles a user replacing their password, such as a
+// temporary one, and logs them in
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordChange
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	resp, err := h.authService.ChangePassword(r.Context(), &req)
+	if err != nil {
+		respondWithServiceError(w, r, err)
 		return
 	}
 
@@ -76,7 +85,3 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.WriteHeader(code)
 	w.Write(response)
 }
-
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
-}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// backupWriteTimeout bounds how long a backup requested over the API may take
+const backupWriteTimeout = 10 * time.Minute
+
+// BackupHandler handles the admin endpoints of database backups
+type BackupHandler struct {
+	backupService *service.BackupService
+}
+
+// NewBackupHandler creates a new backup handler
+func NewBackupHandler(backupService *service.BackupService) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+	}
+}
+
+// ListBackups lists the stored backups, newest first
+func (h *BackupHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	backups, err := h.backupService.ListBackups(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+	if backups == nil {
+		backups = []models.Backup{}
+	}
+
+	respondWithJSON(w, http.StatusOK, backups)
+}
+
+// CreateBackup takes a backup and responds once it is stored
+func (h *BackupHandler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// A large database takes longer to upload than the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(backupWriteTimeout))
+
+	// Finish the backup even if the client goes away
+	backup, err := h.backupService.CreateBackup(context.WithoutCancel(r.Context()), userID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, backup)
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergey/work-track-backend/internal/export"
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// CalendarHandler handles the calendar feed and its token
+type CalendarHandler struct {
+	calendarService *service.CalendarService
+}
+
+// NewCalendarHandler creates a new calendar handler
+func NewCalendarHandler(calendarService *service.CalendarService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: calendarService,
+	}
+}
+
+// GetToken reports whether the authenticated user's calendar feed is enabled
+func (h *CalendarHandler) GetToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	status, err := h.calendarService.GetTokenStatus(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, status)
+}
+
+// RegenerateToken creates a new feed URL for the authenticated user,
+// invalidating the previous one
+func (h *CalendarHandler) RegenerateToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	token, stored, err := h.calendarService.RegenerateToken(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	path := fmt.Sprintf("/api/calendar/%s.ics", token)
+
+	respondWithJSON(w, http.StatusCreated, models.CalendarTokenResponse{
+		Token:     token,
+		URL:       baseURL(r) + path,
+		WebcalURL: "webcal://" + r.Host + path,
+		CreatedAt: stored.CreatedAt,
+	})
+}
+
+// RevokeToken disables the authenticated user's calendar feed
+func (h *CalendarHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.calendarService.RevokeToken(r.Context(), userID); err != nil {
+		respondWithServiceErrorDetail(w, r, err, "Calendar feed is not enabled")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Feed serves a user's track items as an iCalendar feed. It is public: the
+// secret token in the URL identifies the user, since calendar apps cannot send
+// a bearer token.
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	var types []string
+	for _, t := range strings.Split(r.URL.Query().Get("type"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+
+	feed, err := h.calendarService.GetFeed(r.Context(), chi.URLParam(r, "token"), types)
+	if err != nil {
+		respondWithServiceErrorDetail(w, r, err, "Calendar not found")
+		return
+	}
+
+	var buf bytes.Buffer
+	err = export.WriteICS(&buf, feed.Items, &export.ICSOptions{
+		Name:     strings.TrimSpace("Work Track: " + feed.User.FirstName + " " + feed.User.LastName),
+		Location: feed.Location,
+	})
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="work-track.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age="+fmt.Sprint(int(time.Hour.Seconds())))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
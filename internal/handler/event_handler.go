@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/events"
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+const (
+	// sseKeepAlive is the interval of comments sent on an idle stream, so
+	// proxies keep the connection open and dead clients are noticed
+	sseKeepAlive = 20 * time.Second
+	// sseWriteTimeout bounds each write to a stream. The server's WriteTimeout
+	// covers a whole response, so streams replace it with a per-write deadline.
+	sseWriteTimeout = 10 * time.Second
+	// sseRetry is the reconnection delay suggested to clients, in milliseconds
+	sseRetry = 3000
+)
+
+// EventHandler handles the live event stream
+type EventHandler struct {
+	eventService *service.EventService
+}
+
+// NewEventHandler creates a new event handler
+func NewEventHandler(eventService *service.EventService) *EventHandler {
+	return &EventHandler{
+		eventService: eventService,
+	}
+}
+
+// Stream pushes changes to the authenticated user's data as Server-Sent
+// Events. With team=true, supervisors receive the changes of all users.
+// Clients resume with the Last-Event-ID header (or last_event_id parameter);
+// when that is not possible a "reset" event tells them to reload.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	sub, replay, resumed, err := h.eventService.Subscribe(r.Context(), userID, r.URL.Query().Get("team") == "true", lastEventID)
+	if err != nil {
+		respondWithServiceErrorDetail(w, r, err, "Only supervisors can follow team events")
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	write := func(format string, args ...any) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout)); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	send := func(e events.Event) error {
+		return write("id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable response buffering in nginx
+	w.WriteHeader(http.StatusOK)
+
+	if err := write("retry: %d\n\n", sseRetry); err != nil {
+		return
+	}
+	if !resumed {
+		if err := write("event: reset\ndata: {}\n\n"); err != nil {
+			return
+		}
+	}
+	for _, e := range replay {
+		if err := send(e); err != nil {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				// Shutting down, or the client fell behind; it reconnects and resumes
+				return
+			}
+			if err := send(e); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if err := write(": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/export"
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// ExportHandler handles summary and export endpoints for track items
+type ExportHandler struct {
+	trackItemService *service.TrackItemService
+	avatars          *export.AvatarLoader
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(trackItemService *service.TrackItemService, avatars *export.AvatarLoader) *ExportHandler {
+	return &ExportHandler{
+		trackItemService: trackItemService,
+		avatars:          avatars,
+	}
+}
+
+// Summary returns the totals of the authenticated user's track items over a date range
+func (h *ExportHandler) Summary(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	params := r.URL.Query()
+	dr, err := h.trackItemService.ResolveDateRange(r.Context(), userID, params.Get("start_date"), params.Get("end_date"), params.Get("tz"))
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	summary, err := h.trackItemService.GetSummary(r.Context(), userID, dr)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, summary)
+}
+
+// ExportCSV streams the authenticated user's track items over a date range as CSV
+func (h *ExportHandler) ExportCSV(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	params := r.URL.Query()
+	dr, err := h.trackItemService.ResolveDateRange(r.Context(), userID, params.Get("start_date"), params.Get("end_date"), params.Get("tz"))
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	opts, err := export.NewCSVOptions(
+		params.Get("columns"),
+		params.Get("locale"),
+		params.Get("delimiter"),
+		params.Get("decimal"),
+		params.Get("bom") == "true",
+		dr.Location,
+	)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="track-items_%s_%s.csv"`, params.Get("start_date"), params.Get("end_date")))
+
+	// Once rows are written the status can no longer change, so failures are only logged
+	cw, err := export.NewCSVWriter(w, opts)
+	if err != nil {
+		middleware.LoggerFromContext(r.Context()).Error("CSV export failed", "err", err)
+		return
+	}
+	err = h.trackItemService.StreamTrackItems(r.Context(), userID, dr, func(item *models.TrackItem) error {
+		return cw.Write(item)
+	})
+	if err != nil {
+		middleware.LoggerFromContext(r.Context()).Error("CSV export failed", "err", err)
+		return
+	}
+	if err := cw.Close(); err != nil {
+		middleware.LoggerFromContext(r.Context()).Error("CSV export failed", "err", err)
+	}
+}
+
+// ExportXLSX returns the authenticated user's timesheet as an Excel workbook.
+// With team=true, supervisors get one sheet per team member.
+func (h *ExportHandler) ExportXLSX(w http.ResponseWriter, r *http.Request) {
+	timesheets, filename, ok := h.timesheets(w, r)
+	if !ok {
+		return
+	}
+
+	buf, err := export.WriteTimesheetXLSX(timesheets)
+	if err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	respondWithFile(w, r, buf, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", filename+".xlsx")
+}
+
+// ExportPDF returns the authenticated user's timesheet as a printable PDF with
+// signature lines. With team=true, supervisors get one timesheet per team member.
+func (h *ExportHandler) ExportPDF(w http.ResponseWriter, r *http.Request) {
+	timesheets, filename, ok := h.timesheets(w, r)
+	if !ok {
+		return
+	}
+
+	refs := make(map[int]string)
+	for _, ts := range timesheets {
+		if ts.User.Avatar != "" {
+			refs[ts.User.ID] = ts.User.Avatar
+		}
+	}
+	// A missing avatar is replaced by initials rather than failing the export
+	avatars, errs := h.avatars.LoadAll(r.Context(), refs)
+	for avatarUserID, err := range errs {
+		middleware.LoggerFromContext(r.Context()).Warn("PDF export: avatar skipped", "avatar_user_id", avatarUserID, "err", err)
+	}
+	opts := &export.PDFOptions{
+		GeneratedAt: time.Now(),
+		Avatars:     avatars,
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteTimesheetPDF(&buf, timesheets, opts); err != nil {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	respondWithFile(w, r, &buf, "application/pdf", filename+".pdf")
+}
+
+// timesheets loads the timesheets requested by the month, start_date, end_date,
+// team and tz query parameters, along with a download file name without
+// extension. On failure the error response is written and ok is false.
+func (h *ExportHandler) timesheets(w http.ResponseWriter, r *http.Request) (timesheets []models.Timesheet, filename string, ok bool) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return nil, "", false
+	}
+
+	params := r.URL.Query()
+	startDate, endDate, err := timesheetDates(params)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return nil, "", false
+	}
+
+	if params.Get("team") == "true" {
+		timesheets, err = h.trackItemService.GetTeamTimesheets(r.Context(), userID, startDate, endDate, params.Get("tz"))
+	} else {
+		var timesheet *models.Timesheet
+		timesheet, err = h.trackItemService.GetTimesheet(r.Context(), userID, startDate, endDate, params.Get("tz"))
+		if err == nil {
+			timesheets = []models.Timesheet{*timesheet}
+		}
+	}
+	if errors.Is(err, service.ErrUnauthorized) {
+		respondWithServiceErrorDetail(w, r, err, "Team timesheets are only available to supervisors")
+		return nil, "", false
+	}
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return nil, "", false
+	}
+
+	return timesheets, fmt.Sprintf("timesheet_%s_%s", startDate, endDate), true
+}
+
+// respondWithFile sends a generated document as a download
+func respondWithFile(w http.ResponseWriter, r *http.Request, buf *bytes.Buffer, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		middleware.LoggerFromContext(r.Context()).Warn("Failed to send file", "filename", filename, "err", err)
+	}
+}
+
+// timesheetDates returns the inclusive date range of a timesheet request:
+// either month (YYYY-MM) or start_date and end_date
+func timesheetDates(params url.Values) (string, string, error) {
+	month := params.Get("month")
+	if month == "" {
+		return params.Get("start_date"), params.Get("end_date"), nil
+	}
+
+	first, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "", "", &service.ValidationError{
+			Kind: service.ErrInvalidDateRange,
+			Fields: []models.FieldError{
+				{Field: "month", Message: "invalid month format, use YYYY-MM", Code: models.FieldInvalidFormat},
+			},
+		}
+	}
+	last := first.AddDate(0, 1, -1)
+
+	return first.Format("2006-01-02"), last.Format("2006-01-02"), nil
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+const (
+	// IdempotencyKeyHeader carries the key a client chooses for a request it may retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader marks a response replayed from an earlier request
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotentRequestSize limits the body read to fingerprint a request;
+	// it leaves room for the largest import
+	maxIdempotentRequestSize = maxImportSize + 1<<20
+	// maxIdempotentResponseSize limits the response stored for replay. Larger
+	// responses are sent but not stored, and the key is released.
+	maxIdempotentResponseSize = 1 << 20
+)
+
+// IdempotencyMiddleware makes mutating requests safe to retry
+type IdempotencyMiddleware struct {
+	idempotencyService *service.IdempotencyService
+}
+
+// NewIdempotencyMiddleware creates a new idempotency middleware
+func NewIdempotencyMiddleware(idempotencyService *service.IdempotencyService) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		idempotencyService: idempotencyService,
+	}
+}
+
+// Handler stores the response of POST, PUT, PATCH and DELETE requests sent
+// with an Idempotency-Key header, and replays it when the user sends the same
+// request with the same key again. It must run after AuthMiddleware. Server
+// errors are not stored, so those requests can be retried.
+func (m *IdempotencyMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if key == "" || !ok || !isMutatingMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Fingerprint the request, then hand the body on to the handler
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentRequestSize))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				respondWithError(w, r, http.StatusRequestEntityTooLarge, "Request body is too large")
+				return
+			}
+			respondWithError(w, r, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+		hash.Write(body)
+
+		replay, err := m.idempotencyService.Begin(r.Context(), userID, key, hex.EncodeToString(hash.Sum(nil)))
+		if err != nil {
+			respondWithServiceError(w, r, err)
+			return
+		}
+
+		if replay != nil {
+			for name, values := range replay.ResponseHeader {
+				w.Header()[name] = values
+			}
+			w.Header().Set(idempotentReplayedHeader, "true")
+			w.WriteHeader(replay.StatusCode)
+			w.Write(replay.ResponseBody)
+			return
+		}
+
+		// Store the response even if the client has gone away, as it will retry
+		ctx := context.WithoutCancel(r.Context())
+		rec := &recordingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		stored := false
+		defer func() {
+			// Free the key of a request that failed, panicked or whose response
+			// could not be stored, so it can be retried
+			if !stored {
+				if err := m.idempotencyService.Release(ctx, userID, key); err != nil {
+					middleware.LoggerFromContext(r.Context()).Error("Failed to release idempotency key", "err", err)
+				}
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.statusCode >= http.StatusInternalServerError || rec.overflow {
+			return
+		}
+		if err := m.idempotencyService.Complete(ctx, userID, key, rec.statusCode, replayedHeader(rec.Header()), rec.body.Bytes()); err != nil {
+			middleware.LoggerFromContext(r.Context()).Error("Failed to store idempotent response", "err", err)
+			return
+		}
+		stored = true
+	})
+}
+
+// replayedHeaders are the response headers stored for replay. The others
+// are set per request by other middleware, such as the request ID, or do not
+// describe the stored body.
+var replayedHeaders = []string{
+	"Content-Type",
+	"Content-Disposition",
+	"Location",
+	"X-Content-Type-Options",
+}
+
+// replayedHeader returns the headers of a response stored for replay
+func replayedHeader(header http.Header) http.Header {
+	replayed := http.Header{}
+	for _, name := range replayedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			replayed[name] = values
+		}
+	}
+	return replayed
+}
+
+// isMutatingMethod reports whether requests with method change data
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// recordingResponseWriter passes a response through while keeping a copy of it
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool // The body exceeded maxIdempotentResponseSize
+}
+
+func (rw *recordingResponseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	if !rw.overflow {
+		if rw.body.Len()+len(b) > maxIdempotentResponseSize {
+			rw.overflow = true
+			rw.body = bytes.Buffer{}
+		} else {
+			rw.body.Write(b)
+		}
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// ImpersonationHandler handles the admin endpoint to act as a user
+type ImpersonationHandler struct {
+	impersonationService *service.ImpersonationService
+}
+
+// NewImpersonationHandler creates a new impersonation handler
+func NewImpersonationHandler(impersonationService *service.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationService: impersonationService,
+	}
+}
+
+// Impersonate issues a time-limited token with which the admin acts as a
+// user, read-only unless the body allows writes
+func (h *ImpersonationHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := userAdminParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ImpersonationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	resp, err := h.impersonationService.Impersonate(r.Context(), actorID, userID, &req)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, resp)
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// problemTypeBase prefixes the types of problems, which are documented in the
+// API documentation
+const problemTypeBase = "/problems/"
+
+// problemType describes a kind of problem the API reports
+type problemType struct {
+	status int
+	slug   string
+	title  string
+}
+
+var (
+	problemValidation    = problemType{http.StatusBadRequest, "validation-error", "Invalid request"}
+	problemInvalidQuery  = problemType{http.StatusBadRequest, "invalid-query", "Invalid query parameters"}
+	problemNotFound      = problemType{http.StatusNotFound, "not-found", "Not found"}
+	problemForbidden     = problemType{http.StatusForbidden, "forbidden", "Access denied"}
+	problemAlreadyExists = problemType{http.StatusConflict, "already-exists", "Already exists"}
+	problemInProgress    = problemType{http.StatusConflict, "in-progress", "Already in progress"}
+)
+
+// problemTypes maps the errors of services and repositories to the problems
+// they are reported as. The first match wins, so more specific errors come
+// first.
+var problemTypes = []struct {
+	err error
+	problemType
+}{
+	{service.ErrInvalidListQuery, problemInvalidQuery},
+	{service.ErrInvalidSyncQuery, problemInvalidQuery},
+	{service.ErrInvalidDateRange, problemType{http.StatusBadRequest, "invalid-date-range", "Invalid date range"}},
+	{service.ErrInvalidTimeZone, problemType{http.StatusBadRequest, "invalid-time-zone", "Invalid time zone"}},
+	{service.ErrInvalidImport, problemType{http.StatusBadRequest, "invalid-import", "Invalid import"}},
+	{service.ErrInvalidWebhook, problemType{http.StatusBadRequest, "invalid-webhook", "Invalid webhook"}},
+	{service.ErrInvalidIdempotencyKey, problemType{http.StatusBadRequest, "invalid-idempotency-key", "Invalid idempotency key"}},
+	{service.ErrValidation, problemValidation},
+	{service.ErrInvalidRole, problemValidation},
+	{service.ErrPasswordTooShort, problemValidation},
+
+	{service.ErrInvalidCredentials, problemType{http.StatusUnauthorized, "invalid-credentials", "Invalid credentials"}},
+	{service.ErrAccountDisabled, problemType{http.StatusForbidden, "account-disabled", "Account disabled"}},
+	{service.ErrPasswordChangeRequired, problemType{http.StatusForbidden, "password-change-required", "Password change required"}},
+	{service.ErrUnauthorized, problemForbidden},
+
+	{repository.ErrUserNotFound, problemNotFound},
+	{repository.ErrTrackItemNotFound, problemNotFound},
+	{repository.ErrShiftTemplateNotFound, problemNotFound},
+	{repository.ErrWebhookNotFound, problemNotFound},
+	{repository.ErrWebhookDeliveryNotFound, problemNotFound},
+	{repository.ErrDataExportNotFound, problemNotFound},
+	{repository.ErrCalendarTokenNotFound, problemNotFound},
+	{repository.ErrTimerNotFound, problemNotFound},
+	{service.ErrNoDeletionScheduled, problemNotFound},
+
+	{service.ErrEmailAlreadyExists, problemAlreadyExists},
+	{repository.ErrTimesheetAlreadyApproved, problemAlreadyExists},
+	{repository.ErrTimerRunning, problemType{http.StatusConflict, "timer-running", "Timer already running"}},
+	{service.ErrCopyConflict, problemType{http.StatusConflict, "copy-conflict", "Conflicting track items"}},
+	{service.ErrOwnAccount, problemType{http.StatusConflict, "own-account", "Own account"}},
+	{service.ErrOwnTimesheet, problemType{http.StatusConflict, "own-timesheet", "Own timesheet"}},
+	{service.ErrImpersonationNotAllowed, problemType{http.StatusConflict, "impersonation-not-allowed", "Impersonation not allowed"}},
+	{service.ErrDeliveryNotRetried, problemType{http.StatusConflict, "delivery-not-retried", "Delivery cannot be retried"}},
+	{service.ErrExportNotReady, problemType{http.StatusConflict, "export-not-ready", "Export not ready"}},
+	{service.ErrExportExpired, problemType{http.StatusGone, "export-expired", "Export expired"}},
+	{service.ErrExportInProgress, problemInProgress},
+	{service.ErrBackupInProgress, problemInProgress},
+	{service.ErrIdempotencyKeyInProgress, problemInProgress},
+	{service.ErrIdempotencyKeyMismatch, problemType{http.StatusUnprocessableEntity, "idempotency-key-reused", "Idempotency key reused"}},
+	{service.ErrBackupsDisabled, problemType{http.StatusServiceUnavailable, "backups-disabled", "Backups not configured"}},
+}
+
+// problemFor describes err as a problem. The detail is the message of the
+// known error or validation error found in err, prefixed with the failed
+// operation of a batch, never that of other errors wrapping it, which may
+// describe internals. Errors the API does not know are internal errors, whose
+// details are not exposed.
+func problemFor(err error) *models.Problem {
+	for _, known := range problemTypes {
+		if !errors.Is(err, known.err) {
+			continue
+		}
+
+		problem := &models.Problem{
+			Type:   problemTypeBase + known.slug,
+			Title:  known.title,
+			Status: known.status,
+			Detail: known.err.Error(),
+		}
+		var verr *service.ValidationError
+		if errors.As(err, &verr) {
+			problem.Detail = verr.Error()
+			problem.Errors = verr.Fields
+		}
+		var opErr *service.BatchOperationError
+		if errors.As(err, &opErr) {
+			problem.Detail = fmt.Sprintf("operation %d (%s) failed: %s", opErr.Index, opErr.Op, problem.Detail)
+		}
+		return problem
+	}
+
+	return &models.Problem{
+		Status: http.StatusInternalServerError,
+		Detail: "Internal server error",
+	}
+}
+
+// respondWithServiceError maps an error of a service to its problem response.
+// Unknown errors are logged and reported as internal errors.
+func respondWithServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := problemFor(err)
+	if problem.Status == http.StatusInternalServerError {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	respondWithProblem(w, r, problem)
+}
+
+// respondWithServiceErrorDetail is respondWithServiceError with detail in
+// place of the error's own message, for known errors that deserve a more
+// helpful one
+func respondWithServiceErrorDetail(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	problem := problemFor(err)
+	if problem.Status == http.StatusInternalServerError {
+		respondWithInternalError(w, r, err)
+		return
+	}
+
+	problem.Detail = detail
+	respondWithProblem(w, r, problem)
+}
+
+// respondWithProblem sends a problem response
+func respondWithProblem(w http.ResponseWriter, r *http.Request, problem *models.Problem) {
+	middleware.WriteProblem(w, r, problem)
+}
+
+// respondWithError sends a problem response that is described by its status
+// and a message, for errors of the request itself such as a malformed body
+func respondWithError(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	respondWithProblem(w, r, &models.Problem{Status: status, Detail: detail})
+}
+
+// respondWithInternalError logs err and sends a generic problem with the
+// request ID, so that the failure can be found in the logs without exposing
+// its details to the client
+func respondWithInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	middleware.LoggerFromContext(r.Context()).Error("Request failed", "err", err)
+	respondWithError(w, r, http.StatusInternalServerError, "Internal server error")
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// ShiftTemplateHandler handles shift template endpoints
+type ShiftTemplateHandler struct {
+	templateService *service.ShiftTemplateService
+}
+
+// NewShiftTemplateHandler creates a new shift template handler
+func NewShiftTemplateHandler(templateService *service.ShiftTemplateService) *ShiftTemplateHandler {
+	return &ShiftTemplateHandler{
+		templateService: templateService,
+	}
+}
+
+// ListTemplates retrieves all shift templates for the authenticated user
+func (h *ShiftTemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	templates, err := h.templateService.GetUserTemplates(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, templates)
+}
+
+// CreateTemplate creates a new shift template
+func (h *ShiftTemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.CreateShiftTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tmpl, err := h.templateService.CreateTemplate(r.Context(), userID, &req)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, tmpl)
+}
+
+// GetTemplate retrieves a specific shift template
+func (h *ShiftTemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	templateID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid shift template ID")
+		return
+	}
+
+	tmpl, err := h.templateService.GetTemplate(r.Context(), userID, templateID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tmpl)
+}
+
+// UpdateTemplate updates a shift template
+func (h *ShiftTemplateHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	templateID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid shift template ID")
+		return
+	}
+
+	var req models.UpdateShiftTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tmpl, err := h.templateService.UpdateTemplate(r.Context(), userID, templateID, &req)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tmpl)
+}
+
+// DeleteTemplate deletes a shift template
+func (h *ShiftTemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	templateID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid shift template ID")
+		return
+	}
+
+	err = h.templateService.DeleteTemplate(r.Context(), userID, templateID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ApplyTemplate generates track items from a shift template, or previews them on a dry run
+func (h *ShiftTemplateHandler) ApplyTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	templateID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid shift template ID")
+		return
+	}
+
+	var req models.ApplyShiftTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	resp, err := h.templateService.ApplyTemplate(r.Context(), userID, templateID, &req)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if req.DryRun {
+		status = http.StatusOK
+	}
+
+	respondWithJSON(w, status, resp)
+}
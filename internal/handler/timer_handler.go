@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// TimerHandler handles timer endpoints
+type TimerHandler struct {
+	timerService *service.TimerService
+}
+
+// NewTimerHandler creates a new timer handler
+func NewTimerHandler(timerService *service.TimerService) *TimerHandler {
+	return &TimerHandler{
+		timerService: timerService,
+	}
+}
+
+// GetTimer retrieves the authenticated user's running timer
+func (h *TimerHandler) GetTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	timer, err := h.timerService.GetTimer(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, timer)
+}
+
+// StartTimer starts a timer for the authenticated user
+func (h *TimerHandler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.StartTimerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	timer, err := h.timerService.StartTimer(r.Context(), userID, &req)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, timer)
+}
+
+// StopTimer stops the authenticated user's timer and returns the track item
+// it recorded
+func (h *TimerHandler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	item, err := h.timerService.StopTimer(r.Context(), userID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, item)
+}
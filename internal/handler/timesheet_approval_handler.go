@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// TimesheetApprovalHandler handles timesheet approval endpoints
+type TimesheetApprovalHandler struct {
+	approvalService *service.TimesheetApprovalService
+}
+
+// NewTimesheetApprovalHandler creates a new timesheet approval handler
+func NewTimesheetApprovalHandler(approvalService *service.TimesheetApprovalService) *TimesheetApprovalHandler {
+	return &TimesheetApprovalHandler{
+		approvalService: approvalService,
+	}
+}
+
+// ListApprovals retrieves the approvals of the authenticated user's
+// timesheets, or of the team's with ?team=true
+func (h *TimesheetApprovalHandler) ListApprovals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	params := r.URL.Query()
+	approvals, err := h.approvalService.GetApprovals(r.Context(), userID, params.Get("team") == "true", params.Get("month"))
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			respondWithServiceErrorDetail(w, r, err, "Team approvals are only available to supervisors")
+			return
+		}
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	if approvals == nil {
+		approvals = []models.TimesheetApproval{}
+	}
+	respondWithJSON(w, http.StatusOK, approvals)
+}
+
+// ApproveTimesheet approves a user's timesheet for a month
+func (h *TimesheetApprovalHandler) ApproveTimesheet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.ApproveTimesheetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	approval, err := h.approvalService.ApproveTimesheet(r.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			respondWithServiceErrorDetail(w, r, err, "Only supervisors can approve timesheets")
+			return
+		}
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, approval)
+}
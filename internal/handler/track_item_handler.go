@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sergey/work-track-backend/internal/importer"
 	"github.com/sergey/work-track-backend/internal/middleware"
 	"github.com/sergey/work-track-backend/internal/models"
-	"github.com/sergey/work-track-backend/internal/repository"
 	"github.com/sergey/work-track-backend/internal/service"
 )
 
@@ -25,54 +27,62 @@ func NewTrackItemHandler(trackItemService *service.TrackItemService) *TrackItemH
 	}
 }
 
-// ListTrackItems retrieves all track items for the authenticated user
+// ListTrackItems retrieves the authenticated user's track items, optionally filtered, sorted and paginated
 func (h *TrackItemHandler) ListTrackItems(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Check for date range query parameters
-	startDate := r.URL.Query().Get("start_date")
-	endDate := r.URL.Query().Get("end_date")
-
-	var items []models.TrackItem
-	var err error
-
-	if startDate != "" && endDate != "" {
-		// Get items by date range
-		items, err = h.trackItemService.GetTrackItemsByDateRange(r.Context(), userID, startDate, endDate)
-	} else {
-		// Get all items
-		items, err = h.trackItemService.GetUserTrackItems(r.Context(), userID)
+	params := r.URL.Query()
+	query := models.ListTrackItemsQuery{
+		StartDate:     params.Get("start_date"),
+		EndDate:       params.Get("end_date"),
+		Type:          params.Get("type"),
+		EmergencyCall: params.Get("emergency_call"),
+		HolidayCall:   params.Get("holiday_call"),
+		MinHours:      params.Get("min_hours"),
+		MaxHours:      params.Get("max_hours"),
+		Sort:          params.Get("sort"),
+		Limit:         params.Get("limit"),
+		Cursor:        params.Get("cursor"),
+		IncludeTotal:  params.Get("include_total"),
+		TimeZone:      params.Get("tz"),
 	}
 
+	page, err := h.trackItemService.ListTrackItems(r.Context(), userID, &query)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	// Plain lists keep returning a bare array; paginated or counted lists use the page envelope
+	if query.Limit == "" && query.Cursor == "" && query.IncludeTotal == "" {
+		respondWithJSON(w, http.StatusOK, page.Items)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, items)
+	respondWithJSON(w, http.StatusOK, page)
 }
 
 // CreateTrackItem creates a new track item
 func (h *TrackItemHandler) CreateTrackItem(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req models.CreateTrackItemRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	item, err := h.trackItemService.CreateTrackItem(r.Context(), userID, &req)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithServiceError(w, r, err)
 		return
 	}
 
@@ -83,27 +93,19 @@ func (h *TrackItemHandler) CreateTrackItem(w http.ResponseWriter, r *http.Reques
 func (h *TrackItemHandler) GetTrackItem(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	itemID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid track item ID")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid track item ID")
 		return
 	}
 
 	item, err := h.trackItemService.GetTrackItem(r.Context(), userID, itemID)
 	if err != nil {
-		if errors.Is(err, repository.ErrTrackItemNotFound) {
-			respondWithError(w, http.StatusNotFound, "Track item not found")
-			return
-		}
-		if errors.Is(err, service.ErrUnauthorized) {
-			respondWithError(w, http.StatusForbidden, "Access denied")
-			return
-		}
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithServiceError(w, r, err)
 		return
 	}
 
@@ -114,33 +116,25 @@ func (h *TrackItemHandler) GetTrackItem(w http.ResponseWriter, r *http.Request)
 func (h *TrackItemHandler) UpdateTrackItem(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	itemID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid track item ID")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid track item ID")
 		return
 	}
 
 	var req models.UpdateTrackItemRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	item, err := h.trackItemService.UpdateTrackItem(r.Context(), userID, itemID, &req)
 	if err != nil {
-		if errors.Is(err, repository.ErrTrackItemNotFound) {
-			respondWithError(w, http.StatusNotFound, "Track item not found")
-			return
-		}
-		if errors.Is(err, service.ErrUnauthorized) {
-			respondWithError(w, http.StatusForbidden, "Access denied")
-			return
-		}
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithServiceError(w, r, err)
 		return
 	}
 
@@ -151,29 +145,253 @@ func (h *TrackItemHandler) UpdateTrackItem(w http.ResponseWriter, r *http.Reques
 func (h *TrackItemHandler) DeleteTrackItem(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	itemID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid track item ID")
+		respondWithError(w, r, http.StatusBadRequest, "Invalid track item ID")
 		return
 	}
 
 	err = h.trackItemService.DeleteTrackItem(r.Context(), userID, itemID)
 	if err != nil {
-		if errors.Is(err, repository.ErrTrackItemNotFound) {
-			respondWithError(w, http.StatusNotFound, "Track item not found")
-			return
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BatchTrackItems applies several create, update and delete operations in one request
+func (h *TrackItemHandler) BatchTrackItems(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.BatchTrackItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	results, err := h.trackItemService.BatchTrackItems(r.Context(), userID, &req)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	resp := models.BatchTrackItemsResponse{
+		Mode:    req.Mode,
+		Results: make([]models.BatchOperationResult, 0, len(results)),
+	}
+	for _, result := range results {
+		res := models.BatchOperationResult{
+			Index:  result.Index,
+			Op:     result.Op,
+			Status: batchOperationStatus(result.Op, result.Err),
+			Item:   result.Item,
+		}
+		if result.Err != nil {
+			// Internal errors are logged and reported without their details
+			problem := problemFor(result.Err)
+			if problem.Status == http.StatusInternalServerError {
+				middleware.LoggerFromContext(r.Context()).Error("Batch operation failed", "index", result.Index, "err", result.Err)
+			}
+			res.Error = problem.Detail
+			res.Errors = problem.Errors
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Results = append(resp.Results, res)
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// batchOperationStatus returns the HTTP status matching the outcome of a batch operation
+func batchOperationStatus(op string, err error) int {
+	switch {
+	case err != nil:
+		return problemFor(err).Status
+	case op == models.BatchOpCreate:
+		return http.StatusCreated
+	case op == models.BatchOpDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+// GetSyncChanges returns the track item changes since a sync cursor
+func (h *TrackItemHandler) GetSyncChanges(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	resp, err := h.trackItemService.GetChanges(r.Context(), userID, query.Get("since"), query.Get("limit"))
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// PushSyncChanges applies track item changes made by a client while offline
+func (h *TrackItemHandler) PushSyncChanges(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	results, err := h.trackItemService.PushChanges(r.Context(), userID, &req)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	for i := range results {
+		result := &results[i]
+		if result.Err == nil {
+			continue
 		}
-		if errors.Is(err, service.ErrUnauthorized) {
-			respondWithError(w, http.StatusForbidden, "Access denied")
+		// Internal errors are logged and reported without their details
+		problem := problemFor(result.Err)
+		if problem.Status == http.StatusInternalServerError {
+			middleware.LoggerFromContext(r.Context()).Error("Sync change failed", "index", result.Index, "err", result.Err)
+		}
+		result.Error = problem.Detail
+		result.Errors = problem.Errors
+	}
+
+	respondWithJSON(w, http.StatusOK, models.SyncPushResponse{Results: results})
+}
+
+// CopyTrackItems duplicates a range of track items to another start date
+func (h *TrackItemHandler) CopyTrackItems(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req models.CopyTrackItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	resp, err := h.trackItemService.CopyTrackItems(r.Context(), userID, &req)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+// maxImportSize limits the size of an uploaded import file
+const maxImportSize = 10 << 20
+
+// ImportTrackItems validates an uploaded CSV or XLSX file and returns a preview
+// of the import. With confirm=true the valid rows are stored as well.
+func (h *TrackItemHandler) ImportTrackItems(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Request must be multipart/form-data with a file of at most 10 MB")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	req := models.ImportTrackItemsRequest{
+		DateFormat: r.FormValue("date_format"),
+		TimeZone:   r.FormValue("time_zone"),
+		Duplicates: r.FormValue("duplicates"),
+		Confirm:    r.FormValue("confirm") == "true",
+	}
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			respondWithError(w, r, http.StatusBadRequest, "mapping must be a JSON object of field names to column headers")
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+
+	table, err := importer.Read(file, format, r.FormValue("delimiter"), r.FormValue("sheet"))
+	if err != nil {
+		respondWithServiceError(w, r, importReadError(err))
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	resp, err := h.trackItemService.ImportTrackItems(r.Context(), userID, table, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrImportRejected):
+			// The preview tells the client which rows to fix
+			respondWithJSON(w, http.StatusUnprocessableEntity, resp)
+		default:
+			respondWithServiceError(w, r, err)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if resp.Confirmed {
+		status = http.StatusCreated
+	}
+	respondWithJSON(w, status, resp)
+}
+
+// importReadError describes why an uploaded file could not be read as an
+// invalid import field, with a fixed message rather than the parser's own
+func importReadError(err error) error {
+	field, code, message := "file", models.FieldInvalidFormat, "file could not be read, upload a CSV or XLSX file"
+	switch {
+	case errors.Is(err, importer.ErrUnsupportedFormat):
+		field, code, message = "format", models.FieldInvalidValue, "unsupported file format, use csv or xlsx"
+	case errors.Is(err, importer.ErrUnsupportedDelimiter):
+		field, code, message = "delimiter", models.FieldInvalidValue, `unsupported delimiter, use ",", ";", "|" or "tab"`
+	case errors.Is(err, importer.ErrSheetNotFound):
+		field, code, message = "sheet", models.FieldInvalidValue, "sheet not found"
+	case errors.Is(err, importer.ErrEmptyFile):
+		message = "file is empty"
+	case errors.Is(err, importer.ErrTooManyRows):
+		code, message = models.FieldOutOfRange, importer.ErrTooManyRows.Error()
+	}
+
+	return &service.ValidationError{
+		Kind:   service.ErrInvalidImport,
+		Fields: []models.FieldError{{Field: field, Message: message, Code: code}},
+	}
 }
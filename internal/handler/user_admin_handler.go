@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergey/work-track-backend/internal/middleware"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/service"
+)
+
+// UserAdminHandler handles the admin endpoints of user accounts
+type UserAdminHandler struct {
+	userAdminService *service.UserAdminService
+}
+
+// NewUserAdminHandler creates a new user administration handler
+func NewUserAdminHandler(userAdminService *service.UserAdminService) *UserAdminHandler {
+	return &UserAdminHandler{
+		userAdminService: userAdminService,
+	}
+}
+
+// ListUsers retrieves a page of users, optionally filtered by a search term,
+// role and whether they are disabled
+func (h *UserAdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	page, err := h.userAdminService.ListUsers(r.Context(), &actorID, &models.ListUsersQuery{
+		Search:   query.Get("q"),
+		Role:     query.Get("role"),
+		Disabled: query.Get("disabled"),
+		Limit:    query.Get("limit"),
+		Cursor:   query.Get("cursor"),
+	})
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// GetUser retrieves a user
+func (h *UserAdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := userAdminParams(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userAdminService.GetUser(r.Context(), &actorID, userID)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// DisableUser disables a user's account
+func (h *UserAdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+// EnableUser enables a disabled account again
+func (h *UserAdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *UserAdminHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	actorID, userID, ok := userAdminParams(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userAdminService.SetDisabled(r.Context(), &actorID, userID, disabled)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// ResetPassword replaces a user's password. Without a password in the body a
+// temporary one is generated and returned.
+func (h *UserAdminHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := userAdminParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	password, err := h.userAdminService.ResetPassword(r.Context(), &actorID, userID, req.Password)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, models.PasswordResetResponse{TemporaryPassword: password})
+}
+
+// SetRole changes a user's role
+func (h *UserAdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := userAdminParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.userAdminService.SetRole(r.Context(), &actorID, userID, req.Role)
+	if err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// DeleteUser deletes a user and all their data
+func (h *UserAdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	actorID, userID, ok := userAdminParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.userAdminService.DeleteUser(r.Context(), &actorID, userID); err != nil {
+		respondWithServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userAdminParams reads the authenticated admin and the user ID of a request,
+// responding with an error when either is missing
+func userAdminParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	actorID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		respondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return 0, 0, false
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return 0, 0, false
+	}
+
+	return actorID, userID, true
+}
@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Supported file formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// MaxRows limits the number of data rows in one import
+const MaxRows = 5000
+
+var (
+	// ErrTooManyRows is returned when a file has more than MaxRows data rows
+	ErrTooManyRows = fmt.Errorf("file must not have more than %d rows", MaxRows)
+	// ErrUnsupportedFormat is returned for a file format other than csv and xlsx
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+	// ErrUnsupportedDelimiter is returned for an unknown CSV delimiter
+	ErrUnsupportedDelimiter = errors.New("unsupported delimiter")
+	// ErrSheetNotFound is returned when the workbook has no such sheet
+	ErrSheetNotFound = errors.New("sheet not found")
+	// ErrEmptyFile is returned when a file has no header row
+	ErrEmptyFile = errors.New("file is empty")
+)
+
+// Table is the content of an imported file: the header row and the data rows
+// below it. Every row has the same number of cells as the header.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Read reads a table in the given format. delimiter applies to CSV only and is
+// detected from the header line when empty; sheet applies to XLSX only and
+// defaults to the first sheet.
+func Read(r io.Reader, format, delimiter, sheet string) (*Table, error) {
+	switch format {
+	case FormatCSV:
+		return ReadCSV(r, delimiter)
+	case FormatXLSX:
+		return ReadXLSX(r, sheet)
+	default:
+		return nil, fmt.Errorf("%w %q, use csv or xlsx", ErrUnsupportedFormat, format)
+	}
+}
+
+// ReadCSV reads a CSV table, skipping a leading UTF-8 byte order mark
+func ReadCSV(r io.Reader, delimiter string) (*Table, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xEF\xBB\xBF")) {
+		br.Discard(3)
+	}
+
+	comma, err := csvDelimiter(br, delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := readRecords(cr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	return newTable(records)
+}
+
+// ReadXLSX reads a table from a worksheet. Cells are read unformatted, so
+// numbers keep their full precision and dates come as Excel serial numbers.
+func ReadXLSX(r io.Reader, sheet string) (*Table, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XLSX: %w", err)
+	}
+	defer f.Close()
+
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	}
+	if idx, err := f.GetSheetIndex(sheet); err != nil || idx < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrSheetNotFound, sheet)
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read XLSX: %w", err)
+	}
+	defer rows.Close()
+
+	var records [][]string
+	for rows.Next() {
+		if len(records) > MaxRows {
+			return nil, ErrTooManyRows
+		}
+		record, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read XLSX: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Error(); err != nil {
+		return nil, fmt.Errorf("failed to read XLSX: %w", err)
+	}
+
+	return newTable(records)
+}
+
+// readRecords reads all CSV records, stopping early when there are too many
+func readRecords(cr *csv.Reader) ([][]string, error) {
+	var records [][]string
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > MaxRows {
+			return nil, ErrTooManyRows
+		}
+		records = append(records, record)
+	}
+}
+
+// newTable splits records into the header and data rows, padding short rows
+func newTable(records [][]string) (*Table, error) {
+	if len(records) == 0 {
+		return nil, ErrEmptyFile
+	}
+
+	header := make([]string, len(records[0]))
+	for i, name := range records[0] {
+		header[i] = strings.TrimSpace(name)
+	}
+
+	table := &Table{Header: header}
+	for _, record := range records[1:] {
+		row := make([]string, len(header))
+		copy(row, record)
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+// csvDelimiter returns the requested delimiter, or guesses it from the header
+// line by picking the most frequent of ",", ";" and tab
+func csvDelimiter(br *bufio.Reader, delimiter string) (rune, error) {
+	switch delimiter {
+	case ",", ";", "|":
+		return rune(delimiter[0]), nil
+	case "tab", "\t":
+		return '\t', nil
+	case "":
+	default:
+		return 0, fmt.Errorf("%w %q, use \",\", \";\", \"|\" or \"tab\"", ErrUnsupportedDelimiter, delimiter)
+	}
+
+	line, _ := br.Peek(br.Size())
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	comma, best := ',', 0
+	for _, candidate := range []rune{',', ';', '\t'} {
+		if n := bytes.Count(line, []byte(string(candidate))); n > best {
+			comma, best = candidate, n
+		}
+	}
+
+	return comma, nil
+}
@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// dateLayouts are the accepted date layouts of each date format. Dates with
+// an offset are only accepted in ISO format.
+var dateLayouts = map[string][]string{
+	models.ImportDateISO: {"2006-01-02"},
+	models.ImportDateDMY: {"2.1.2006", "2/1/2006", "2-1-2006"},
+	models.ImportDateMDY: {"1/2/2006"},
+}
+
+var timeLayouts = []string{"15:04", "15:04:05"}
+
+// ValidDateFormat reports whether format is a known date format
+func ValidDateFormat(format string) bool {
+	_, ok := dateLayouts[format]
+	return ok
+}
+
+// ParseDate parses a date cell, optionally combined with a separate time cell.
+// Dates without an offset are wall-clock time in loc. Excel serial numbers,
+// as stored in XLSX date cells, are accepted in any format.
+func ParseDate(value, timeValue, format string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("date is required")
+	}
+
+	date, err := parseDateTime(value, format, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	timeValue = strings.TrimSpace(timeValue)
+	if timeValue == "" {
+		return date, nil
+	}
+
+	clock, err := parseClock(timeValue)
+	if err != nil {
+		return time.Time{}, err
+	}
+	date = date.In(loc)
+	return time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, loc), nil
+}
+
+func parseDateTime(value, format string, loc *time.Location) (time.Time, error) {
+	if serial, err := strconv.ParseFloat(value, 64); err == nil {
+		return fromExcelSerial(serial, loc)
+	}
+
+	if format == models.ImportDateISO {
+		if date, err := time.Parse(time.RFC3339, value); err == nil {
+			return date, nil
+		}
+		// Accept "T" as the separator between date and time
+		value = strings.Replace(value, "T", " ", 1)
+	}
+
+	for _, layout := range dateLayouts[format] {
+		if date, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return date, nil
+		}
+		for _, clock := range timeLayouts {
+			if date, err := time.ParseInLocation(layout+" "+clock, value, loc); err == nil {
+				return date, nil
+			}
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q for date format %s", value, format)
+}
+
+// parseClock parses a time of day, either as text or as an Excel day fraction
+func parseClock(value string) (time.Time, error) {
+	if fraction, err := strconv.ParseFloat(value, 64); err == nil && fraction >= 0 && fraction < 1 {
+		return fromExcelSerial(fraction, time.UTC)
+	}
+	for _, layout := range timeLayouts {
+		if clock, err := time.Parse(layout, value); err == nil {
+			return clock, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use HH:MM", value)
+}
+
+// fromExcelSerial converts an Excel serial date to wall-clock time in loc
+func fromExcelSerial(serial float64, loc *time.Location) (time.Time, error) {
+	t, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %v", serial)
+	}
+	// Round away floating point noise in the day fraction
+	t = t.Round(time.Second)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+}
+
+// ParseNumber parses a numeric cell. Empty cells are zero, and a comma is
+// accepted as the decimal separator.
+func ParseNumber(name, value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if value == "" {
+		return 0, nil
+	}
+	if !strings.Contains(value, ".") {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+	return n, nil
+}
+
+// ParseBool parses a yes/no cell. Empty cells are false.
+func ParseBool(name, value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "no", "n", "false", "0", "-":
+		return false, nil
+	case "yes", "y", "true", "1", "x", "✓":
+		return true, nil
+	default:
+		return false, fmt.Errorf("%s must be yes or no", name)
+	}
+}
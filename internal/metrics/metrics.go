@@ -0,0 +1,87 @@
+// Package metrics defines the Prometheus metrics exposed by the server.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Login results counted by Logins
+const (
+	LoginSucceeded = "succeeded"
+	LoginFailed    = "failed"
+)
+
+var (
+	// HTTPRequests counts handled requests by method, chi route pattern and status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests handled.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes request latency by method and chi route pattern
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// HTTPRequestsInFlight is the number of requests being handled
+	HTTPRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests being handled.",
+	})
+
+	// TrackItemChanges counts committed track item changes by event type, such
+	// as "track_item.created"
+	TrackItemChanges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "worktrack_track_item_changes_total",
+		Help: "Number of track items created, updated and deleted.",
+	}, []string{"event"})
+
+	// Logins counts login attempts by result
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "worktrack_logins_total",
+		Help: "Number of login attempts.",
+	}, []string{"result"})
+
+	// TokensIssued counts the access tokens issued on registration and login
+	TokensIssued = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "worktrack_tokens_issued_total",
+		Help: "Number of access tokens issued.",
+	})
+
+	// ReplicationLastSync is when the write-ahead log was last replicated
+	ReplicationLastSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "worktrack_replication_last_sync_timestamp_seconds",
+		Help: "Unix time of the last successful replication of the write-ahead log.",
+	})
+)
+
+// NewRegistry creates a registry with the server's metrics, Go runtime and
+// process metrics, and the connection pool statistics of db
+func NewRegistry(db *sql.DB) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "sqlite"),
+		HTTPRequests,
+		HTTPRequestDuration,
+		HTTPRequestsInFlight,
+		TrackItemChanges,
+		Logins,
+		TokensIssued,
+		ReplicationLastSync,
+	)
+	return reg
+}
+
+// Handler serves the metrics of reg in the Prometheus text format
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
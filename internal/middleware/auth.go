@@ -2,10 +2,14 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/sergey/work-track-backend/internal/util"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ContextKey is a custom type for context keys
@@ -16,21 +20,38 @@ const (
 	UserIDKey ContextKey = "userID"
 )
 
-// AuthMiddleware validates JWT tokens and adds user ID to context
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+// AccountChecker reports whether the account a token was issued to may still
+// use the API. Tokens issued before the account's password was reset are
+// rejected with util.ErrRevokedToken.
+type AccountChecker interface {
+	AccountActive(ctx context.Context, userID, tokenVersion int) (bool, error)
+}
+
+// tokenAccount is an account a token was issued to, at the account's token
+// version then
+type tokenAccount struct {
+	userID       int
+	tokenVersion int
+}
+
+// AuthMiddleware validates JWT tokens, rejects tokens of accounts that were
+// disabled or deleted since or whose password was reset, and adds user ID to
+// context. Requests with an impersonation token are handled as the user and
+// audited.
+func AuthMiddleware(jwtSecret string, checker AccountChecker, impersonations ImpersonationAuditor) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get token from Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				writeError(w, r, http.StatusUnauthorized, "Authorization header required")
 				return
 			}
 
 			// Extract token from "Bearer <token>"
 			parts := strings.Split(authHeader, " ")
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+				writeError(w, r, http.StatusUnauthorized, "Invalid authorization header format")
 				return
 			}
 
@@ -39,13 +60,46 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 			// Validate token
 			claims, err := util.ValidateToken(tokenString, jwtSecret)
 			if err != nil {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				writeError(w, r, http.StatusUnauthorized, "Invalid or expired token")
 				return
 			}
 
+			// The admin acting as the user must still be allowed in as well
+			accounts := []tokenAccount{{claims.UserID, claims.TokenVersion}}
+			if claims.Impersonated() {
+				accounts = append(accounts, tokenAccount{claims.ActorID, claims.ActorTokenVersion})
+			}
+			for _, account := range accounts {
+				active, err := checker.AccountActive(r.Context(), account.userID, account.tokenVersion)
+				if errors.Is(err, util.ErrRevokedToken) {
+					writeError(w, r, http.StatusUnauthorized, "Token has been revoked")
+					return
+				}
+				if err != nil {
+					LoggerFromContext(r.Context()).Error("Failed to check account", "err", err)
+					writeError(w, r, http.StatusInternalServerError, "Internal server error")
+					return
+				}
+				if !active {
+					writeError(w, r, http.StatusForbidden, "Account is disabled or deleted")
+					return
+				}
+			}
+
 			// Add user ID to context
+			setLogUserID(r.Context(), claims.UserID, claims.ActorID)
+			trace.SpanFromContext(r.Context()).SetAttributes(semconv.EnduserID(strconv.Itoa(claims.UserID)))
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			if !claims.Impersonated() {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			ctx = context.WithValue(ctx, ImpersonationKey, &Impersonation{
+				ActorID:  claims.ActorID,
+				ReadOnly: claims.ReadOnly,
+			})
+			serveImpersonated(w, r.WithContext(ctx), next, claims, impersonations)
 		})
 	}
 }
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/sergey/work-track-backend/internal/util"
+)
+
+const (
+	// ImpersonationKey is the context key for the admin acting as the user
+	ImpersonationKey ContextKey = "impersonation"
+
+	// ImpersonatedByHeader marks responses to requests an admin made as the
+	// user, with the admin's user ID
+	ImpersonatedByHeader = "X-Impersonated-By"
+)
+
+// Impersonation describes the admin acting as the authenticated user
+type Impersonation struct {
+	ActorID  int
+	ReadOnly bool
+}
+
+// secretRoute is a route whose response carries a secret of the user, such
+// as the calendar feed URL or an export download link, that would outlive
+// an impersonated session
+type secretRoute struct {
+	method  string
+	pattern string // path.Match pattern of the request path
+}
+
+// secretRoutes are denied to impersonated sessions, read-only or not
+var secretRoutes = []secretRoute{
+	{http.MethodGet, "/api/me/calendar-token"},
+	{http.MethodPost, "/api/me/calendar-token"},
+	{http.MethodGet, "/api/me/export/*"},
+}
+
+// ImpersonationAuditor records the requests admins make as a user
+type ImpersonationAuditor interface {
+	AuditRequest(ctx context.Context, actorID, userID int, method, path string, status int) error
+}
+
+// serveImpersonated serves a request made with an impersonation token. The
+// response is marked, secrets of the user are withheld, writes are rejected
+// in read-only sessions and the request is audited with its status once it
+// is served.
+func serveImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler, claims *util.Claims, auditor ImpersonationAuditor) {
+	w.Header().Set(ImpersonatedByHeader, strconv.Itoa(claims.ActorID))
+	wrapped := &responseWriter{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+	}
+
+	switch {
+	case requestsSecret(r):
+		writeError(wrapped, r, http.StatusForbidden, "Secrets of the user are not available to impersonated sessions")
+	case claims.ReadOnly && !safeMethod(r.Method):
+		writeError(wrapped, r, http.StatusForbidden, "Impersonated sessions are read-only")
+	default:
+		next.ServeHTTP(wrapped, r)
+	}
+
+	// Record the request even if the client went away
+	ctx := context.WithoutCancel(r.Context())
+	if err := auditor.AuditRequest(ctx, claims.ActorID, claims.UserID, r.Method, r.URL.Path, wrapped.statusCode); err != nil {
+		LoggerFromContext(ctx).Error("Failed to audit impersonated request", "actor_id", claims.ActorID, "err", err)
+	}
+}
+
+// requestsSecret reports whether a request is for one of the secretRoutes.
+// HEAD is matched like GET.
+func requestsSecret(r *http.Request) bool {
+	method := r.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	requestPath := path.Clean(r.URL.Path)
+	for _, route := range secretRoutes {
+		if matched, _ := path.Match(route.pattern, requestPath); matched && route.method == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// safeMethod reports whether a method only reads
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// GetImpersonationFromContext retrieves the admin acting as the authenticated
+// user, if any, from the request context
+func GetImpersonationFromContext(ctx context.Context) (*Impersonation, bool) {
+	impersonation, ok := ctx.Value(ImpersonationKey).(*Impersonation)
+	return impersonation, ok
+}
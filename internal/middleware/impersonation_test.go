@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergey/work-track-backend/internal/util"
+)
+
+// activeAccounts accepts every account
+type activeAccounts struct{}
+
+func (activeAccounts) AccountActive(ctx context.Context, userID, tokenVersion int) (bool, error) {
+	return true, nil
+}
+
+// auditedRequest is a request recorded by recordingAuditor
+type auditedRequest struct {
+	method, path string
+	status       int
+}
+
+// recordingAuditor keeps the impersonated requests it is asked to audit
+type recordingAuditor struct {
+	requests []auditedRequest
+}
+
+func (a *recordingAuditor) AuditRequest(ctx context.Context, actorID, userID int, method, path string, status int) error {
+	a.requests = append(a.requests, auditedRequest{method, path, status})
+	return nil
+}
+
+func TestImpersonatedRequests(t *testing.T) {
+	const secret = "secret"
+	const actorID, userID = 1, 2
+
+	auditor := &recordingAuditor{}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := chi.NewRouter()
+	r.Route("/api/me", func(r chi.Router) {
+		r.Use(AuthMiddleware(secret, activeAccounts{}, auditor))
+		r.Get("/", ok)
+		r.Put("/", ok)
+		r.Get("/export/{id}", ok)
+		r.Get("/calendar-token", ok)
+		r.Post("/calendar-token", ok)
+		r.Delete("/calendar-token", ok)
+	})
+
+	token, err := util.GenerateToken(userID, 0, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readOnly, _, err := util.GenerateImpersonationToken(actorID, 0, userID, 0, true, time.Hour, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readWrite, _, err := util.GenerateImpersonationToken(actorID, 0, userID, 0, false, time.Hour, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "profile", token: readOnly, method: http.MethodGet, path: "/api/me/", want: http.StatusOK},
+		{name: "write in a read-only session", token: readOnly, method: http.MethodPut, path: "/api/me/", want: http.StatusForbidden},
+		{name: "write in a read-write session", token: readWrite, method: http.MethodPut, path: "/api/me/", want: http.StatusOK},
+		{name: "calendar token", token: readOnly, method: http.MethodGet, path: "/api/me/calendar-token", want: http.StatusForbidden},
+		{name: "calendar token with HEAD", token: readOnly, method: http.MethodHead, path: "/api/me/calendar-token", want: http.StatusForbidden},
+		{name: "calendar token with a trailing slash", token: readWrite, method: http.MethodGet, path: "/api/me/calendar-token/", want: http.StatusForbidden},
+		{name: "regenerated calendar token", token: readWrite, method: http.MethodPost, path: "/api/me/calendar-token", want: http.StatusForbidden},
+		{name: "revoking the calendar token", token: readWrite, method: http.MethodDelete, path: "/api/me/calendar-token", want: http.StatusOK},
+		{name: "export download link", token: readWrite, method: http.MethodGet, path: "/api/me/export/7", want: http.StatusForbidden},
+		{name: "own calendar token", token: token, method: http.MethodGet, path: "/api/me/calendar-token", want: http.StatusOK},
+		{name: "own export", token: token, method: http.MethodGet, path: "/api/me/export/7", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditor.requests = nil
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d", rec.Code, tt.want)
+			}
+			if tt.token == token {
+				if len(auditor.requests) != 0 {
+					t.Errorf("got audited requests %v, want none", auditor.requests)
+				}
+				return
+			}
+			want := auditedRequest{tt.method, tt.path, tt.want}
+			if len(auditor.requests) != 1 || auditor.requests[0] != want {
+				t.Errorf("got audited requests %v, want %v", auditor.requests, want)
+			}
+		})
+	}
+}
@@ -1,15 +1,31 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"net"
 	"net/http"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
-// responseWriter wraps http.ResponseWriter to capture status code
+// requestLogKey is the context key for the requestLog of a request
+const requestLogKey ContextKey = "requestLog"
+
+// requestLog collects details that inner handlers learn about a request, such
+// as the authenticated user, for its log line
+type requestLog struct {
+	userID  int
+	actorID int // Admin acting as the user, if any
+}
+
+// responseWriter wraps http.ResponseWriter to capture status code and size
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -17,7 +33,20 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// Logger logs HTTP requests
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController, so handlers
+// behind the logger can flush and change write deadlines
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Logger logs HTTP requests. Server errors are logged at error level, other
+// requests at info level. It must run after RequestID.
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -27,18 +56,71 @@ func Logger(next http.Handler) http.Handler {
 			ResponseWriter: w,
 			statusCode:     http.StatusOK,
 		}
+		info := &requestLog{}
+		ctx := context.WithValue(r.Context(), requestLogKey, info)
 
 		// Call next handler
-		next.ServeHTTP(wrapped, r)
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
 
 		// Log request details
-		duration := time.Since(start)
-		log.Printf(
-			"%s %s %d %s",
-			r.Method,
-			r.URL.Path,
-			wrapped.statusCode,
-			duration,
-		)
+		level := slog.LevelInfo
+		if wrapped.statusCode >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", wrapped.statusCode),
+			slog.Int64("bytes", wrapped.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_ip", remoteIP(r)),
+			slog.String("request_id", GetRequestIDFromContext(ctx)),
+		}
+		if info.userID != 0 {
+			attrs = append(attrs, slog.Int("user_id", info.userID))
+		}
+		if info.actorID != 0 {
+			attrs = append(attrs, slog.Int("actor_id", info.actorID))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+		}
+		slog.LogAttrs(ctx, level, "request", attrs...)
 	})
 }
+
+// LoggerFromContext returns the default logger with the request ID and user
+// ID of the request, if any, so that log lines can be correlated with it
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if requestID := GetRequestIDFromContext(ctx); requestID != "" {
+		logger = logger.With("request_id", requestID)
+	}
+	if userID, ok := GetUserIDFromContext(ctx); ok {
+		logger = logger.With("user_id", userID)
+	}
+	return logger
+}
+
+// setLogUserID records the authenticated user, and the admin acting as the
+// user if any, for the request's log line
+func setLogUserID(ctx context.Context, userID, actorID int) {
+	if info, ok := ctx.Value(requestLogKey).(*requestLog); ok {
+		info.userID = userID
+		info.actorID = actorID
+	}
+}
+
+// remoteIP returns the client address, taken from X-Forwarded-For when the
+// server runs behind a proxy
+func remoteIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(ip)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
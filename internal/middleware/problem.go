@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// ProblemContentType is the media type of error responses
+const ProblemContentType = "application/problem+json"
+
+// WriteProblem sends p as the error response to r. Without a type the problem
+// is described by its status alone, and its instance is the request path
+// unless set. Server errors carry the request ID.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *models.Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.Status >= http.StatusInternalServerError && p.RequestID == "" {
+		p.RequestID = GetRequestIDFromContext(r.Context())
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
+
+// writeError sends a problem response with a status and a detail message
+func writeError(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, r, &models.Problem{Status: status, Detail: detail})
+}
+
+// NotFound reports requests for routes that do not exist
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, "No route matches the request")
+}
+
+// MethodNotAllowed reports requests with a method a route does not support
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed for the route")
+}
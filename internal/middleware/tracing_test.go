@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	spanExporter     = tracetest.NewInMemoryExporter()
+	spanProvider     *sdktrace.TracerProvider
+	installProvider  sync.Once
+	incomingTrace, _ = trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	incomingSpan, _  = trace.SpanIDFromHex("00f067aa0ba902b7")
+)
+
+// recordSpans installs a tracer provider exporting to memory, once, since the
+// package tracer keeps delegating to the first provider installed. It returns
+// a function that returns the spans ended since the call.
+func recordSpans(t *testing.T) func() tracetest.SpanStubs {
+	t.Helper()
+
+	installProvider.Do(func() {
+		spanProvider = tracing.NewProvider(spanExporter, config.TracingConfig{ServiceName: "worktrack-test", SampleRatio: 1})
+		otel.SetTracerProvider(spanProvider)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	spanExporter.Reset()
+
+	return func() tracetest.SpanStubs {
+		t.Helper()
+		if err := spanProvider.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return spanExporter.GetSpans()
+	}
+}
+
+func TestTracing(t *testing.T) {
+	// The handler sees the request's span, so calls it makes continue the trace
+	var outgoing http.Header
+	r := chi.NewRouter()
+	r.Use(RequestID)
+	r.Use(Tracing)
+	r.Get("/api/v1/track-items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		outgoing = http.Header{}
+		otel.GetTextMapPropagator().Inject(r.Context(), propagation.HeaderCarrier(outgoing))
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Post("/api/v1/sync", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		traceparent string
+		wantName    string
+		wantRoute   string
+		wantStatus  int
+		wantCode    codes.Code
+	}{
+		{
+			name:        "continues the trace of the client",
+			method:      http.MethodGet,
+			path:        "/api/v1/track-items/42",
+			traceparent: "00-" + incomingTrace.String() + "-" + incomingSpan.String() + "-01",
+			wantName:    "GET /api/v1/track-items/{id}",
+			wantRoute:   "/api/v1/track-items/{id}",
+			wantStatus:  http.StatusNotFound,
+			wantCode:    codes.Unset,
+		},
+		{
+			name:       "server error",
+			method:     http.MethodPost,
+			path:       "/api/v1/sync",
+			wantName:   "POST /api/v1/sync",
+			wantRoute:  "/api/v1/sync",
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   codes.Error,
+		},
+		{
+			name:       "no route",
+			method:     http.MethodGet,
+			path:       "/unknown",
+			wantName:   "GET",
+			wantStatus: http.StatusNotFound,
+			wantCode:   codes.Unset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := recordSpans(t)
+			outgoing = nil
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(RequestIDHeader, "req-1234")
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			got := spans()
+			if len(got) != 1 {
+				t.Fatalf("got %d spans, want 1", len(got))
+			}
+			span := got[0]
+
+			if span.Name != tt.wantName {
+				t.Errorf("got span name %q, want %q", span.Name, tt.wantName)
+			}
+			if span.SpanKind != trace.SpanKindServer {
+				t.Errorf("got span kind %v, want server", span.SpanKind)
+			}
+			if span.Status.Code != tt.wantCode {
+				t.Errorf("got span status %v, want %v", span.Status.Code, tt.wantCode)
+			}
+
+			attrs := attribute.NewSet(span.Attributes...)
+			if status, _ := attrs.Value("http.response.status_code"); status.AsInt64() != int64(tt.wantStatus) {
+				t.Errorf("got status code attribute %v, want %d", status.Emit(), tt.wantStatus)
+			}
+			if route, ok := attrs.Value("http.route"); route.AsString() != tt.wantRoute || ok != (tt.wantRoute != "") {
+				t.Errorf("got route attribute %q, want %q", route.AsString(), tt.wantRoute)
+			}
+			if path, _ := attrs.Value("url.path"); path.AsString() != tt.path {
+				t.Errorf("got path attribute %q, want %q", path.AsString(), tt.path)
+			}
+			if id, _ := attrs.Value("request_id"); id.AsString() != "req-1234" {
+				t.Errorf("got request ID attribute %q, want req-1234", id.AsString())
+			}
+
+			if tt.traceparent == "" {
+				if span.Parent.IsValid() {
+					t.Errorf("got parent %v, want a new trace", span.Parent)
+				}
+				return
+			}
+			if span.SpanContext.TraceID() != incomingTrace || span.Parent.SpanID() != incomingSpan || !span.Parent.IsRemote() {
+				t.Errorf("got trace %s with parent %s, want the client's trace %s and span %s",
+					span.SpanContext.TraceID(), span.Parent.SpanID(), incomingTrace, incomingSpan)
+			}
+			want := "00-" + incomingTrace.String() + "-" + span.SpanContext.SpanID().String() + "-01"
+			if got := outgoing.Get("traceparent"); got != want {
+				t.Errorf("the handler propagates traceparent %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// Data export statuses
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+	DataExportExpired = "expired" // The archive was deleted after its download period
+)
+
+// DataExport represents an archive of all of a user's data prepared for download
+type DataExport struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"-"`
+	Status      string     `json:"status"`
+	TokenHash   string     `json:"-"`
+	FilePath    string     `json:"-"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // The download link stops working at this time
+}
+
+// DataExportResponse represents a newly requested data export
+type DataExportResponse struct {
+	DataExport
+	DownloadURL string `json:"download_url"` // Works once the status is "ready"
+}
+
+// DeleteAccountRequest represents the confirmation needed to delete an account
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// DeleteAccountResponse represents a scheduled account deletion
+type DeleteAccountResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
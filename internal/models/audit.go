@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit log actions
+const (
+	AuditExportRequested   = "account.export_requested"
+	AuditExportDownloaded  = "account.export_downloaded"
+	AuditDeletionScheduled = "account.deletion_scheduled"
+	AuditDeletionCancelled = "account.deletion_cancelled"
+	AuditUsersListed       = "user.listed"
+	AuditUserViewed        = "user.viewed"
+	AuditUserCreated       = "user.created"
+	AuditPasswordReset     = "user.password_reset"
+	AuditRoleChanged       = "user.role_changed"
+	AuditUserDisabled      = "user.disabled"
+	AuditUserEnabled       = "user.enabled"
+	AuditUserDeleted       = "user.deleted"
+	AuditImpersonation     = "impersonation.started"
+	AuditImpersonatedCall  = "impersonation.request"
+)
+
+// AuditEntry represents an action recorded in the audit log
+type AuditEntry struct {
+	ID        int       `json:"id"`
+	UserID    *int      `json:"user_id,omitempty"`  // Account the entry is about
+	ActorID   *int      `json:"actor_id,omitempty"` // User who performed the action
+	Action    string    `json:"action"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// Backup describes a stored copy of the database
+type Backup struct {
+	Key       string    `json:"key"` // Storage key, used to restore the backup
+	Size      int64     `json:"size"`
+	Encrypted bool      `json:"encrypted"`
+	CreatedAt time.Time `json:"created_at"`
+}
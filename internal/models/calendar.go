@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarToken represents the secret token of a user's calendar feed. The
+// token itself is only known when it is generated.
+type CalendarToken struct {
+	ID         int        `json:"-"`
+	UserID     int        `json:"-"`
+	TokenHash  string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // Last time a calendar client fetched the feed
+}
+
+// CalendarTokenResponse represents a newly generated calendar feed token
+type CalendarTokenResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	WebcalURL string    `json:"webcal_url"` // Opens the subscription dialog of calendar apps
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CalendarTokenStatus represents whether a user's calendar feed is enabled
+type CalendarTokenStatus struct {
+	Enabled bool `json:"enabled"`
+	*CalendarToken
+}
@@ -0,0 +1,22 @@
+package models
+
+// Health check statuses
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+	// HealthStatusUnknown marks a check that could not tell; it does not fail readiness
+	HealthStatusUnknown = "unknown"
+)
+
+// HealthCheck is the outcome of one readiness check
+type HealthCheck struct {
+	Status     string  `json:"status"`
+	Message    string  `json:"message,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// HealthReport is the response of the readiness endpoint
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyKey records a request sent with an Idempotency-Key header and,
+// once it has completed, its response
+type IdempotencyKey struct {
+	UserID         int
+	Key            string
+	RequestHash    string      // SHA-256 of the method, path and body
+	StatusCode     int         // 0 while the request is being processed
+	ResponseHeader http.Header // Only the headers replayed with the response
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+}
+
+// Completed reports whether the response has been stored
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -0,0 +1,49 @@
+package models
+
+// Import duplicate strategies
+const (
+	ImportDuplicatesSkip   = "skip"   // Leave out rows that duplicate an existing item (default)
+	ImportDuplicatesImport = "import" // Import duplicates as additional items
+)
+
+// Import date formats
+const (
+	ImportDateISO = "iso" // 2024-01-20, 2024-01-20 08:00 or RFC3339 (default)
+	ImportDateDMY = "dmy" // 20.01.2024 or 20/01/2024, optionally followed by 08:00
+	ImportDateMDY = "mdy" // 01/20/2024, optionally followed by 08:00
+)
+
+// ImportTrackItemsRequest represents the options of a track item import
+type ImportTrackItemsRequest struct {
+	// Mapping maps track item fields (date, time, type, working_hours,
+	// working_shifts, emergency_call, holiday_call) to column headers. Fields
+	// left out are read from the column with the same name, if any.
+	Mapping    map[string]string `json:"mapping,omitempty"`
+	DateFormat string            `json:"date_format,omitempty"`
+	TimeZone   string            `json:"time_zone,omitempty"` // Time zone of dates without an offset
+	Duplicates string            `json:"duplicates,omitempty"`
+	Confirm    bool              `json:"confirm"` // Store the items; otherwise only the preview is returned
+}
+
+// ImportRow represents the outcome of one data row of an import
+type ImportRow struct {
+	Row       int        `json:"row"` // Line number in the file, the header being line 1
+	Item      *TrackItem `json:"item,omitempty"`
+	Errors    []string   `json:"errors,omitempty"`
+	Duplicate bool       `json:"duplicate"`
+	// DuplicateOf is the existing item the row duplicates, or the earlier row of
+	// the file when DuplicateRow is set instead
+	DuplicateOf  *int `json:"duplicate_of,omitempty"`
+	DuplicateRow *int `json:"duplicate_row,omitempty"`
+}
+
+// ImportTrackItemsResponse represents the preview or result of an import
+type ImportTrackItemsResponse struct {
+	Confirmed  bool        `json:"confirmed"`
+	Total      int         `json:"total"`
+	Valid      int         `json:"valid"`
+	Invalid    int         `json:"invalid"`
+	Duplicates int         `json:"duplicates"`
+	Imported   int         `json:"imported"`
+	Rows       []ImportRow `json:"rows"`
+}
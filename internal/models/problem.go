@@ -0,0 +1,30 @@
+package models
+
+// Codes of field errors, telling clients why a field is invalid
+const (
+	FieldRequired      = "required"       // The field is missing or empty
+	FieldInvalidFormat = "invalid_format" // The value cannot be parsed, e.g. a malformed date
+	FieldInvalidValue  = "invalid_value"  // The value is well-formed but not one of the accepted ones
+	FieldOutOfRange    = "out_of_range"   // The value is too small, too large or too many
+	FieldTooShort      = "too_short"      // The value is shorter than allowed
+	FieldConflict      = "conflict"       // The value contradicts another field
+)
+
+// FieldError describes why one field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+// Problem is an error response in the problem details format of RFC 7807,
+// sent as application/problem+json
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"` // Set on server errors, to find them in the logs
+}
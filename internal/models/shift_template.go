@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// ShiftTemplate represents a reusable shift definition with an optional recurrence rule
+type ShiftTemplate struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	Name          string    `json:"name"`
+	Type          string    `json:"type"`
+	EmergencyCall bool      `json:"emergency_call"`
+	HolidayCall   bool      `json:"holiday_call"`
+	WorkingHours  float64   `json:"working_hours"`
+	WorkingShifts float64   `json:"working_shifts"`
+	StartTime     string    `json:"start_time"`           // Time of day of generated items: "HH:MM"
+	Recurrence    string    `json:"recurrence,omitempty"` // RRULE subset, e.g. "FREQ=WEEKLY;BYDAY=MO,WE,FR"
+	AnchorDate    string    `json:"anchor_date"`          // First day of the recurrence: "YYYY-MM-DD"
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CreateShiftTemplateRequest represents the data needed to create a shift template
+type CreateShiftTemplateRequest struct {
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	EmergencyCall bool    `json:"emergency_call"`
+	HolidayCall   bool    `json:"holiday_call"`
+	WorkingHours  float64 `json:"working_hours"`
+	WorkingShifts float64 `json:"working_shifts"`
+	StartTime     string  `json:"start_time,omitempty"` // Defaults to "00:00"
+	Recurrence    string  `json:"recurrence,omitempty"`
+	AnchorDate    string  `json:"anchor_date,omitempty"` // Defaults to today
+}
+
+// UpdateShiftTemplateRequest represents the data needed to update a shift template
+type UpdateShiftTemplateRequest struct {
+	Name          *string  `json:"name,omitempty"`
+	Type          *string  `json:"type,omitempty"`
+	EmergencyCall *bool    `json:"emergency_call,omitempty"`
+	HolidayCall   *bool    `json:"holiday_call,omitempty"`
+	WorkingHours  *float64 `json:"working_hours,omitempty"`
+	WorkingShifts *float64 `json:"working_shifts,omitempty"`
+	StartTime     *string  `json:"start_time,omitempty"`
+	Recurrence    *string  `json:"recurrence,omitempty"`
+	AnchorDate    *string  `json:"anchor_date,omitempty"`
+}
+
+// ApplyShiftTemplateRequest represents a request to generate track items from a template
+type ApplyShiftTemplateRequest struct {
+	StartDate  string `json:"start_date"`           // YYYY-MM-DD
+	EndDate    string `json:"end_date"`             // YYYY-MM-DD, inclusive
+	Recurrence string `json:"recurrence,omitempty"` // Overrides the template recurrence
+	TimeZone   string `json:"time_zone,omitempty"`  // Overrides the user's time zone
+	DryRun     bool   `json:"dry_run"`
+}
+
+// ApplyShiftTemplateResponse lists the track items generated from a template. On a
+// dry run nothing is stored and the created items have no ID.
+type ApplyShiftTemplateResponse struct {
+	DryRun  bool        `json:"dry_run"`
+	Created []TrackItem `json:"created"`
+	Skipped []TrackItem `json:"skipped"` // Days that already have an item of the same type
+}
@@ -0,0 +1,128 @@
+package models
+
+import "time"
+
+// Track item fields versioned for sync
+const (
+	FieldType          = "type"
+	FieldEmergencyCall = "emergency_call"
+	FieldHolidayCall   = "holiday_call"
+	FieldWorkingHours  = "working_hours"
+	FieldWorkingShifts = "working_shifts"
+	FieldDate          = "date"
+)
+
+// TrackItemFields lists the track item fields versioned for sync
+var TrackItemFields = []string{FieldType, FieldEmergencyCall, FieldHolidayCall, FieldWorkingHours, FieldWorkingShifts, FieldDate}
+
+// FieldChange records the last change to a track item field
+type FieldChange struct {
+	Version int64     `json:"v"`
+	At      time.Time `json:"at"`
+}
+
+// FieldClock maps track item fields to their last change
+type FieldClock map[string]FieldChange
+
+// FieldValue returns the value of a track item field as sent in JSON, or nil
+// for an unknown field
+func (t *TrackItem) FieldValue(field string) any {
+	switch field {
+	case FieldType:
+		return t.Type
+	case FieldEmergencyCall:
+		return t.EmergencyCall
+	case FieldHolidayCall:
+		return t.HolidayCall
+	case FieldWorkingHours:
+		return t.WorkingHours
+	case FieldWorkingShifts:
+		return t.WorkingShifts
+	case FieldDate:
+		return t.Date.UTC().Format(time.RFC3339Nano)
+	}
+	return nil
+}
+
+// Sync change operations
+const (
+	SyncOpUpsert = "upsert"
+	SyncOpDelete = "delete"
+)
+
+// Sync conflict modes
+const (
+	SyncConflictLWW    = "lww"    // Resolve each conflicting field by the latest change
+	SyncConflictReport = "report" // Keep the server value and report the conflict
+)
+
+// Sync change statuses
+const (
+	SyncStatusCreated  = "created"
+	SyncStatusUpdated  = "updated"
+	SyncStatusDeleted  = "deleted"
+	SyncStatusConflict = "conflict" // Some or all of the change was not applied
+	SyncStatusError    = "error"
+)
+
+// TrackItemTombstone records a deleted track item
+type TrackItemTombstone struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	ClientID  string    `json:"client_id,omitempty"`
+	Version   int64     `json:"version"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SyncResponse represents the track item changes since a sync cursor
+type SyncResponse struct {
+	Items   []TrackItem          `json:"items"`   // Created or updated items, by version
+	Deleted []TrackItemTombstone `json:"deleted"` // Deleted items, by version
+	Cursor  string               `json:"cursor"`  // Pass as since to get later changes
+	HasMore bool                 `json:"has_more"`
+}
+
+// SyncChange represents a change made by a client, possibly while offline.
+// The item is identified by its client ID or, for items created elsewhere,
+// its server ID.
+type SyncChange struct {
+	ClientID    string                 `json:"client_id,omitempty"`
+	ID          int                    `json:"id,omitempty"`
+	Op          string                 `json:"op"`           // "upsert" or "delete"
+	BaseVersion int64                  `json:"base_version"` // Version of the item the change was made on, 0 for new items
+	ChangedAt   time.Time              `json:"changed_at"`   // When the change was made on the client
+	Fields      UpdateTrackItemRequest `json:"fields"`       // Changed fields; all of type and date for new items
+}
+
+// SyncRequest represents a list of client changes
+type SyncRequest struct {
+	Conflict string       `json:"conflict,omitempty"` // "lww" (default) or "report"
+	Changes  []SyncChange `json:"changes"`
+}
+
+// SyncConflict reports a field changed both on the server and by the client
+type SyncConflict struct {
+	Field       string `json:"field"`
+	ServerValue any    `json:"server_value"`
+	ClientValue any    `json:"client_value"`
+	Winner      string `json:"winner"` // "server" or "client"
+}
+
+// SyncChangeResult reports the outcome of a client change
+type SyncChangeResult struct {
+	Index     int            `json:"index"`
+	ClientID  string         `json:"client_id,omitempty"`
+	ID        int            `json:"id,omitempty"`
+	Status    string         `json:"status"`
+	Item      *TrackItem     `json:"item,omitempty"`    // Current server state of the item
+	Deleted   bool           `json:"deleted,omitempty"` // The item was deleted on the server
+	Conflicts []SyncConflict `json:"conflicts,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Errors    []FieldError   `json:"errors,omitempty"` // Invalid fields of the change
+	Err       error          `json:"-"`                // Why the change failed, mapped to Error and Errors by the handler
+}
+
+// SyncPushResponse represents the outcome of a list of client changes
+type SyncPushResponse struct {
+	Results []SyncChangeResult `json:"results"`
+}
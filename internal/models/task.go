@@ -16,6 +16,11 @@ type TrackItem struct {
 	Date          time.Time `json:"date"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
+	ClientID      string    `json:"client_id,omitempty"` // Set on items created through sync
+	Version       int64     `json:"version"`             // Sync version of the last change
+
+	FieldClock FieldClock `json:"-"` // When each field last changed
+	ChangedAt  time.Time  `json:"-"` // When the change being saved was made, if not now
 }
 
 // CreateTrackItemRequest represents the data needed to create a new track item
@@ -43,3 +48,124 @@ type DateRangeQuery struct {
 	StartDate string `json:"start_date"` // ISO 8601 format: "2024-01-20"
 	EndDate   string `json:"end_date"`   // ISO 8601 format: "2024-01-25"
 }
+
+// TrackItemSummary aggregates totals over a set of track items
+type TrackItemSummary struct {
+	Items          int     `json:"items"`
+	WorkingHours   float64 `json:"working_hours"`
+	WorkingShifts  float64 `json:"working_shifts"`
+	EmergencyCalls int     `json:"emergency_calls"`
+	HolidayCalls   int     `json:"holiday_calls"`
+}
+
+// Add adds a track item to the totals
+func (s *TrackItemSummary) Add(item *TrackItem) {
+	s.Items++
+	s.WorkingHours += item.WorkingHours
+	s.WorkingShifts += item.WorkingShifts
+	if item.EmergencyCall {
+		s.EmergencyCalls++
+	}
+	if item.HolidayCall {
+		s.HolidayCalls++
+	}
+}
+
+// SummaryResponse represents the totals of a user's track items over a date range
+type SummaryResponse struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	TimeZone  string `json:"time_zone"`
+	TrackItemSummary
+}
+
+// ListTrackItemsQuery represents the query parameters accepted when listing track items
+type ListTrackItemsQuery struct {
+	StartDate     string // YYYY-MM-DD
+	EndDate       string // YYYY-MM-DD
+	Type          string // Comma-separated list of types
+	EmergencyCall string // "true" or "false"
+	HolidayCall   string // "true" or "false"
+	MinHours      string
+	MaxHours      string
+	Sort          string // "date", "-date" (default), "working_hours" or "-working_hours"
+	Limit         string
+	Cursor        string // Opaque value taken from TrackItemPage.NextCursor
+	IncludeTotal  string // "true" to count all matching items
+	TimeZone      string // Overrides the user's time zone for the date bounds
+}
+
+// TrackItemPage represents one page of a paginated track item list
+type TrackItemPage struct {
+	Items      []TrackItem `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"` // Empty on the last page
+	Total      *int        `json:"total,omitempty"`       // Only set when requested
+}
+
+// Conflict strategies for copying track items onto days that already have items of the same type
+const (
+	CopyConflictSkip      = "skip"      // Keep the existing item and do not copy
+	CopyConflictOverwrite = "overwrite" // Replace the existing item with the copy
+	CopyConflictFail      = "fail"      // Abort the whole copy
+)
+
+// CopyTrackItemsRequest represents a request to duplicate a range of track items
+type CopyTrackItemsRequest struct {
+	SourceStartDate string `json:"source_start_date"`   // YYYY-MM-DD
+	SourceEndDate   string `json:"source_end_date"`     // YYYY-MM-DD, inclusive
+	TargetStartDate string `json:"target_start_date"`   // YYYY-MM-DD
+	Conflict        string `json:"conflict,omitempty"`  // "skip" (default), "overwrite" or "fail"
+	TimeZone        string `json:"time_zone,omitempty"` // Overrides the user's time zone
+}
+
+// CopyTrackItemsResponse represents the result of copying track items
+type CopyTrackItemsResponse struct {
+	Created     []TrackItem `json:"created"`
+	Skipped     []TrackItem `json:"skipped"`     // Source items not copied because of a conflict
+	Overwritten int         `json:"overwritten"` // Number of existing items replaced
+}
+
+// Batch modes accepted by the batch endpoint
+const (
+	BatchModeAtomic     = "atomic"      // All operations succeed or none are applied
+	BatchModeBestEffort = "best_effort" // Each operation is applied independently
+)
+
+// Batch operation kinds
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// BatchOperation represents a single create, update or delete within a batch
+type BatchOperation struct {
+	Op      string                  `json:"op"`                // "create", "update" or "delete"
+	ID      int                     `json:"id,omitempty"`      // Required for update and delete
+	Item    *CreateTrackItemRequest `json:"item,omitempty"`    // Required for create
+	Changes *UpdateTrackItemRequest `json:"changes,omitempty"` // Required for update
+}
+
+// BatchTrackItemsRequest represents a list of track item operations applied together
+type BatchTrackItemsRequest struct {
+	Mode       string           `json:"mode"` // "atomic" (default) or "best_effort"
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperationResult reports the outcome of a single batch operation
+type BatchOperationResult struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	Status int          `json:"status"` // HTTP status the operation would have returned on its own
+	Item   *TrackItem   `json:"item,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"` // Invalid fields of the operation
+}
+
+// BatchTrackItemsResponse represents the response of a batch request
+type BatchTrackItemsResponse struct {
+	Mode      string                 `json:"mode"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []BatchOperationResult `json:"results"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// Timer event types, sent on the live event stream only
+const (
+	EventTimerStarted = "timer.started"
+	EventTimerStopped = "timer.stopped"
+)
+
+// Timer is the shift a user is clocked into. Stopping it records the time
+// worked as a track item.
+type Timer struct {
+	UserID        int        `json:"user_id"`
+	Type          string     `json:"type"`
+	EmergencyCall bool       `json:"emergency_call"`
+	HolidayCall   bool       `json:"holiday_call"`
+	WorkingShifts float64    `json:"working_shifts"`
+	StartedAt     time.Time  `json:"started_at"`
+	StoppedAt     *time.Time `json:"stopped_at,omitempty"`    // Set once stopped
+	TrackItemID   *int       `json:"track_item_id,omitempty"` // Track item recorded when stopped
+}
+
+// StartTimerRequest represents the data needed to start a timer
+type StartTimerRequest struct {
+	Type          string  `json:"type"`
+	EmergencyCall bool    `json:"emergency_call"`
+	HolidayCall   bool    `json:"holiday_call"`
+	WorkingShifts float64 `json:"working_shifts"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// TimesheetDay represents one calendar day of a timesheet
+type TimesheetDay struct {
+	Date  time.Time   `json:"date"` // Midnight in the timesheet's time zone
+	Items []TrackItem `json:"items"`
+	TrackItemSummary
+}
+
+// Types returns the distinct track item types of the day in order of appearance
+func (d *TimesheetDay) Types() []string {
+	var types []string
+	seen := make(map[string]bool)
+	for _, item := range d.Items {
+		if !seen[item.Type] {
+			seen[item.Type] = true
+			types = append(types, item.Type)
+		}
+	}
+	return types
+}
+
+// Timesheet represents a user's track items laid out day by day over a date range
+type Timesheet struct {
+	User      User             `json:"user"`
+	StartDate time.Time        `json:"start_date"`
+	EndDate   time.Time        `json:"end_date"`
+	TimeZone  string           `json:"time_zone"`
+	Days      []TimesheetDay   `json:"days"` // One entry per calendar day, including empty days
+	Totals    TrackItemSummary `json:"totals"`
+}
+
+// TimesheetApproval records a supervisor's sign-off of a user's timesheet for
+// a month, with the totals that were approved
+type TimesheetApproval struct {
+	ID         int              `json:"id"`
+	UserID     int              `json:"user_id"`
+	Month      string           `json:"month"`       // YYYY-MM
+	ApprovedBy *int             `json:"approved_by"` // Unset once the approver's account is deleted
+	ApprovedAt time.Time        `json:"approved_at"`
+	Totals     TrackItemSummary `json:"totals"` // In the user's time zone
+}
+
+// ApproveTimesheetRequest represents the request to approve a user's timesheet
+type ApproveTimesheetRequest struct {
+	UserID int    `json:"user_id"`
+	Month  string `json:"month"` // YYYY-MM
+}
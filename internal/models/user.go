@@ -4,6 +4,13 @@ import (
 	"time"
 )
 
+// User roles
+const (
+	RoleUser       = "user"
+	RoleSupervisor = "supervisor" // Can export the timesheets of the whole team
+	RoleAdmin      = "admin"
+)
+
 // User represents a user in the system
 type User struct {
 	ID           int       `json:"id"`
@@ -11,9 +18,33 @@ type User struct {
 	LastName     string    `json:"last_name"`
 	Avatar       string    `json:"avatar,omitempty"` // URL or path to avatar image
 	Login        string    `json:"login"`
-	PasswordHash string    `json:"-"` // Never expose password hash in JSON
+	PasswordHash string    `json:"-"`         // Never expose password hash in JSON
+	TimeZone     string    `json:"time_zone"` // IANA time zone dates are interpreted in, e.g. "Europe/Moscow"
+	Role         string    `json:"role"`      // "user", "supervisor" or "admin"
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	// DeletionScheduledAt is set while the account is waiting to be deleted
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	// DisabledAt is set while the account is disabled and cannot log in
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	// PasswordChangeRequired is set while the user has a temporary password
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+	TokenVersion           int  `json:"-"` // Incremented when the password is reset or the role changes, revoking older tokens
+}
+
+// ValidRole reports whether role is one of the user roles
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleSupervisor || role == RoleAdmin
+}
+
+// IsDisabled reports whether the account is disabled
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// IsSupervisor reports whether the user may see the data of other team members
+func (u *User) IsSupervisor() bool {
+	return u.Role == RoleSupervisor || u.Role == RoleAdmin
 }
 
 // UserRegistration represents the data needed to register a new user
@@ -23,6 +54,15 @@ type UserRegistration struct {
 	Login     string `json:"login"`
 	Password  string `json:"password"`
 	Avatar    string `json:"avatar,omitempty"`
+	TimeZone  string `json:"time_zone,omitempty"` // Defaults to "UTC"
+}
+
+// UpdateProfileRequest represents the profile fields a user can change
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+	Avatar    *string `json:"avatar,omitempty"`
+	TimeZone  *string `json:"time_zone,omitempty"`
 }
 
 // UserLogin represents the data needed to log in
@@ -31,8 +71,70 @@ type UserLogin struct {
 	Password string `json:"password"`
 }
 
+// PasswordChange represents the data needed to replace a password, such as a
+// temporary one set by an admin
+type PasswordChange struct {
+	Login       string `json:"login"`
+	Password    string `json:"password"`
+	NewPassword string `json:"new_password"`
+}
+
 // AuthResponse represents the response after successful authentication
 type AuthResponse struct {
 	Token string `json:"token"`
 	User  User   `json:"user"`
 }
+
+// ImpersonationRequest represents the request of an admin to act as a user.
+// The session is read-only unless writes are allowed.
+type ImpersonationRequest struct {
+	AllowWrites bool   `json:"allow_writes"`
+	Reason      string `json:"reason"`
+}
+
+// Impersonation describes a session in which an admin acts as a user
+type Impersonation struct {
+	ActorID   int       `json:"actor_id"`
+	UserID    int       `json:"user_id"`
+	ReadOnly  bool      `json:"read_only"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ImpersonationResponse represents the token an admin acts as a user with
+type ImpersonationResponse struct {
+	Token         string        `json:"token"`
+	User          User          `json:"user"`
+	Impersonation Impersonation `json:"impersonation"`
+}
+
+// ListUsersQuery represents the query parameters accepted when admins list users
+type ListUsersQuery struct {
+	Search   string // Matched against login, first and last name
+	Role     string
+	Disabled string // "true" or "false"
+	Limit    string
+	Cursor   string // Opaque value taken from UserPage.NextCursor
+}
+
+// UserPage represents one page of the user list, ordered by ID
+type UserPage struct {
+	Items      []User `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"` // Empty on the last page
+	Total      int    `json:"total"`                 // Number of users matching the query
+}
+
+// SetRoleRequest represents a change of a user's role by an admin
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// PasswordResetRequest represents a password reset by an admin. Without a
+// password a temporary one is generated.
+type PasswordResetRequest struct {
+	Password string `json:"password,omitempty"`
+}
+
+// PasswordResetResponse returns a generated temporary password once
+type PasswordResetResponse struct {
+	TemporaryPassword string `json:"temporary_password,omitempty"`
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// AuditRepository handles database operations for the audit log
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create appends an entry to the audit log
+func (r *AuditRepository) Create(ctx context.Context, entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (user_id, actor_id, action, details, created_at)
+		VALUES (?, ?, ?, ?, datetime('now'))
+	`
+
+	result, err := r.db.ExecContext(ctx, query, entry.UserID, entry.ActorID, entry.Action, entry.Details)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	entry.ID = int(id)
+
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM audit_log WHERE id = ?", entry.ID).
+		Scan(&entry.CreatedAt)
+}
+
+// FindByUserID retrieves the entries about a user, oldest first
+func (r *AuditRepository) FindByUserID(ctx context.Context, userID int) ([]models.AuditEntry, error) {
+	query := `
+		SELECT id, user_id, actor_id, action, details, created_at
+		FROM audit_log
+		WHERE user_id = ?
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		var subjectID, actorID sql.NullInt64
+		if err := rows.Scan(&entry.ID, &subjectID, &actorID, &entry.Action, &entry.Details, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.UserID = nullableID(subjectID)
+		entry.ActorID = nullableID(actorID)
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+
+	return entries, nil
+}
+
+func nullableID(id sql.NullInt64) *int {
+	if !id.Valid {
+		return nil
+	}
+	value := int(id.Int64)
+	return &value
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+var (
+	ErrCalendarTokenNotFound = errors.New("calendar token not found")
+)
+
+// CalendarTokenRepository handles database operations for calendar feed tokens
+type CalendarTokenRepository struct {
+	db *sql.DB
+}
+
+// NewCalendarTokenRepository creates a new calendar token repository
+func NewCalendarTokenRepository(db *sql.DB) *CalendarTokenRepository {
+	return &CalendarTokenRepository{db: db}
+}
+
+// Replace stores a user's token, replacing the previous one
+func (r *CalendarTokenRepository) Replace(ctx context.Context, token *models.CalendarToken) error {
+	query := `
+		INSERT INTO calendar_tokens (user_id, token_hash, created_at)
+		VALUES (?, ?, datetime('now'))
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at, last_used_at = NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, token.UserID, token.TokenHash); err != nil {
+		return fmt.Errorf("failed to store calendar token: %w", err)
+	}
+
+	return r.db.QueryRowContext(ctx, "SELECT id, created_at FROM calendar_tokens WHERE user_id = ?", token.UserID).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+// FindByUserID retrieves a user's token
+func (r *CalendarTokenRepository) FindByUserID(ctx context.Context, userID int) (*models.CalendarToken, error) {
+	return r.findOne(ctx, "user_id = ?", userID)
+}
+
+// FindByHash retrieves the token with the given hash
+func (r *CalendarTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.CalendarToken, error) {
+	return r.findOne(ctx, "token_hash = ?", tokenHash)
+}
+
+func (r *CalendarTokenRepository) findOne(ctx context.Context, where string, arg interface{}) (*models.CalendarToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, created_at, last_used_at
+		FROM calendar_tokens
+		WHERE ` + where
+
+	var token models.CalendarToken
+	var lastUsedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, arg).
+		Scan(&token.ID, &token.UserID, &token.TokenHash, &token.CreatedAt, &lastUsedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCalendarTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to find calendar token: %w", err)
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &token, nil
+}
+
+// Touch records that the token was used
+func (r *CalendarTokenRepository) Touch(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE calendar_tokens SET last_used_at = datetime('now') WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to update calendar token: %w", err)
+	}
+	return nil
+}
+
+// DeleteByUserID removes a user's token
+func (r *CalendarTokenRepository) DeleteByUserID(ctx context.Context, userID int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM calendar_tokens WHERE user_id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar token: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrCalendarTokenNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+var (
+	ErrDataExportNotFound = errors.New("data export not found")
+)
+
+const dataExportColumns = "id, user_id, status, token_hash, file_path, error, created_at, completed_at, expires_at"
+
+// DataExportRepository handles database operations for account data exports
+type DataExportRepository struct {
+	db *sql.DB
+}
+
+// NewDataExportRepository creates a new data export repository
+func NewDataExportRepository(db *sql.DB) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+// Create inserts a new pending export
+func (r *DataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	query := `
+		INSERT INTO data_exports (user_id, status, token_hash, created_at)
+		VALUES (?, ?, ?, datetime('now'))
+	`
+
+	result, err := r.db.ExecContext(ctx, query, export.UserID, export.Status, export.TokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	export.ID = int(id)
+
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM data_exports WHERE id = ?", export.ID).
+		Scan(&export.CreatedAt)
+}
+
+// FindByID retrieves an export by ID
+func (r *DataExportRepository) FindByID(ctx context.Context, id int) (*models.DataExport, error) {
+	return r.findOne(ctx, "id = ?", id)
+}
+
+// FindByTokenHash retrieves the export with the given download token hash
+func (r *DataExportRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.DataExport, error) {
+	return r.findOne(ctx, "token_hash = ?", tokenHash)
+}
+
+// FindPendingByUserID retrieves a user's export that is still being prepared
+func (r *DataExportRepository) FindPendingByUserID(ctx context.Context, userID int) (*models.DataExport, error) {
+	return r.findOne(ctx, "user_id = ? AND status = 'pending'", userID)
+}
+
+func (r *DataExportRepository) findOne(ctx context.Context, where string, arg interface{}) (*models.DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE ` + where + ` LIMIT 1`
+
+	export, err := scanDataExport(r.db.QueryRowContext(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, fmt.Errorf("failed to find data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// FindExpired retrieves ready exports whose download period has ended, and
+// pending exports created before staleBefore, which were interrupted by a restart
+func (r *DataExportRepository) FindExpired(ctx context.Context, now, staleBefore time.Time) ([]models.DataExport, error) {
+	query := `
+		SELECT ` + dataExportColumns + `
+		FROM data_exports
+		WHERE (status = 'ready' AND expires_at <= ?) OR (status = 'pending' AND created_at <= ?)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now.UTC(), staleBefore.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []models.DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data export: %w", err)
+		}
+		exports = append(exports, *export)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating data exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+// FindByUserID retrieves all exports of a user
+func (r *DataExportRepository) FindByUserID(ctx context.Context, userID int) ([]models.DataExport, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+dataExportColumns+` FROM data_exports WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []models.DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data export: %w", err)
+		}
+		exports = append(exports, *export)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating data exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+// Complete records the outcome of building an export
+func (r *DataExportRepository) Complete(ctx context.Context, export *models.DataExport) error {
+	query := `
+		UPDATE data_exports
+		SET status = ?, file_path = ?, error = ?, completed_at = ?, expires_at = ?
+		WHERE id = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, export.Status, export.FilePath, export.Error, utcOrNil(export.CompletedAt), utcOrNil(export.ExpiresAt), export.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update data export: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes an export record
+func (r *DataExportRepository) Delete(ctx context.Context, id int) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM data_exports WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete data export: %w", err)
+	}
+	return nil
+}
+
+// scanDataExport scans a row of dataExportColumns
+func scanDataExport(row interface{ Scan(dest ...any) error }) (*models.DataExport, error) {
+	var export models.DataExport
+	var completedAt, expiresAt sql.NullTime
+	err := row.Scan(&export.ID, &export.UserID, &export.Status, &export.TokenHash, &export.FilePath, &export.Error, &export.CreatedAt, &completedAt, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+
+	return &export, nil
+}
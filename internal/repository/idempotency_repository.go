@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+var (
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyExists   = errors.New("idempotency key already exists")
+)
+
+const idempotencyKeyColumns = "user_id, key, request_hash, status_code, response_headers, response_body, created_at, expires_at"
+
+// IdempotencyRepository handles database operations for idempotency keys
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency repository
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Create reserves a key for a request being processed. It returns
+// ErrIdempotencyKeyExists when the user already holds the key.
+func (r *IdempotencyRepository) Create(ctx context.Context, key *models.IdempotencyKey) error {
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, request_hash, created_at, expires_at)
+		VALUES (?, ?, ?, datetime('now'), ?)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, key.UserID, key.Key, key.RequestHash, key.ExpiresAt.UTC())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrIdempotencyKeyExists
+		}
+		return fmt.Errorf("failed to create idempotency key: %w", err)
+	}
+
+	return r.db.QueryRowContext(ctx, "SELECT created_at FROM idempotency_keys WHERE user_id = ? AND key = ?", key.UserID, key.Key).
+		Scan(&key.CreatedAt)
+}
+
+// Find retrieves a user's key
+func (r *IdempotencyRepository) Find(ctx context.Context, userID int, key string) (*models.IdempotencyKey, error) {
+	query := `SELECT ` + idempotencyKeyColumns + ` FROM idempotency_keys WHERE user_id = ? AND key = ?`
+
+	var k models.IdempotencyKey
+	var statusCode sql.NullInt64
+	var header string
+	err := r.db.QueryRowContext(ctx, query, userID, key).
+		Scan(&k.UserID, &k.Key, &k.RequestHash, &statusCode, &header, &k.ResponseBody, &k.CreatedAt, &k.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to find idempotency key: %w", err)
+	}
+	k.StatusCode = int(statusCode.Int64)
+	if err := json.Unmarshal([]byte(header), &k.ResponseHeader); err != nil {
+		return nil, fmt.Errorf("invalid response headers: %w", err)
+	}
+
+	return &k, nil
+}
+
+// Complete stores the response of the request holding a key
+func (r *IdempotencyRepository) Complete(ctx context.Context, key *models.IdempotencyKey) error {
+	header, err := json.Marshal(key.ResponseHeader)
+	if err != nil {
+		return fmt.Errorf("failed to encode response headers: %w", err)
+	}
+
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = ?, response_headers = ?, response_body = ?
+		WHERE user_id = ? AND key = ?
+	`
+
+	_, err = r.db.ExecContext(ctx, query, key.StatusCode, string(header), key.ResponseBody, key.UserID, key.Key)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Delete releases a user's key
+func (r *IdempotencyRepository) Delete(ctx context.Context, userID int, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?", userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStale releases a user's key if it has expired, or if its request
+// started before abandonedBefore and never completed
+func (r *IdempotencyRepository) DeleteStale(ctx context.Context, userID int, key string, now, abandonedBefore time.Time) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = ? AND key = ? AND (expires_at <= ? OR (status_code IS NULL AND created_at <= ?))
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, key, now.UTC(), abandonedBefore.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to delete stale idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes all keys that expired by now and returns how many
+// were removed
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= ?", now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected()
+}
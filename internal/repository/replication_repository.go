@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// CheckpointMode is how a checkpoint treats readers and writers of the log
+type CheckpointMode string
+
+const (
+	// CheckpointPassive copies as many frames as it can without waiting
+	CheckpointPassive CheckpointMode = "PASSIVE"
+	// CheckpointTruncate waits for readers and writers, copies every frame
+	// and truncates the log
+	CheckpointTruncate CheckpointMode = "TRUNCATE"
+)
+
+// ReplicationRepository holds the locks the WAL replicator needs to copy the
+// write-ahead log without SQLite restarting it underneath
+type ReplicationRepository struct {
+	db  *sql.DB
+	pin *sql.Conn // Connection holding the read transaction of Pin
+}
+
+// NewReplicationRepository creates a new replication repository
+func NewReplicationRepository(db *sql.DB) *ReplicationRepository {
+	return &ReplicationRepository{db: db}
+}
+
+// Pin starts a read transaction on a dedicated connection and keeps it open
+// until Unpin, ending the one started before. While the transaction reads
+// from the log, checkpoints can copy frames into the database file but the
+// log is not restarted, so no frame is overwritten before it is replicated.
+func (r *ReplicationRepository) Pin(ctx context.Context) error {
+	if err := r.Unpin(); err != nil {
+		return err
+	}
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "BEGIN"); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to begin read transaction: %w", err)
+	}
+	var seq int64
+	if err := conn.QueryRowContext(ctx, "SELECT seq FROM replication_seq WHERE id = 1").Scan(&seq); err != nil {
+		discardConn(conn)
+		return fmt.Errorf("failed to read replication sequence: %w", err)
+	}
+
+	r.pin = conn
+	return nil
+}
+
+// Unpin ends the read transaction started by Pin
+func (r *ReplicationRepository) Unpin() error {
+	if r.pin == nil {
+		return nil
+	}
+
+	conn := r.pin
+	r.pin = nil
+	if _, err := conn.ExecContext(context.Background(), "ROLLBACK"); err != nil {
+		discardConn(conn)
+		return fmt.Errorf("failed to end read transaction: %w", err)
+	}
+
+	return conn.Close()
+}
+
+// Checkpoint copies frames of the log into the database file. It returns
+// the number of frames in the log and whether all of them were copied.
+func (r *ReplicationRepository) Checkpoint(ctx context.Context, mode CheckpointMode) (int, bool, error) {
+	var busy, frames, checkpointed int
+	err := r.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint("+string(mode)+")").Scan(&busy, &frames, &checkpointed)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+
+	return frames, busy == 0 && checkpointed == frames, nil
+}
+
+// Bump writes a transaction of the replicator's own. When every frame of the
+// log has been checkpointed, this write restarts the log.
+func (r *ReplicationRepository) Bump(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, "UPDATE replication_seq SET seq = seq + 1 WHERE id = 1"); err != nil {
+		return fmt.Errorf("failed to update replication sequence: %w", err)
+	}
+
+	return nil
+}
+
+// CopyPages writes a copy of the database to path, which must not exist yet,
+// with SQLite's online backup API. Unlike VACUUM INTO it keeps every page
+// where it is, so frames of the write-ahead log apply to the copy.
+func (r *ReplicationRepository) CopyPages(ctx context.Context, path string) error {
+	dst, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("failed to create database copy: %w", err)
+	}
+	defer dst.Close()
+
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create database copy: %w", err)
+	}
+	defer dstConn.Close()
+	srcConn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer srcConn.Close()
+
+	err = dstConn.Raw(func(dc any) error {
+		return srcConn.Raw(func(sc any) error {
+			d, ok := dc.(*sqlite3.SQLiteConn)
+			s, ok2 := sc.(*sqlite3.SQLiteConn)
+			if !ok || !ok2 {
+				return errors.New("not a SQLite connection")
+			}
+
+			backup, err := d.Backup("main", s, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+
+	return nil
+}
+
+// WriteLock is a write transaction that keeps every other writer waiting
+type WriteLock struct {
+	conn *sql.Conn
+}
+
+// LockWrites begins a write transaction on a dedicated connection, waiting
+// for the current writer to finish. Nothing is written to the log until the
+// lock is released.
+func (r *ReplicationRepository) LockWrites(ctx context.Context) (*WriteLock, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		discardConn(conn)
+		return nil, fmt.Errorf("failed to lock database for writing: %w", err)
+	}
+
+	return &WriteLock{conn: conn}, nil
+}
+
+// Release rolls the transaction back and releases the lock. Releasing a
+// released lock does nothing.
+func (l *WriteLock) Release() {
+	if l.conn == nil {
+		return
+	}
+
+	conn := l.conn
+	l.conn = nil
+	if _, err := conn.ExecContext(context.Background(), "ROLLBACK"); err != nil {
+		discardConn(conn)
+		return
+	}
+	conn.Close()
+}
+
+// discardConn closes conn instead of returning it to the pool, for a
+// connection whose transaction state is unknown
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
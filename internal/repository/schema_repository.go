@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrSchemaVersionUnknown is returned when the database does not record
+	// which migrations have been applied
+	ErrSchemaVersionUnknown = errors.New("schema version is not recorded")
+	// ErrForeignKeyViolation is returned when a migration would leave rows
+	// referencing missing rows
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+)
+
+// SchemaRepository handles operations on the database as a whole
+type SchemaRepository struct {
+	db *sql.DB
+}
+
+// NewSchemaRepository creates a new schema repository
+func NewSchemaRepository(db *sql.DB) *SchemaRepository {
+	return &SchemaRepository{db: db}
+}
+
+// Ping verifies that the database file can be read
+func (r *SchemaRepository) Ping(ctx context.Context) error {
+	var n int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&n); err != nil {
+		return fmt.Errorf("failed to query database: %w", err)
+	}
+
+	return nil
+}
+
+// Version returns the version of the newest applied migration and whether a
+// migration failed part way, as recorded in the schema_migrations table
+func (r *SchemaRepository) Version(ctx context.Context) (int, bool, error) {
+	var version int
+	var dirty bool
+	err := r.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "no such table") {
+			return 0, false, ErrSchemaVersionUnknown
+		}
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// Migrate runs a migration script and records version as the schema version
+// in one transaction, so a failing script leaves the schema unchanged.
+// Version 0 records that no migration is applied.
+//
+// Foreign keys are not enforced while the script runs, so it can rebuild a
+// table the way SQLite recommends without cascading deletes to the rows that
+// reference it. They are checked before the transaction is committed instead.
+func (r *SchemaRepository) Migrate(ctx context.Context, script string, version int) (err error) {
+	// The pragma has no effect inside a transaction, and applies to one
+	// connection, so the migration gets a connection of its own
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("failed to disable foreign keys: %w", err)
+	}
+	defer func() {
+		if _, restoreErr := conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON"); restoreErr != nil {
+			// Never return a connection without foreign keys to the pool
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+			if err == nil {
+				err = fmt.Errorf("failed to enable foreign keys: %w", restoreErr)
+			}
+		}
+	}()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("failed to run migration: %w", err)
+	}
+	if err := foreignKeyCheck(ctx, tx); err != nil {
+		return err
+	}
+	if err := setSchemaVersion(ctx, tx, version); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
+
+	return nil
+}
+
+// foreignKeyCheck returns an error naming the first row that references a
+// missing row, if any
+func foreignKeyCheck(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return fmt.Errorf("failed to check foreign keys: %w", err)
+		}
+		return fmt.Errorf("%w: row %d of %s references a missing row of %s", ErrForeignKeyViolation, rowID.Int64, table, parent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+
+	return nil
+}
+
+// ForceVersion records version as the schema version and clears the dirty
+// flag without running any migration
+func (r *SchemaRepository) ForceVersion(ctx context.Context, version int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setSchemaVersion(ctx, tx, version); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit schema version: %w", err)
+	}
+
+	return nil
+}
+
+// setSchemaVersion replaces the row of schema_migrations, in the layout
+// golang-migrate uses, so either tool can be run on the database
+func setSchemaVersion(ctx context.Context, tx *sql.Tx, version int) error {
+	_, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (version uint64, dirty bool);
+		CREATE UNIQUE INDEX IF NOT EXISTS version_unique ON schema_migrations (version);
+		DELETE FROM schema_migrations;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	if version == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)", version, false); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+
+	return nil
+}
+
+// Snapshot writes a consistent, compacted copy of the database to path with
+// VACUUM INTO. The file must not exist yet. Writers are not blocked.
+func (r *SchemaRepository) Snapshot(ctx context.Context, path string) error {
+	if _, err := r.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	return nil
+}
+
+// IntegrityCheck runs SQLite's integrity check and returns an error
+// describing the first problems it found
+func (r *SchemaRepository) IntegrityCheck(ctx context.Context) error {
+	rows, err := r.db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("failed to check database integrity: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return fmt.Errorf("failed to check database integrity: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check database integrity: %w", err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("database is corrupt: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+var (
+	ErrShiftTemplateNotFound = errors.New("shift template not found")
+)
+
+// ShiftTemplateRepository handles database operations for shift templates
+type ShiftTemplateRepository struct {
+	db *sql.DB
+}
+
+// NewShiftTemplateRepository creates a new shift template repository
+func NewShiftTemplateRepository(db *sql.DB) *ShiftTemplateRepository {
+	return &ShiftTemplateRepository{db: db}
+}
+
+// Create inserts a new shift template into the database
+func (r *ShiftTemplateRepository) Create(ctx context.Context, tmpl *models.ShiftTemplate) error {
+	query := `
+		INSERT INTO shift_templates (user_id, name, type, emergency_call, holiday_call, working_hours, working_shifts, start_time, recurrence, anchor_date, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+	`
+
+	result, err := r.db.ExecContext(ctx, query, tmpl.UserID, tmpl.Name, tmpl.Type, tmpl.EmergencyCall, tmpl.HolidayCall, tmpl.WorkingHours, tmpl.WorkingShifts, tmpl.StartTime, tmpl.Recurrence, tmpl.AnchorDate)
+	if err != nil {
+		return fmt.Errorf("failed to create shift template: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	tmpl.ID = int(id)
+
+	err = r.db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM shift_templates WHERE id = ?", tmpl.ID).
+		Scan(&tmpl.CreatedAt, &tmpl.UpdatedAt)
+
+	return err
+}
+
+// FindByUserID retrieves all shift templates of a user
+func (r *ShiftTemplateRepository) FindByUserID(ctx context.Context, userID int) ([]models.ShiftTemplate, error) {
+	query := `
+		SELECT id, user_id, name, type, emergency_call, holiday_call, working_hours, working_shifts, start_time, recurrence, anchor_date, created_at, updated_at
+		FROM shift_templates
+		WHERE user_id = ?
+		ORDER BY name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shift templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []models.ShiftTemplate
+	for rows.Next() {
+		var tmpl models.ShiftTemplate
+		err := rows.Scan(
+			&tmpl.ID,
+			&tmpl.UserID,
+			&tmpl.Name,
+			&tmpl.Type,
+			&tmpl.EmergencyCall,
+			&tmpl.HolidayCall,
+			&tmpl.WorkingHours,
+			&tmpl.WorkingShifts,
+			&tmpl.StartTime,
+			&tmpl.Recurrence,
+			&tmpl.AnchorDate,
+			&tmpl.CreatedAt,
+			&tmpl.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shift template: %w", err)
+		}
+		templates = append(templates, tmpl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shift templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// FindByID retrieves a specific shift template by ID
+func (r *ShiftTemplateRepository) FindByID(ctx context.Context, id int) (*models.ShiftTemplate, error) {
+	query := `
+		SELECT id, user_id, name, type, emergency_call, holiday_call, working_hours, working_shifts, start_time, recurrence, anchor_date, created_at, updated_at
+		FROM shift_templates
+		WHERE id = ?
+	`
+
+	var tmpl models.ShiftTemplate
+	err := r.db.QueryRowContext(ctx, query, id).
+		Scan(&tmpl.ID, &tmpl.UserID, &tmpl.Name, &tmpl.Type, &tmpl.EmergencyCall, &tmpl.HolidayCall, &tmpl.WorkingHours, &tmpl.WorkingShifts, &tmpl.StartTime, &tmpl.Recurrence, &tmpl.AnchorDate, &tmpl.CreatedAt, &tmpl.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrShiftTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to find shift template: %w", err)
+	}
+
+	return &tmpl, nil
+}
+
+// Update updates an existing shift template
+func (r *ShiftTemplateRepository) Update(ctx context.Context, tmpl *models.ShiftTemplate) error {
+	query := `
+		UPDATE shift_templates
+		SET name = ?, type = ?, emergency_call = ?, holiday_call = ?, working_hours = ?, working_shifts = ?, start_time = ?, recurrence = ?, anchor_date = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, tmpl.Name, tmpl.Type, tmpl.EmergencyCall, tmpl.HolidayCall, tmpl.WorkingHours, tmpl.WorkingShifts, tmpl.StartTime, tmpl.Recurrence, tmpl.AnchorDate, tmpl.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update shift template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrShiftTemplateNotFound
+	}
+
+	return r.db.QueryRowContext(ctx, "SELECT updated_at FROM shift_templates WHERE id = ?", tmpl.ID).
+		Scan(&tmpl.UpdatedAt)
+}
+
+// Delete removes a shift template from the database
+func (r *ShiftTemplateRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM shift_templates WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete shift template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrShiftTemplateNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+var (
+	ErrTimerNotFound = errors.New("timer not found")
+	ErrTimerRunning  = errors.New("timer already running")
+)
+
+// TimerRepository handles database operations for timers
+type TimerRepository struct {
+	db   DBTX
+	conn *sql.DB
+}
+
+// NewTimerRepository creates a new timer repository
+func NewTimerRepository(db *sql.DB) *TimerRepository {
+	return &TimerRepository{db: db, conn: db}
+}
+
+// BoundTo returns a repository that runs on the same connection or transaction
+// as repo, so a timer is stopped atomically with the track item it records
+func (r *TimerRepository) BoundTo(repo *TrackItemRepository) *TimerRepository {
+	return &TimerRepository{db: repo.db, conn: r.conn}
+}
+
+// Create starts a timer now. A user can run only one timer at a time.
+func (r *TimerRepository) Create(ctx context.Context, timer *models.Timer) (err error) {
+	ctx, span := startSpan(ctx, "TimerRepository.Create", "INSERT", "timers")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	query := `
+		INSERT INTO timers (user_id, type, emergency_call, holiday_call, working_shifts, started_at)
+		VALUES (?, ?, ?, ?, ?, datetime('now'))
+	`
+
+	_, err = r.db.ExecContext(ctx, query, timer.UserID, timer.Type, timer.EmergencyCall, timer.HolidayCall, timer.WorkingShifts)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrTimerRunning
+		}
+		return fmt.Errorf("failed to create timer: %w", err)
+	}
+
+	return r.db.QueryRowContext(ctx, "SELECT started_at FROM timers WHERE user_id = ?", timer.UserID).
+		Scan(&timer.StartedAt)
+}
+
+// FindByUserID retrieves a user's running timer
+func (r *TimerRepository) FindByUserID(ctx context.Context, userID int) (timer *models.Timer, err error) {
+	ctx, span := startSpan(ctx, "TimerRepository.FindByUserID", "SELECT", "timers")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	query := `
+		SELECT user_id, type, emergency_call, holiday_call, working_shifts, started_at
+		FROM timers
+		WHERE user_id = ?
+	`
+
+	var t models.Timer
+	err = r.db.QueryRowContext(ctx, query, userID).
+		Scan(&t.UserID, &t.Type, &t.EmergencyCall, &t.HolidayCall, &t.WorkingShifts, &t.StartedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTimerNotFound
+		}
+		return nil, fmt.Errorf("failed to find timer: %w", err)
+	}
+
+	return &t, nil
+}
+
+// DeleteByUserID removes a user's running timer
+func (r *TimerRepository) DeleteByUserID(ctx context.Context, userID int) (err error) {
+	ctx, span := startSpan(ctx, "TimerRepository.DeleteByUserID", "DELETE", "timers")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM timers WHERE user_id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete timer: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrTimerNotFound
+	}
+
+	return nil
+}
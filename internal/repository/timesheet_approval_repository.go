@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+var (
+	ErrTimesheetAlreadyApproved = errors.New("timesheet already approved")
+)
+
+// timesheetApprovalColumns are the columns scanned by scanTimesheetApproval
+const timesheetApprovalColumns = "id, user_id, month, approved_by, approved_at, items, working_hours, working_shifts, emergency_calls, holiday_calls"
+
+// scanTimesheetApproval scans a row of timesheetApprovalColumns
+func scanTimesheetApproval(row interface{ Scan(dest ...any) error }) (*models.TimesheetApproval, error) {
+	var approval models.TimesheetApproval
+	var approvedBy sql.NullInt64
+	totals := &approval.Totals
+	err := row.Scan(&approval.ID, &approval.UserID, &approval.Month, &approvedBy, &approval.ApprovedAt,
+		&totals.Items, &totals.WorkingHours, &totals.WorkingShifts, &totals.EmergencyCalls, &totals.HolidayCalls)
+	if err != nil {
+		return nil, err
+	}
+	if approvedBy.Valid {
+		id := int(approvedBy.Int64)
+		approval.ApprovedBy = &id
+	}
+
+	return &approval, nil
+}
+
+// TimesheetApprovalRepository handles database operations for timesheet
+// approvals
+type TimesheetApprovalRepository struct {
+	db   DBTX
+	conn *sql.DB
+}
+
+// NewTimesheetApprovalRepository creates a new timesheet approval repository
+func NewTimesheetApprovalRepository(db *sql.DB) *TimesheetApprovalRepository {
+	return &TimesheetApprovalRepository{db: db, conn: db}
+}
+
+// BoundTo returns a repository that runs on the same connection or transaction
+// as repo, so an approval is stored atomically with the events it causes
+func (r *TimesheetApprovalRepository) BoundTo(repo *TrackItemRepository) *TimesheetApprovalRepository {
+	return &TimesheetApprovalRepository{db: repo.db, conn: r.conn}
+}
+
+// Create inserts a new approval. A month can be approved only once per user.
+func (r *TimesheetApprovalRepository) Create(ctx context.Context, approval *models.TimesheetApproval) (err error) {
+	ctx, span := startSpan(ctx, "TimesheetApprovalRepository.Create", "INSERT", "timesheet_approvals")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	query := `
+		INSERT INTO timesheet_approvals (user_id, month, approved_by, items, working_hours, working_shifts, emergency_calls, holiday_calls, approved_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`
+
+	totals := approval.Totals
+	result, err := r.db.ExecContext(ctx, query, approval.UserID, approval.Month, approval.ApprovedBy,
+		totals.Items, totals.WorkingHours, totals.WorkingShifts, totals.EmergencyCalls, totals.HolidayCalls)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrTimesheetAlreadyApproved
+		}
+		return fmt.Errorf("failed to create timesheet approval: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	approval.ID = int(id)
+
+	return r.db.QueryRowContext(ctx, "SELECT approved_at FROM timesheet_approvals WHERE id = ?", approval.ID).
+		Scan(&approval.ApprovedAt)
+}
+
+// Find retrieves the approvals of a user, or of all users when userID is 0,
+// for a month (YYYY-MM), or for all months when month is empty. The newest
+// months come first.
+func (r *TimesheetApprovalRepository) Find(ctx context.Context, userID int, month string) (approvals []models.TimesheetApproval, err error) {
+	ctx, span := startSpan(ctx, "TimesheetApprovalRepository.Find", "SELECT", "timesheet_approvals")
+	defer func() { endSpan(span, len(approvals), err) }()
+
+	where := []string{"1 = 1"}
+	var args []interface{}
+	if userID != 0 {
+		where = append(where, "user_id = ?")
+		args = append(args, userID)
+	}
+	if month != "" {
+		where = append(where, "month = ?")
+		args = append(args, month)
+	}
+
+	query := `
+		SELECT ` + timesheetApprovalColumns + `
+		FROM timesheet_approvals
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY month DESC, user_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query timesheet approvals: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		approval, err := scanTimesheetApproval(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan timesheet approval: %w", err)
+		}
+		approvals = append(approvals, *approval)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating timesheet approvals: %w", err)
+	}
+
+	return approvals, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/sergey/work-track-backend/internal/repository")
+
+// startSpan starts a span for a repository method, such as
+// "TrackItemRepository.FindByID", running a SQL operation on table
+func startSpan(ctx context.Context, name, operation, table string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(table),
+		),
+	)
+}
+
+// endSpan ends a span started by startSpan, recording the number of rows the
+// method returned or changed and its error, if any. Lookups of rows that do
+// not exist are expected and not recorded as failures.
+func endSpan(span trace.Span, rows int, err error) {
+	span.SetAttributes(semconv.DBResponseReturnedRows(rows))
+	if err != nil && !isNotFound(err) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// isNotFound reports whether err means the row looked for does not exist
+func isNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, ErrTrackItemNotFound) ||
+		errors.Is(err, ErrTombstoneNotFound) ||
+		errors.Is(err, ErrUserNotFound)
+}
+
+// rowCount returns 1 for a method that returned or changed a single row
+// without error, and 0 otherwise
+func rowCount(err error) int {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
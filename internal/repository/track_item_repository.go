@@ -3,55 +3,132 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/mattn/go-sqlite3"
 	"github.com/sergey/work-track-backend/internal/models"
 )
 
 var (
-	ErrTrackItemNotFound = errors.New("track item not found")
+	ErrTrackItemNotFound     = errors.New("track item not found")
+	ErrClientIDAlreadyExists = errors.New("client id already exists")
+	ErrTombstoneNotFound     = errors.New("track item tombstone not found")
 )
 
+// trackItemColumns are the columns scanned by scanTrackItem
+const trackItemColumns = "id, user_id, type, emergency_call, holiday_call, working_hours, working_shifts, date, created_at, updated_at, client_id, version, field_clock"
+
+// tombstoneColumns are the columns scanned by scanTombstone
+const tombstoneColumns = "item_id, user_id, client_id, version, deleted_at"
+
 // TrackItemRepository handles database operations for track items
 type TrackItemRepository struct {
-	db *sql.DB
+	db          DBTX
+	conn        *sql.DB
+	afterCommit *[]func() // Set while bound to a transaction
 }
 
 // NewTrackItemRepository creates a new track item repository
 func NewTrackItemRepository(db *sql.DB) *TrackItemRepository {
-	return &TrackItemRepository{db: db}
+	return &TrackItemRepository{db: db, conn: db}
 }
 
-// Create inserts a new track item into the database
-func (r *TrackItemRepository) Create(ctx context.Context, item *models.TrackItem) error {
-	query := `
-		INSERT INTO track_items (user_id, type, emergency_call, holiday_call, working_hours, working_shifts, date, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
-	`
+// WithTx runs fn with a repository bound to a single transaction. The
+// transaction is committed when fn returns nil and rolled back otherwise.
+// Calling WithTx on a repository that is already bound to a transaction
+// reuses that transaction.
+func (r *TrackItemRepository) WithTx(ctx context.Context, fn func(repo *TrackItemRepository) error) error {
+	if _, ok := r.db.(*sql.Tx); ok {
+		return fn(r)
+	}
 
-	result, err := r.db.ExecContext(ctx, query, item.UserID, item.Type, item.EmergencyCall, item.HolidayCall, item.WorkingHours, item.WorkingShifts, item.Date)
+	var afterCommit []func()
+	err := runInTx(ctx, r.conn, func(tx *sql.Tx) error {
+		return fn(&TrackItemRepository{db: tx, conn: r.conn, afterCommit: &afterCommit})
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create track item: %w", err)
+		return err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
+	for _, f := range afterCommit {
+		f()
+	}
+
+	return nil
+}
+
+// AfterCommit registers f to run once the transaction the repository is bound
+// to has been committed. It is not run if the transaction is rolled back.
+// Outside a transaction f runs immediately.
+func (r *TrackItemRepository) AfterCommit(f func()) {
+	if r.afterCommit == nil {
+		f()
+		return
 	}
-	item.ID = int(id)
+	*r.afterCommit = append(*r.afterCommit, f)
+}
+
+// Create inserts a new track item into the database. The date is stored in UTC.
+// The item takes the next sync version, with all of its fields recorded as
+// changed at item.ChangedAt, or now when that is zero.
+func (r *TrackItemRepository) Create(ctx context.Context, item *models.TrackItem) (err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.Create", "INSERT", "track_items")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	item.Date = item.Date.UTC()
 
-	err = r.db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM track_items WHERE id = ?", item.ID).
-		Scan(&item.CreatedAt, &item.UpdatedAt)
+	return r.WithTx(ctx, func(repo *TrackItemRepository) error {
+		version, err := repo.nextVersion(ctx)
+		if err != nil {
+			return err
+		}
+
+		clock := make(models.FieldClock, len(models.TrackItemFields))
+		for _, field := range models.TrackItemFields {
+			clock[field] = models.FieldChange{Version: version, At: changedAt(item)}
+		}
+		clockJSON, err := json.Marshal(clock)
+		if err != nil {
+			return fmt.Errorf("failed to encode field clock: %w", err)
+		}
+
+		query := `
+			INSERT INTO track_items (user_id, type, emergency_call, holiday_call, working_hours, working_shifts, date, client_id, version, field_clock, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+		`
 
-	return err
+		result, err := repo.db.ExecContext(ctx, query, item.UserID, item.Type, item.EmergencyCall, item.HolidayCall, item.WorkingHours, item.WorkingShifts, item.Date, nullIfEmpty(item.ClientID), version, string(clockJSON))
+		if err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return ErrClientIDAlreadyExists
+			}
+			return fmt.Errorf("failed to create track item: %w", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		item.ID = int(id)
+		item.Version = version
+		item.FieldClock = clock
+
+		return repo.db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM track_items WHERE id = ?", item.ID).
+			Scan(&item.CreatedAt, &item.UpdatedAt)
+	})
 }
 
 // FindByUserID retrieves all track items for a specific user
-func (r *TrackItemRepository) FindByUserID(ctx context.Context, userID int) ([]models.TrackItem, error) {
+func (r *TrackItemRepository) FindByUserID(ctx context.Context, userID int) (items []models.TrackItem, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.FindByUserID", "SELECT", "track_items")
+	defer func() { endSpan(span, len(items), err) }()
+
 	query := `
-		SELECT id, user_id, type, emergency_call, holiday_call, working_hours, working_shifts, date, created_at, updated_at
+		SELECT ` + trackItemColumns + `
 		FROM track_items
 		WHERE user_id = ?
 		ORDER BY date DESC
@@ -63,89 +140,235 @@ func (r *TrackItemRepository) FindByUserID(ctx context.Context, userID int) ([]m
 	}
 	defer rows.Close()
 
-	var items []models.TrackItem
-	for rows.Next() {
-		var item models.TrackItem
-		err := rows.Scan(
-			&item.ID,
-			&item.UserID,
-			&item.Type,
-			&item.EmergencyCall,
-			&item.HolidayCall,
-			&item.WorkingHours,
-			&item.WorkingShifts,
-			&item.Date,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan track item: %w", err)
-		}
-		items = append(items, item)
-	}
+	return scanTrackItems(rows)
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating track items: %w", err)
+// FindByDateRange retrieves track items for a user from startDate up to, but
+// not including, endDate. Dates are stored in UTC, so the bounds are compared
+// in UTC as well.
+func (r *TrackItemRepository) FindByDateRange(ctx context.Context, userID int, startDate, endDate time.Time) (items []models.TrackItem, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.FindByDateRange", "SELECT", "track_items")
+	defer func() { endSpan(span, len(items), err) }()
+
+	query := `
+		SELECT ` + trackItemColumns + `
+		FROM track_items
+		WHERE user_id = ? AND date >= ? AND date < ?
+		ORDER BY date DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, startDate.UTC(), endDate.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query track items by date range: %w", err)
 	}
+	defer rows.Close()
 
-	return items, nil
+	return scanTrackItems(rows)
 }
 
-// FindByDateRange retrieves track items for a user within a date range
-func (r *TrackItemRepository) FindByDateRange(ctx context.Context, userID int, startDate, endDate time.Time) ([]models.TrackItem, error) {
+// StreamByDateRange calls fn for each of a user's track items from startDate
+// up to, but not including, endDate, oldest first, without holding the whole
+// result in memory. Iteration stops at the first error returned by fn.
+func (r *TrackItemRepository) StreamByDateRange(ctx context.Context, userID int, startDate, endDate time.Time, fn func(item *models.TrackItem) error) (err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.StreamByDateRange", "SELECT", "track_items")
+	streamed := 0
+	defer func() { endSpan(span, streamed, err) }()
+
 	query := `
-		SELECT id, user_id, type, emergency_call, holiday_call, working_hours, working_shifts, date, created_at, updated_at
+		SELECT ` + trackItemColumns + `
 		FROM track_items
-		WHERE user_id = ? AND date >= ? AND date <= ?
-		ORDER BY date DESC
+		WHERE user_id = ? AND date >= ? AND date < ?
+		ORDER BY date ASC, id ASC
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, userID, startDate.UTC(), endDate.UTC())
 	if err != nil {
-		return nil, fmt.Errorf("failed to query track items by date range: %w", err)
+		return fmt.Errorf("failed to query track items by date range: %w", err)
 	}
 	defer rows.Close()
 
-	var items []models.TrackItem
 	for rows.Next() {
-		var item models.TrackItem
-		err := rows.Scan(
-			&item.ID,
-			&item.UserID,
-			&item.Type,
-			&item.EmergencyCall,
-			&item.HolidayCall,
-			&item.WorkingHours,
-			&item.WorkingShifts,
-			&item.Date,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-		)
+		item, err := scanTrackItem(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan track item: %w", err)
+			return fmt.Errorf("failed to scan track item: %w", err)
+		}
+		if err := fn(item); err != nil {
+			return err
 		}
-		items = append(items, item)
+		streamed++
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating track items: %w", err)
+		return fmt.Errorf("error iterating track items: %w", err)
 	}
 
-	return items, nil
+	return nil
+}
+
+// Sort fields supported by FindPage
+const (
+	SortByDate         = "date"
+	SortByWorkingHours = "working_hours"
+)
+
+// TrackItemQuery describes a filtered, sorted and optionally paginated list of a user's track items
+type TrackItemQuery struct {
+	StartDate     *time.Time // Inclusive
+	EndDate       *time.Time // Exclusive
+	Types         []string
+	EmergencyCall *bool
+	HolidayCall   *bool
+	MinHours      *float64
+	MaxHours      *float64
+
+	SortField string // SortByDate (default) or SortByWorkingHours
+	SortDesc  bool
+
+	// After is the keyset position of the previous page: only rows that come
+	// strictly after it in sort order are returned. Nil starts from the beginning.
+	After *models.TrackItem
+	Limit int // 0 means no limit
+}
+
+// FindPage retrieves a user's track items matching the query. Ties on the sort
+// field are broken by ID so that keyset pagination is stable.
+func (r *TrackItemRepository) FindPage(ctx context.Context, userID int, q *TrackItemQuery) (items []models.TrackItem, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.FindPage", "SELECT", "track_items")
+	defer func() { endSpan(span, len(items), err) }()
+
+	where, args := q.where(userID)
+
+	sortColumn := SortByDate
+	if q.SortField == SortByWorkingHours {
+		sortColumn = SortByWorkingHours
+	}
+	direction, cmp := "ASC", ">"
+	if q.SortDesc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if q.After != nil {
+		var value interface{} = q.After.Date.UTC()
+		if sortColumn == SortByWorkingHours {
+			value = q.After.WorkingHours
+		}
+		where = append(where, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", sortColumn, cmp, sortColumn, cmp))
+		args = append(args, value, value, q.After.ID)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM track_items
+		WHERE %s
+		ORDER BY %s %s, id %s
+	`, trackItemColumns, strings.Join(where, " AND "), sortColumn, direction, direction)
+
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query track items page: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTrackItems(rows)
+}
+
+// Count returns the number of a user's track items matching the query filters.
+// Sorting and pagination fields are ignored.
+func (r *TrackItemRepository) Count(ctx context.Context, userID int, q *TrackItemQuery) (count int, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.Count", "SELECT", "track_items")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	where, args := q.where(userID)
+
+	query := "SELECT COUNT(*) FROM track_items WHERE " + strings.Join(where, " AND ")
+
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count track items: %w", err)
+	}
+
+	return count, nil
+}
+
+// where builds the SQL conditions and arguments for the query filters. The
+// user_id and date conditions come first so idx_track_items_user_date is used.
+func (q *TrackItemQuery) where(userID int) ([]string, []interface{}) {
+	where := []string{"user_id = ?"}
+	args := []interface{}{userID}
+
+	if q.StartDate != nil {
+		where = append(where, "date >= ?")
+		args = append(args, q.StartDate.UTC())
+	}
+	if q.EndDate != nil {
+		where = append(where, "date < ?")
+		args = append(args, q.EndDate.UTC())
+	}
+	if len(q.Types) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(q.Types)), ", ")
+		where = append(where, "type IN ("+placeholders+")")
+		for _, t := range q.Types {
+			args = append(args, t)
+		}
+	}
+	if q.EmergencyCall != nil {
+		where = append(where, "emergency_call = ?")
+		args = append(args, *q.EmergencyCall)
+	}
+	if q.HolidayCall != nil {
+		where = append(where, "holiday_call = ?")
+		args = append(args, *q.HolidayCall)
+	}
+	if q.MinHours != nil {
+		where = append(where, "working_hours >= ?")
+		args = append(args, *q.MinHours)
+	}
+	if q.MaxHours != nil {
+		where = append(where, "working_hours <= ?")
+		args = append(args, *q.MaxHours)
+	}
+
+	return where, args
 }
 
 // FindByID retrieves a specific track item by ID
-func (r *TrackItemRepository) FindByID(ctx context.Context, id int) (*models.TrackItem, error) {
+func (r *TrackItemRepository) FindByID(ctx context.Context, id int) (_ *models.TrackItem, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.FindByID", "SELECT", "track_items")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
 	query := `
-		SELECT id, user_id, type, emergency_call, holiday_call, working_hours, working_shifts, date, created_at, updated_at
+		SELECT ` + trackItemColumns + `
 		FROM track_items
 		WHERE id = ?
 	`
 
-	var item models.TrackItem
-	err := r.db.QueryRowContext(ctx, query, id).
-		Scan(&item.ID, &item.UserID, &item.Type, &item.EmergencyCall, &item.HolidayCall, &item.WorkingHours, &item.WorkingShifts, &item.Date, &item.CreatedAt, &item.UpdatedAt)
+	item, err := scanTrackItem(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTrackItemNotFound
+		}
+		return nil, fmt.Errorf("failed to find track item: %w", err)
+	}
+
+	return item, nil
+}
+
+// FindByClientID retrieves a user's track item by the ID its client assigned
+func (r *TrackItemRepository) FindByClientID(ctx context.Context, userID int, clientID string) (_ *models.TrackItem, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.FindByClientID", "SELECT", "track_items")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	query := `
+		SELECT ` + trackItemColumns + `
+		FROM track_items
+		WHERE user_id = ? AND client_id = ?
+	`
 
+	item, err := scanTrackItem(r.db.QueryRowContext(ctx, query, userID, clientID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrTrackItemNotFound
@@ -153,52 +376,308 @@ func (r *TrackItemRepository) FindByID(ctx context.Context, id int) (*models.Tra
 		return nil, fmt.Errorf("failed to find track item: %w", err)
 	}
 
-	return &item, nil
+	return item, nil
 }
 
-// Update updates an existing track item
-func (r *TrackItemRepository) Update(ctx context.Context, item *models.TrackItem) error {
+// FindChangedSince retrieves up to limit of a user's track items whose sync
+// version is greater than since, in version order
+func (r *TrackItemRepository) FindChangedSince(ctx context.Context, userID int, since int64, limit int) (items []models.TrackItem, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.FindChangedSince", "SELECT", "track_items")
+	defer func() { endSpan(span, len(items), err) }()
+
 	query := `
-		UPDATE track_items
-		SET type = ?, emergency_call = ?, holiday_call = ?, working_hours = ?, working_shifts = ?, date = ?, updated_at = datetime('now')
-		WHERE id = ?
+		SELECT ` + trackItemColumns + `
+		FROM track_items
+		WHERE user_id = ? AND version > ?
+		ORDER BY version
+		LIMIT ?
 	`
 
-	result, err := r.db.ExecContext(ctx, query, item.Type, item.EmergencyCall, item.HolidayCall, item.WorkingHours, item.WorkingShifts, item.Date, item.ID)
+	rows, err := r.db.QueryContext(ctx, query, userID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changed track items: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTrackItems(rows)
+}
+
+// FindTombstone retrieves the tombstone of a user's deleted track item by its
+// ID or, when clientID is set, by the ID its client assigned
+func (r *TrackItemRepository) FindTombstone(ctx context.Context, userID, itemID int, clientID string) (_ *models.TrackItemTombstone, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.FindTombstone", "SELECT", "track_item_tombstones")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	query := `SELECT ` + tombstoneColumns + ` FROM track_item_tombstones WHERE user_id = ? AND item_id = ?`
+	args := []interface{}{userID, itemID}
+	if clientID != "" {
+		query = `SELECT ` + tombstoneColumns + ` FROM track_item_tombstones WHERE user_id = ? AND client_id = ? ORDER BY version DESC LIMIT 1`
+		args = []interface{}{userID, clientID}
+	}
+
+	tombstone, err := scanTombstone(r.db.QueryRowContext(ctx, query, args...))
 	if err != nil {
-		return fmt.Errorf("failed to update track item: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTombstoneNotFound
+		}
+		return nil, fmt.Errorf("failed to find track item tombstone: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
+	return tombstone, nil
+}
+
+// FindTombstonesSince retrieves up to limit of a user's tombstones whose sync
+// version is greater than since, in version order
+func (r *TrackItemRepository) FindTombstonesSince(ctx context.Context, userID int, since int64, limit int) (tombstones []models.TrackItemTombstone, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.FindTombstonesSince", "SELECT", "track_item_tombstones")
+	defer func() { endSpan(span, len(tombstones), err) }()
+
+	query := `
+		SELECT ` + tombstoneColumns + `
+		FROM track_item_tombstones
+		WHERE user_id = ? AND version > ?
+		ORDER BY version
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, since, limit)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, fmt.Errorf("failed to query track item tombstones: %w", err)
 	}
-	if rows == 0 {
-		return ErrTrackItemNotFound
+	defer rows.Close()
+
+	for rows.Next() {
+		tombstone, err := scanTombstone(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan track item tombstone: %w", err)
+		}
+		tombstones = append(tombstones, *tombstone)
 	}
 
-	err = r.db.QueryRowContext(ctx, "SELECT updated_at FROM track_items WHERE id = ?", item.ID).
-		Scan(&item.UpdatedAt)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating track item tombstones: %w", err)
+	}
 
-	return nil
+	return tombstones, nil
 }
 
-// Delete removes a track item from the database
-func (r *TrackItemRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM track_items WHERE id = ?`
+// Update updates an existing track item. The date is stored in UTC. The item
+// takes the next sync version, and the fields that differ from the stored item
+// are recorded as changed at item.ChangedAt, or now when that is zero.
+func (r *TrackItemRepository) Update(ctx context.Context, item *models.TrackItem) (err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.Update", "UPDATE", "track_items")
+	defer func() { endSpan(span, rowCount(err), err) }()
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	item.Date = item.Date.UTC()
+
+	return r.WithTx(ctx, func(repo *TrackItemRepository) error {
+		stored, err := repo.FindByID(ctx, item.ID)
+		if err != nil {
+			return err
+		}
+
+		version, err := repo.nextVersion(ctx)
+		if err != nil {
+			return err
+		}
+
+		clock := stored.FieldClock
+		for _, field := range models.TrackItemFields {
+			if item.FieldValue(field) != stored.FieldValue(field) {
+				clock[field] = models.FieldChange{Version: version, At: changedAt(item)}
+			}
+		}
+		clockJSON, err := json.Marshal(clock)
+		if err != nil {
+			return fmt.Errorf("failed to encode field clock: %w", err)
+		}
+
+		query := `
+			UPDATE track_items
+			SET type = ?, emergency_call = ?, holiday_call = ?, working_hours = ?, working_shifts = ?, date = ?, version = ?, field_clock = ?, updated_at = datetime('now')
+			WHERE id = ?
+		`
+
+		_, err = repo.db.ExecContext(ctx, query, item.Type, item.EmergencyCall, item.HolidayCall, item.WorkingHours, item.WorkingShifts, item.Date, version, string(clockJSON), item.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update track item: %w", err)
+		}
+		item.ClientID = stored.ClientID
+		item.Version = version
+		item.FieldClock = clock
+
+		return repo.db.QueryRowContext(ctx, "SELECT updated_at FROM track_items WHERE id = ?", item.ID).
+			Scan(&item.UpdatedAt)
+	})
+}
+
+// Delete removes a track item from the database, leaving a tombstone with the
+// next sync version so that syncing clients learn about the deletion
+func (r *TrackItemRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.Delete", "DELETE", "track_items")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	return r.WithTx(ctx, func(repo *TrackItemRepository) error {
+		version, err := repo.nextVersion(ctx)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO track_item_tombstones (item_id, user_id, client_id, version, deleted_at)
+			SELECT id, user_id, client_id, ?, datetime('now')
+			FROM track_items
+			WHERE id = ?
+		`
+
+		result, err := repo.db.ExecContext(ctx, query, version, id)
+		if err != nil {
+			return fmt.Errorf("failed to create track item tombstone: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rows == 0 {
+			return ErrTrackItemNotFound
+		}
+
+		if _, err := repo.db.ExecContext(ctx, `DELETE FROM track_items WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete track item: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// nextVersion issues the next sync version. Versions follow commit order:
+// the update holds SQLite's write lock until the transaction ends, so no
+// other transaction can take a later version and commit first.
+func (r *TrackItemRepository) nextVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := r.db.QueryRowContext(ctx, "UPDATE sync_state SET version = version + 1 WHERE id = 1 RETURNING version").
+		Scan(&version)
 	if err != nil {
-		return fmt.Errorf("failed to delete track item: %w", err)
+		return 0, fmt.Errorf("failed to issue sync version: %w", err)
+	}
+
+	return version, nil
+}
+
+// changedAt returns when the change to item was made
+func changedAt(item *models.TrackItem) time.Time {
+	if item.ChangedAt.IsZero() {
+		return time.Now().UTC().Truncate(time.Second)
 	}
+	return item.ChangedAt.UTC().Truncate(time.Second)
+}
+
+// nullIfEmpty stores an empty string as NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// NormalizeDates rewrites every stored date that is not in the canonical UTC
+// form, such as rows created with the offset the client sent. The instant in
+// time is kept. It returns the number of rows that need, or with dryRun would
+// need, rewriting.
+func (r *TrackItemRepository) NormalizeDates(ctx context.Context, dryRun bool) (fixed int, err error) {
+	ctx, span := startSpan(ctx, "TrackItemRepository.NormalizeDates", "UPDATE", "track_items")
+	defer func() { endSpan(span, fixed, err) }()
 
-	rows, err := result.RowsAffected()
+	rows, err := r.db.QueryContext(ctx, "SELECT id, date, CAST(date AS TEXT) FROM track_items")
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return 0, fmt.Errorf("failed to query track item dates: %w", err)
 	}
-	if rows == 0 {
-		return ErrTrackItemNotFound
+
+	fixes := make(map[int]time.Time)
+	for rows.Next() {
+		var id int
+		var date time.Time
+		var raw string
+		if err := rows.Scan(&id, &date, &raw); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan track item date: %w", err)
+		}
+		if raw != date.UTC().Format(sqlite3.SQLiteTimestampFormats[0]) {
+			fixes[id] = date.UTC()
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating track item dates: %w", err)
 	}
 
-	return nil
+	if dryRun || len(fixes) == 0 {
+		return len(fixes), nil
+	}
+
+	err = r.WithTx(ctx, func(repo *TrackItemRepository) error {
+		for id, date := range fixes {
+			if _, err := repo.db.ExecContext(ctx, "UPDATE track_items SET date = ? WHERE id = ?", date, id); err != nil {
+				return fmt.Errorf("failed to normalize track item %d: %w", id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(fixes), nil
+}
+
+// scanTrackItem scans a row of trackItemColumns
+func scanTrackItem(row interface{ Scan(dest ...any) error }) (*models.TrackItem, error) {
+	var item models.TrackItem
+	var clientID sql.NullString
+	var clock string
+	err := row.Scan(&item.ID, &item.UserID, &item.Type, &item.EmergencyCall, &item.HolidayCall, &item.WorkingHours, &item.WorkingShifts, &item.Date, &item.CreatedAt, &item.UpdatedAt, &clientID, &item.Version, &clock)
+	if err != nil {
+		return nil, err
+	}
+	item.ClientID = clientID.String
+	if err := json.Unmarshal([]byte(clock), &item.FieldClock); err != nil {
+		return nil, fmt.Errorf("invalid field clock: %w", err)
+	}
+	if item.FieldClock == nil {
+		item.FieldClock = models.FieldClock{}
+	}
+
+	return &item, nil
+}
+
+// scanTrackItems reads all track items from rows
+func scanTrackItems(rows *sql.Rows) ([]models.TrackItem, error) {
+	var items []models.TrackItem
+	for rows.Next() {
+		item, err := scanTrackItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan track item: %w", err)
+		}
+		items = append(items, *item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating track items: %w", err)
+	}
+
+	return items, nil
+}
+
+// scanTombstone scans a row of tombstoneColumns
+func scanTombstone(row interface{ Scan(dest ...any) error }) (*models.TrackItemTombstone, error) {
+	var tombstone models.TrackItemTombstone
+	var clientID sql.NullString
+	err := row.Scan(&tombstone.ID, &tombstone.UserID, &clientID, &tombstone.Version, &tombstone.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	tombstone.ClientID = clientID.String
+
+	return &tombstone, nil
 }
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/sergey/work-track-backend/internal/models"
 )
@@ -15,6 +16,27 @@ var (
 	ErrUserAlreadyExists = errors.New("user already exists")
 )
 
+// userColumns are the columns scanned by scanUser
+const userColumns = "id, first_name, last_name, avatar, login, password_hash, timezone, role, created_at, updated_at, deletion_scheduled_at, disabled_at, password_change_required, token_version"
+
+// scanUser scans a row of userColumns
+func scanUser(row interface{ Scan(dest ...any) error }) (*models.User, error) {
+	var user models.User
+	var deletionScheduledAt, disabledAt sql.NullTime
+	err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Avatar, &user.Login, &user.PasswordHash, &user.TimeZone, &user.Role, &user.CreatedAt, &user.UpdatedAt, &deletionScheduledAt, &disabledAt, &user.PasswordChangeRequired, &user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	if deletionScheduledAt.Valid {
+		user.DeletionScheduledAt = &deletionScheduledAt.Time
+	}
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
+
+	return &user, nil
+}
+
 // UserRepository handles database operations for users
 type UserRepository struct {
 	db *sql.DB
@@ -26,13 +48,20 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 }
 
 // Create inserts a new user into the database
-func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+func (r *UserRepository) Create(ctx context.Context, user *models.User) (err error) {
+	ctx, span := startSpan(ctx, "UserRepository.Create", "INSERT", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
 	query := `
-		INSERT INTO users (first_name, last_name, avatar, login, password_hash, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+		INSERT INTO users (first_name, last_name, avatar, login, password_hash, timezone, role, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
 	`
 
-	result, err := r.db.ExecContext(ctx, query, user.FirstName, user.LastName, user.Avatar, user.Login, user.PasswordHash)
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+
+	result, err := r.db.ExecContext(ctx, query, user.FirstName, user.LastName, user.Avatar, user.Login, user.PasswordHash, user.TimeZone, user.Role)
 	if err != nil {
 		// Check for unique constraint violation
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
@@ -55,16 +84,17 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 }
 
 // FindByLogin retrieves a user by login
-func (r *UserRepository) FindByLogin(ctx context.Context, login string) (*models.User, error) {
+func (r *UserRepository) FindByLogin(ctx context.Context, login string) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepository.FindByLogin", "SELECT", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
 	query := `
-		SELECT id, first_name, last_name, avatar, login, password_hash, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE login = ?
 	`
 
-	var user models.User
-	err := r.db.QueryRowContext(ctx, query, login).
-		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Avatar, &user.Login, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, login))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -73,20 +103,21 @@ func (r *UserRepository) FindByLogin(ctx context.Context, login string) (*models
 		return nil, fmt.Errorf("failed to find user by login: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 // FindByID retrieves a user by ID
-func (r *UserRepository) FindByID(ctx context.Context, id int) (*models.User, error) {
+func (r *UserRepository) FindByID(ctx context.Context, id int) (_ *models.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepository.FindByID", "SELECT", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
 	query := `
-		SELECT id, first_name, last_name, avatar, login, password_hash, created_at, updated_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE id = ?
 	`
 
-	var user models.User
-	err := r.db.QueryRowContext(ctx, query, id).
-		Scan(&user.ID, &user.FirstName, &user.LastName, &user.Avatar, &user.Login, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -95,5 +126,313 @@ func (r *UserRepository) FindByID(ctx context.Context, id int) (*models.User, er
 		return nil, fmt.Errorf("failed to find user by ID: %w", err)
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+// FindActive retrieves the users that are neither disabled nor scheduled
+// for deletion, ordered by name
+func (r *UserRepository) FindActive(ctx context.Context) (users []models.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepository.FindActive", "SELECT", "users")
+	defer func() { endSpan(span, len(users), err) }()
+
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE disabled_at IS NULL AND deletion_scheduled_at IS NULL
+		ORDER BY last_name, first_name, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// UserQuery holds the filters and keyset position of a page of users
+type UserQuery struct {
+	Search   string // Substring of the login, first or last name, case-insensitive
+	Role     string
+	Disabled *bool
+
+	AfterID int // Only users with a greater ID are returned; 0 starts from the beginning
+	Limit   int // 0 means no limit
+}
+
+// FindPage retrieves the users matching the query, ordered by ID
+func (r *UserRepository) FindPage(ctx context.Context, q *UserQuery) (users []models.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepository.FindPage", "SELECT", "users")
+	defer func() { endSpan(span, len(users), err) }()
+
+	where, args := q.where()
+	if q.AfterID > 0 {
+		where = append(where, "id > ?")
+		args = append(args, q.AfterID)
+	}
+
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY id
+	`
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users page: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// Count returns the number of users matching the query filters
+func (r *UserRepository) Count(ctx context.Context, q *UserQuery) (count int, err error) {
+	ctx, span := startSpan(ctx, "UserRepository.Count", "SELECT", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	where, args := q.where()
+	query := "SELECT COUNT(*) FROM users WHERE " + strings.Join(where, " AND ")
+
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return count, nil
+}
+
+// where builds the SQL conditions and arguments for the query filters
+func (q *UserQuery) where() ([]string, []interface{}) {
+	where := []string{"1 = 1"}
+	var args []interface{}
+
+	if q.Search != "" {
+		// LIKE is case-insensitive for ASCII; wildcards in the search are literal
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q.Search) + "%"
+		where = append(where, `(login LIKE ? ESCAPE '\' OR first_name LIKE ? ESCAPE '\' OR last_name LIKE ? ESCAPE '\' OR first_name || ' ' || last_name LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern, pattern, pattern)
+	}
+	if q.Role != "" {
+		where = append(where, "role = ?")
+		args = append(args, q.Role)
+	}
+	if q.Disabled != nil {
+		if *q.Disabled {
+			where = append(where, "disabled_at IS NOT NULL")
+		} else {
+			where = append(where, "disabled_at IS NULL")
+		}
+	}
+
+	return where, args
+}
+
+// FindDueForDeletion retrieves the users whose scheduled deletion time has passed
+func (r *UserRepository) FindDueForDeletion(ctx context.Context, now time.Time) (users []models.User, err error) {
+	ctx, span := startSpan(ctx, "UserRepository.FindDueForDeletion", "SELECT", "users")
+	defer func() { endSpan(span, len(users), err) }()
+
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// SetDeletionScheduledAt schedules the deletion of a user, or cancels it when at is nil
+func (r *UserRepository) SetDeletionScheduledAt(ctx context.Context, id int, at *time.Time) (err error) {
+	ctx, span := startSpan(ctx, "UserRepository.SetDeletionScheduledAt", "UPDATE", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	result, err := r.db.ExecContext(ctx, "UPDATE users SET deletion_scheduled_at = ?, updated_at = datetime('now') WHERE id = ?", utcOrNil(at), id)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetDisabledAt disables a user, or enables the user again when at is nil
+func (r *UserRepository) SetDisabledAt(ctx context.Context, id int, at *time.Time) (err error) {
+	ctx, span := startSpan(ctx, "UserRepository.SetDisabledAt", "UPDATE", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	result, err := r.db.ExecContext(ctx, "UPDATE users SET disabled_at = ?, updated_at = datetime('now') WHERE id = ?", utcOrNil(at), id)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdatePassword replaces the password hash of a user and increments the
+// token version, which revokes the tokens issued before. mustChange marks a
+// temporary password the user has to replace before logging in.
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string, mustChange bool) (err error) {
+	ctx, span := startSpan(ctx, "UserRepository.UpdatePassword", "UPDATE", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	query := `
+		UPDATE users
+		SET password_hash = ?, password_change_required = ?, token_version = token_version + 1, updated_at = datetime('now')
+		WHERE id = ?
+	`
+	result, err := r.db.ExecContext(ctx, query, passwordHash, mustChange, id)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateRole changes the role of a user and increments the token version,
+// which revokes the tokens issued before, among them the impersonation tokens
+// the user issued as an admin
+func (r *UserRepository) UpdateRole(ctx context.Context, id int, role string) (err error) {
+	ctx, span := startSpan(ctx, "UserRepository.UpdateRole", "UPDATE", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	query := `
+		UPDATE users
+		SET role = ?, token_version = token_version + 1, updated_at = datetime('now')
+		WHERE id = ?
+	`
+	result, err := r.db.ExecContext(ctx, query, role, id)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a user. Track items and other data of the user are removed
+// by the ON DELETE CASCADE foreign keys.
+func (r *UserRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, span := startSpan(ctx, "UserRepository.Delete", "DELETE", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Update updates the profile fields of an existing user
+func (r *UserRepository) Update(ctx context.Context, user *models.User) (err error) {
+	ctx, span := startSpan(ctx, "UserRepository.Update", "UPDATE", "users")
+	defer func() { endSpan(span, rowCount(err), err) }()
+
+	query := `
+		UPDATE users
+		SET first_name = ?, last_name = ?, avatar = ?, timezone = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, user.FirstName, user.LastName, user.Avatar, user.TimeZone, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return r.db.QueryRowContext(ctx, "SELECT updated_at FROM users WHERE id = ?", user.ID).
+		Scan(&user.UpdatedAt)
 }
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+const (
+	webhookColumns  = "id, user_id, url, secret, events, description, all_users, active, created_at, updated_at"
+	deliveryColumns = "id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at"
+)
+
+// WebhookRepository handles database operations for webhooks and their
+// delivery outbox
+type WebhookRepository struct {
+	db   DBTX
+	conn *sql.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db, conn: db}
+}
+
+// BoundTo returns a repository that runs on the same connection or transaction
+// as repo, so events are queued atomically with the track item changes that
+// caused them
+func (r *WebhookRepository) BoundTo(repo *TrackItemRepository) *WebhookRepository {
+	return &WebhookRepository{db: repo.db, conn: r.conn}
+}
+
+// Create inserts a new webhook into the database
+func (r *WebhookRepository) Create(ctx context.Context, hook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (user_id, url, secret, events, description, all_users, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+	`
+
+	result, err := r.db.ExecContext(ctx, query, hook.UserID, hook.URL, hook.Secret, strings.Join(hook.Events, ","), hook.Description, hook.AllUsers, hook.Active)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	hook.ID = int(id)
+
+	return r.db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM webhooks WHERE id = ?", hook.ID).
+		Scan(&hook.CreatedAt, &hook.UpdatedAt)
+}
+
+// FindByID retrieves a webhook by ID
+func (r *WebhookRepository) FindByID(ctx context.Context, id int) (*models.Webhook, error) {
+	hook, err := scanWebhook(r.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to find webhook: %w", err)
+	}
+
+	return hook, nil
+}
+
+// FindByUserID retrieves all webhooks registered by a user
+func (r *WebhookRepository) FindByUserID(ctx context.Context, userID int) ([]models.Webhook, error) {
+	return r.findAll(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? ORDER BY id`, userID)
+}
+
+// FindSubscribed retrieves the active webhooks that receive events of the
+// given type about a user: the user's own, and those of all users registered
+// by users who are still admins
+func (r *WebhookRepository) FindSubscribed(ctx context.Context, userID int, eventType string) ([]models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE active = 1
+			AND (user_id = ? OR (all_users = 1 AND user_id IN (SELECT id FROM users WHERE role = 'admin')))
+			AND ',' || events || ',' LIKE ?
+		ORDER BY id
+	`
+
+	return r.findAll(ctx, query, userID, "%,"+eventType+",%")
+}
+
+func (r *WebhookRepository) findAll(ctx context.Context, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var hooks []models.Webhook
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, *hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+
+	return hooks, nil
+}
+
+// Update updates an existing webhook
+func (r *WebhookRepository) Update(ctx context.Context, hook *models.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url = ?, events = ?, description = ?, active = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query, hook.URL, strings.Join(hook.Events, ","), hook.Description, hook.Active, hook.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return r.db.QueryRowContext(ctx, "SELECT updated_at FROM webhooks WHERE id = ?", hook.ID).
+		Scan(&hook.UpdatedAt)
+}
+
+// Delete removes a webhook and its deliveries
+func (r *WebhookRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// CreateDelivery queues an event for delivery to a webhook
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+	`
+
+	result, err := r.db.ExecContext(ctx, query, delivery.WebhookID, delivery.EventID, delivery.EventType, delivery.Payload, delivery.Status)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	delivery.ID = int(id)
+
+	return r.db.QueryRowContext(ctx, "SELECT next_attempt_at, created_at FROM webhook_deliveries WHERE id = ?", delivery.ID).
+		Scan(&delivery.NextAttemptAt, &delivery.CreatedAt)
+}
+
+// FindDelivery retrieves a delivery by ID
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id int) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to find webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// FindDueDeliveries retrieves up to limit pending deliveries of active webhooks
+// whose next attempt is due, oldest first
+func (r *WebhookRepository) FindDueDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= ?
+			AND webhook_id IN (SELECT id FROM webhooks WHERE active = 1)
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`
+
+	return r.findDeliveries(ctx, query, now.UTC(), limit)
+}
+
+// FindDeliveries retrieves the most recent deliveries of a webhook, newest
+// first, optionally limited to one status
+func (r *WebhookRepository) FindDeliveries(ctx context.Context, webhookID int, status string, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE webhook_id = ?`
+	args := []interface{}{webhookID}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	return r.findDeliveries(ctx, query, args...)
+}
+
+func (r *WebhookRepository) findDeliveries(ctx context.Context, query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// UpdateDelivery records the outcome of a delivery attempt
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ?
+		WHERE id = ?
+	`
+
+	var statusCode interface{}
+	if delivery.LastStatusCode != nil {
+		statusCode = *delivery.LastStatusCode
+	}
+
+	_, err := r.db.ExecContext(ctx, query, delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UTC(), statusCode, delivery.LastError, utcOrNil(delivery.DeliveredAt), delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// scanWebhook scans a row of webhookColumns
+func scanWebhook(row interface{ Scan(dest ...any) error }) (*models.Webhook, error) {
+	var hook models.Webhook
+	var events string
+	err := row.Scan(&hook.ID, &hook.UserID, &hook.URL, &hook.Secret, &events, &hook.Description, &hook.AllUsers, &hook.Active, &hook.CreatedAt, &hook.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	hook.Events = strings.Split(events, ",")
+
+	return &hook, nil
+}
+
+// scanDelivery scans a row of deliveryColumns
+func scanDelivery(row interface{ Scan(dest ...any) error }) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var statusCode sql.NullInt64
+	var deliveredAt sql.NullTime
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &delivery.Payload, &delivery.Status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &statusCode, &delivery.LastError, &delivery.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		delivery.LastStatusCode = &code
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+
+	return &delivery, nil
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/export"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/util"
+)
+
+var (
+	ErrExportInProgress    = errors.New("a data export is already being prepared")
+	ErrExportNotReady      = errors.New("data export is not ready")
+	ErrExportExpired       = errors.New("data export has expired")
+	ErrNoDeletionScheduled = errors.New("account deletion is not scheduled")
+)
+
+const (
+	// accountMaintenanceInterval is how often expired exports and accounts due
+	// for deletion are cleaned up
+	accountMaintenanceInterval = 10 * time.Minute
+	// exportBuildTimeout bounds the time spent building one export; pending
+	// exports older than this were interrupted and are marked failed
+	exportBuildTimeout = 10 * time.Minute
+)
+
+// AccountService handles account data exports and account deletion
+type AccountService struct {
+	userRepo      *repository.UserRepository
+	trackItemRepo *repository.TrackItemRepository
+	templateRepo  *repository.ShiftTemplateRepository
+	auditRepo     *repository.AuditRepository
+	exportRepo    *repository.DataExportRepository
+	avatars       *export.AvatarLoader
+	cfg           config.AccountConfig
+	builds        sync.WaitGroup
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(userRepo *repository.UserRepository, trackItemRepo *repository.TrackItemRepository, templateRepo *repository.ShiftTemplateRepository, auditRepo *repository.AuditRepository, exportRepo *repository.DataExportRepository, avatars *export.AvatarLoader, cfg config.AccountConfig) *AccountService {
+	return &AccountService{
+		userRepo:      userRepo,
+		trackItemRepo: trackItemRepo,
+		templateRepo:  templateRepo,
+		auditRepo:     auditRepo,
+		exportRepo:    exportRepo,
+		avatars:       avatars,
+		cfg:           cfg,
+	}
+}
+
+// RequestExport starts building an archive of all of a user's data in the
+// background. The returned token identifies the download; it is not stored.
+func (s *AccountService) RequestExport(ctx context.Context, userID int) (*models.DataExport, string, error) {
+	if _, err := s.exportRepo.FindPendingByUserID(ctx, userID); err == nil {
+		return nil, "", ErrExportInProgress
+	} else if !errors.Is(err, repository.ErrDataExportNotFound) {
+		return nil, "", err
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	job := &models.DataExport{
+		UserID:    userID,
+		Status:    models.DataExportPending,
+		TokenHash: hashToken(token),
+	}
+	if err := s.exportRepo.Create(ctx, job); err != nil {
+		return nil, "", err
+	}
+	if err := s.audit(ctx, userID, models.AuditExportRequested, fmt.Sprintf("export %d", job.ID)); err != nil {
+		return nil, "", err
+	}
+
+	s.builds.Add(1)
+	go func() {
+		defer s.builds.Done()
+		s.buildExport(*job)
+	}()
+
+	return job, token, nil
+}
+
+// GetExport retrieves an export, ensuring it belongs to the user
+func (s *AccountService) GetExport(ctx context.Context, userID, exportID int) (*models.DataExport, error) {
+	job, err := s.exportRepo.FindByID(ctx, exportID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify ownership
+	if job.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	return job, nil
+}
+
+// OpenExport opens the archive of the export identified by a download token.
+// The caller must close the file.
+func (s *AccountService) OpenExport(ctx context.Context, token string) (*models.DataExport, *os.File, error) {
+	job, err := s.exportRepo.FindByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case job.Status == models.DataExportExpired || (job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt)):
+		return nil, nil, ErrExportExpired
+	case job.Status != models.DataExportReady:
+		return nil, nil, ErrExportNotReady
+	}
+
+	f, err := os.Open(job.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open data export: %w", err)
+	}
+
+	if err := s.audit(ctx, job.UserID, models.AuditExportDownloaded, fmt.Sprintf("export %d", job.ID)); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return job, f, nil
+}
+
+// ScheduleDeletion schedules a user's account for deletion after the grace
+// period. The password must be confirmed. Until then the deletion can be cancelled.
+func (s *AccountService) ScheduleDeletion(ctx context.Context, userID int, password string) (time.Time, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if util.CheckPassword(user.PasswordHash, password) != nil {
+		return time.Time{}, ErrInvalidCredentials
+	}
+
+	// Repeated requests keep the original date rather than postponing it
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+
+	at := time.Now().Add(s.cfg.DeletionGrace).UTC().Truncate(time.Second)
+	if err := s.userRepo.SetDeletionScheduledAt(ctx, userID, &at); err != nil {
+		return time.Time{}, err
+	}
+	if err := s.audit(ctx, userID, models.AuditDeletionScheduled, "deletion at "+at.Format(time.RFC3339)); err != nil {
+		return time.Time{}, err
+	}
+
+	return at, nil
+}
+
+// CancelDeletion cancels a scheduled account deletion
+func (s *AccountService) CancelDeletion(ctx context.Context, userID int) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil {
+		return ErrNoDeletionScheduled
+	}
+
+	if err := s.userRepo.SetDeletionScheduledAt(ctx, userID, nil); err != nil {
+		return err
+	}
+
+	return s.audit(ctx, userID, models.AuditDeletionCancelled, "")
+}
+
+// Run removes expired exports and deletes accounts whose grace period has
+// ended, until ctx is cancelled
+func (s *AccountService) Run(ctx context.Context) {
+	ticker := time.NewTicker(accountMaintenanceInterval)
+	defer ticker.Stop()
+
+	for {
+		s.cleanup(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait blocks until exports that are being built have finished
+func (s *AccountService) Wait() {
+	s.builds.Wait()
+}
+
+// cleanup runs one round of maintenance. Failures are logged and retried on
+// the next round.
+func (s *AccountService) cleanup(ctx context.Context) {
+	now := time.Now()
+
+	expired, err := s.exportRepo.FindExpired(ctx, now, now.Add(-exportBuildTimeout))
+	if err != nil {
+		slog.Error("Account maintenance failed", "err", err)
+	}
+	for _, job := range expired {
+		if job.Status == models.DataExportPending {
+			job.Status = models.DataExportFailed
+			job.Error = "export was interrupted, please request a new one"
+		} else {
+			job.Status = models.DataExportExpired
+		}
+		if err := removeExportFile(job.FilePath); err != nil {
+			slog.Error("Account maintenance failed", "err", err)
+			continue
+		}
+		job.FilePath = ""
+		if err := s.exportRepo.Complete(ctx, &job); err != nil {
+			slog.Error("Account maintenance failed", "err", err)
+		}
+	}
+
+	users, err := s.userRepo.FindDueForDeletion(ctx, now)
+	if err != nil {
+		slog.Error("Account maintenance failed", "err", err)
+	}
+	for _, user := range users {
+		if err := deleteAccount(ctx, s.userRepo, s.exportRepo, user.ID); err != nil {
+			slog.Error("Account maintenance: failed to delete user", "user_id", user.ID, "err", err)
+			continue
+		}
+		slog.Info("Account maintenance: deleted user after the grace period", "user_id", user.ID)
+	}
+}
+
+// deleteAccount removes a user's export files and then the user. The rows of
+// track items, templates, tokens, exports and audit entries go with it through
+// ON DELETE CASCADE.
+func deleteAccount(ctx context.Context, userRepo *repository.UserRepository, exportRepo *repository.DataExportRepository, userID int) error {
+	jobs, err := exportRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if err := removeExportFile(job.FilePath); err != nil {
+			return err
+		}
+	}
+
+	return userRepo.Delete(ctx, userID)
+}
+
+// buildExport writes the archive of an export and records the outcome
+func (s *AccountService) buildExport(job models.DataExport) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportBuildTimeout)
+	defer cancel()
+
+	path, err := s.writeExport(ctx, &job)
+	now := time.Now().UTC().Truncate(time.Second)
+	job.CompletedAt = &now
+	if err != nil {
+		slog.Error("Data export failed", "export_id", job.ID, "user_id", job.UserID, "err", err)
+		job.Status = models.DataExportFailed
+		job.Error = "failed to prepare the export, please try again"
+	} else {
+		expires := now.Add(s.cfg.ExportTTL)
+		job.Status = models.DataExportReady
+		job.FilePath = path
+		job.ExpiresAt = &expires
+	}
+
+	if err := s.exportRepo.Complete(ctx, &job); err != nil {
+		slog.Error("Data export failed", "export_id", job.ID, "user_id", job.UserID, "err", err)
+	}
+}
+
+// writeExport gathers the user's data and writes the archive, returning its path
+func (s *AccountService) writeExport(ctx context.Context, job *models.DataExport) (string, error) {
+	user, err := s.userRepo.FindByID(ctx, job.UserID)
+	if err != nil {
+		return "", err
+	}
+	loc, err := loadLocation(user.TimeZone)
+	if err != nil {
+		return "", err
+	}
+
+	archive := &export.AccountArchive{User: user, Location: loc, GeneratedAt: time.Now()}
+	if archive.TrackItems, err = s.trackItemRepo.FindByUserID(ctx, user.ID); err != nil {
+		return "", err
+	}
+	if archive.ShiftTemplates, err = s.templateRepo.FindByUserID(ctx, user.ID); err != nil {
+		return "", err
+	}
+	if archive.AuditLog, err = s.auditRepo.FindByUserID(ctx, user.ID); err != nil {
+		return "", err
+	}
+	if user.Avatar != "" {
+		// The rest of the data is still worth exporting without the avatar
+		if archive.Avatar, err = s.avatars.Load(ctx, user.Avatar); err != nil {
+			slog.Warn("Data export: avatar skipped", "export_id", job.ID, "user_id", user.ID, "err", err)
+		}
+	}
+
+	if err := os.MkdirAll(s.cfg.ExportDir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	// Write to a temporary file so a half-written archive is never served
+	f, err := os.CreateTemp(s.cfg.ExportDir, fmt.Sprintf("export-%d-*.zip.tmp", job.ID))
+	if err != nil {
+		return "", fmt.Errorf("failed to create export file: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if err := export.WriteAccountArchive(f, archive); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	path := filepath.Join(s.cfg.ExportDir, fmt.Sprintf("export-%d.zip", job.ID))
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	return path, nil
+}
+
+// audit records an action on a user's own account
+func (s *AccountService) audit(ctx context.Context, userID int, action, details string) error {
+	return s.auditRepo.Create(ctx, &models.AuditEntry{
+		UserID:  &userID,
+		ActorID: &userID,
+		Action:  action,
+		Details: details,
+	})
+}
+
+// removeExportFile deletes an archive, ignoring archives that are already gone
+func removeExportFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove export file: %w", err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/sergey/work-track-backend/internal/metrics"
 	"github.com/sergey/work-track-backend/internal/models"
 	"github.com/sergey/work-track-backend/internal/repository"
 	"github.com/sergey/work-track-backend/internal/util"
@@ -14,8 +15,16 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrEmailAlreadyExists = errors.New("email already exists")
 	ErrUnauthorized       = errors.New("unauthorized access")
+	ErrAccountDisabled    = errors.New("account is disabled")
+	ErrPasswordTooShort   = errors.New("password too short")
+	// ErrPasswordChangeRequired is returned when a user with a temporary
+	// password logs in, who has to change it first
+	ErrPasswordChangeRequired = errors.New("the password is temporary and must be changed")
 )
 
+// minPasswordLength is the length passwords must have at least
+const minPasswordLength = 6
+
 // AuthService handles authentication business logic
 type AuthService struct {
 	userRepo  *repository.UserRepository
@@ -32,32 +41,12 @@ func NewAuthService(userRepo *repository.UserRepository, jwtSecret string) *Auth
 
 // Register creates a new user account
 func (s *AuthService) Register(ctx context.Context, req *models.UserRegistration) (*models.AuthResponse, error) {
-	// Validate input
-	if req.Login == "" || req.Password == "" {
-		return nil, errors.New("login and password are required")
-	}
-
-	if req.FirstName == "" || req.LastName == "" {
-		return nil, errors.New("first name and last name are required")
-	}
-
-	if len(req.Password) < 6 {
-		return nil, errors.New("password must be at least 6 characters")
-	}
+	ctx, span := tracer.Start(ctx, "AuthService.Register")
+	defer span.End()
 
-	// Hash password
-	hashedPassword, err := util.HashPassword(req.Password)
+	user, err := newUser(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
-	}
-
-	// Create user
-	user := &models.User{
-		FirstName:    req.FirstName,
-		LastName:     req.LastName,
-		Avatar:       req.Avatar,
-		Login:        req.Login,
-		PasswordHash: hashedPassword,
+		return nil, err
 	}
 
 	err = s.userRepo.Create(ctx, user)
@@ -69,9 +58,9 @@ func (s *AuthService) Register(ctx context.Context, req *models.UserRegistration
 	}
 
 	// Generate JWT token
-	token, err := util.GenerateToken(user.ID, s.jwtSecret)
+	token, err := s.issueToken(user)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
 	return &models.AuthResponse{
@@ -82,13 +71,104 @@ func (s *AuthService) Register(ctx context.Context, req *models.UserRegistration
 
 // Login authenticates a user and returns a JWT token
 func (s *AuthService) Login(ctx context.Context, req *models.UserLogin) (*models.AuthResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	// Validate input
+	v := newValidation(ErrValidation)
+	if req.Login == "" {
+		v.add("login", models.FieldRequired, "login is required")
+	}
+	if req.Password == "" {
+		v.add("password", models.FieldRequired, "password is required")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	user, err := s.checkCredentials(ctx, req.Login, req.Password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrAccountDisabled) {
+			metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		}
+		return nil, err
+	}
+	if user.PasswordChangeRequired {
+		metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		return nil, ErrPasswordChangeRequired
+	}
+	metrics.Logins.WithLabelValues(metrics.LoginSucceeded).Inc()
+
+	// Generate JWT token
+	token, err := s.issueToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		Token: token,
+		User:  *user,
+	}, nil
+}
+
+// ChangePassword replaces the password of a user who logs in with the
+// current one, such as a temporary password set by an admin. Tokens issued
+// before are revoked, and a new one is returned.
+func (s *AuthService) ChangePassword(ctx context.Context, req *models.PasswordChange) (*models.AuthResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
 	// Validate input
-	if req.Login == "" || req.Password == "" {
-		return nil, errors.New("login and password are required")
+	v := newValidation(ErrValidation)
+	if req.Login == "" {
+		v.add("login", models.FieldRequired, "login is required")
+	}
+	if req.Password == "" {
+		v.add("password", models.FieldRequired, "password is required")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	if err := validatePassword("new_password", req.NewPassword); err != nil {
+		return nil, err
+	}
+	if req.NewPassword == req.Password {
+		return nil, invalidField(ErrValidation, "new_password", models.FieldInvalidValue, "new_password must differ from the current password")
+	}
+
+	user, err := s.checkCredentials(ctx, req.Login, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := hashPassword(ctx, req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, hashedPassword, false); err != nil {
+		return nil, err
+	}
+
+	// Reload the user for the new token version
+	user, err = s.userRepo.FindByID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	token, err := s.issueToken(user)
+	if err != nil {
+		return nil, err
 	}
 
+	return &models.AuthResponse{
+		Token: token,
+		User:  *user,
+	}, nil
+}
+
+// checkCredentials returns the user with login if password is theirs and
+// the account is not disabled
+func (s *AuthService) checkCredentials(ctx context.Context, login, password string) (*models.User, error) {
 	// Find user by login
-	user, err := s.userRepo.FindByLogin(ctx, req.Login)
+	user, err := s.userRepo.FindByLogin(ctx, login)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
 			return nil, ErrInvalidCredentials
@@ -97,18 +177,93 @@ func (s *AuthService) Login(ctx context.Context, req *models.UserLogin) (*models
 	}
 
 	// Verify password
-	if err := util.CheckPassword(user.PasswordHash, req.Password); err != nil {
+	_, checkSpan := tracer.Start(ctx, "util.CheckPassword")
+	err = util.CheckPassword(user.PasswordHash, password)
+	checkSpan.End()
+	if err != nil {
 		return nil, ErrInvalidCredentials
 	}
+	if user.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
 
-	// Generate JWT token
-	token, err := util.GenerateToken(user.ID, s.jwtSecret)
+	return user, nil
+}
+
+// newUser validates a registration and returns the user to create, with the
+// password hashed
+func newUser(ctx context.Context, req *models.UserRegistration) (*models.User, error) {
+	// Validate input
+	v := newValidation(ErrValidation)
+	if req.Login == "" {
+		v.add("login", models.FieldRequired, "login is required")
+	}
+	if req.Password == "" {
+		v.add("password", models.FieldRequired, "password is required")
+	} else if len(req.Password) < minPasswordLength {
+		v.add("password", models.FieldTooShort, fmt.Sprintf("password must be at least %d characters", minPasswordLength))
+	}
+	if req.FirstName == "" {
+		v.add("first_name", models.FieldRequired, "first name is required")
+	}
+	if req.LastName == "" {
+		v.add("last_name", models.FieldRequired, "last name is required")
+	}
+
+	if req.TimeZone == "" {
+		req.TimeZone = "UTC"
+	}
+	if _, err := loadLocation(req.TimeZone); err != nil {
+		v.add("time_zone", models.FieldInvalidValue, err.Error())
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := hashPassword(ctx, req.Password)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
-	return &models.AuthResponse{
-		Token: token,
-		User:  *user,
+	return &models.User{
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		Avatar:       req.Avatar,
+		Login:        req.Login,
+		PasswordHash: hashedPassword,
+		TimeZone:     req.TimeZone,
 	}, nil
 }
+
+// validatePassword checks that a new password, sent as field, is long enough
+func validatePassword(field, password string) error {
+	if len(password) < minPasswordLength {
+		return invalidField(ErrPasswordTooShort, field, models.FieldTooShort, fmt.Sprintf("%s must be at least %d characters", field, minPasswordLength))
+	}
+
+	return nil
+}
+
+// hashPassword hashes a password for storage
+func hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracer.Start(ctx, "util.HashPassword")
+	defer span.End()
+
+	hashedPassword, err := util.HashPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return hashedPassword, nil
+}
+
+// issueToken generates an access token for a user at its token version
+func (s *AuthService) issueToken(user *models.User) (string, error) {
+	token, err := util.GenerateToken(user.ID, user.TokenVersion, s.jwtSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	metrics.TokensIssued.Inc()
+
+	return token, nil
+}
@@ -0,0 +1,519 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/storage"
+)
+
+var (
+	ErrBackupsDisabled    = errors.New("backups are not configured")
+	ErrBackupInProgress   = errors.New("a backup is already running")
+	ErrBackupNotFound     = errors.New("backup not found")
+	ErrBackupPassphrase   = errors.New("the backup is encrypted and the passphrase is missing or wrong")
+	ErrDatabaseFileExists = errors.New("the database file exists, stop the server and restore with force")
+	errInvalidBackupKey   = errors.New("not a backup key")
+)
+
+const (
+	// backupRetryInterval is the wait after a scheduled backup failed
+	backupRetryInterval = 10 * time.Minute
+
+	// Backups are stored as backups/worktrack-20060102T150405Z.db.gz, with
+	// .age appended when they are encrypted
+	backupKeyPrefix       = "backups/"
+	backupNamePrefix      = "worktrack-"
+	backupTimeFormat      = "20060102T150405Z"
+	backupExtension       = ".db.gz"
+	backupEncryptedSuffix = ".age"
+)
+
+// BackupService takes compressed, optionally encrypted snapshots of the
+// database, stores them and prunes them by the retention policy
+type BackupService struct {
+	schemaRepo *repository.SchemaRepository
+	userRepo   *repository.UserRepository
+	store      storage.Store // nil when backups are not configured
+	cfg        config.BackupConfig
+	running    sync.Mutex
+}
+
+// NewBackupService creates a new backup service. store may be nil, in which
+// case backups are disabled.
+func NewBackupService(schemaRepo *repository.SchemaRepository, userRepo *repository.UserRepository, store storage.Store, cfg config.BackupConfig) *BackupService {
+	return &BackupService{
+		schemaRepo: schemaRepo,
+		userRepo:   userRepo,
+		store:      store,
+		cfg:        cfg,
+	}
+}
+
+// CreateBackup takes a backup on behalf of an admin
+func (s *BackupService) CreateBackup(ctx context.Context, actorID int) (*models.Backup, error) {
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+
+	return s.Backup(ctx)
+}
+
+// ListBackups returns the stored backups, newest first, to an admin
+func (s *BackupService) ListBackups(ctx context.Context, actorID int) ([]models.Backup, error) {
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	if s.store == nil {
+		return nil, ErrBackupsDisabled
+	}
+
+	return listBackups(ctx, s.store)
+}
+
+// ListStoredBackups returns the backups in store, newest first, for tools
+// that run without the server
+func ListStoredBackups(ctx context.Context, store storage.Store) ([]models.Backup, error) {
+	return listBackups(ctx, store)
+}
+
+// Backup snapshots the database with VACUUM INTO, compresses and, with a
+// passphrase configured, encrypts the snapshot, stores it and then deletes
+// the backups the retention policy no longer keeps
+func (s *BackupService) Backup(ctx context.Context) (*models.Backup, error) {
+	if s.store == nil {
+		return nil, ErrBackupsDisabled
+	}
+	if !s.running.TryLock() {
+		return nil, ErrBackupInProgress
+	}
+	defer s.running.Unlock()
+
+	tmpDir, err := os.MkdirTemp("", "worktrack-backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	snapshot := filepath.Join(tmpDir, "snapshot.db")
+	if err := s.schemaRepo.Snapshot(ctx, snapshot); err != nil {
+		return nil, err
+	}
+
+	backup := &models.Backup{
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Encrypted: s.cfg.Passphrase != "",
+	}
+	backup.Key = backupKey(backup.CreatedAt, backup.Encrypted)
+
+	archive := filepath.Join(tmpDir, filepath.Base(backup.Key))
+	if err := writeBackupArchive(snapshot, archive, s.cfg.Passphrase); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	backup.Size = info.Size()
+
+	if err := s.store.Put(ctx, backup.Key, f, backup.Size); err != nil {
+		return nil, err
+	}
+	slog.Info("Database backed up", "key", backup.Key, "size", backup.Size, "store", s.store.String())
+
+	// The new backup is stored; a failed cleanup is retried after the next one
+	if err := s.prune(ctx); err != nil {
+		slog.Error("Failed to delete old backups", "err", err)
+	}
+
+	return backup, nil
+}
+
+// Run takes a backup whenever the newest one is older than the backup
+// interval, until ctx is cancelled. It does nothing when backups are not
+// configured or the interval is 0.
+func (s *BackupService) Run(ctx context.Context) {
+	if s.store == nil || s.cfg.Interval <= 0 {
+		return
+	}
+
+	for {
+		wait := s.untilNextBackup(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if _, err := s.Backup(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Scheduled backup failed", "err", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backupRetryInterval):
+			}
+		}
+	}
+}
+
+// untilNextBackup returns how long to wait for the next scheduled backup
+func (s *BackupService) untilNextBackup(ctx context.Context) time.Duration {
+	backups, err := listBackups(ctx, s.store)
+	if err != nil {
+		slog.Error("Failed to list backups", "err", err)
+		return backupRetryInterval
+	}
+	if len(backups) == 0 {
+		return 0
+	}
+
+	return max(time.Until(backups[0].CreatedAt.Add(s.cfg.Interval)), 0)
+}
+
+// prune deletes the backups the retention policy does not keep
+func (s *BackupService) prune(ctx context.Context) error {
+	backups, err := listBackups(ctx, s.store)
+	if err != nil {
+		return err
+	}
+
+	keep := retainedBackups(backups, s.cfg)
+	for _, backup := range backups {
+		if keep[backup.Key] {
+			continue
+		}
+		if err := s.store.Delete(ctx, backup.Key); err != nil {
+			return err
+		}
+		slog.Info("Deleted old backup", "key", backup.Key)
+	}
+
+	return nil
+}
+
+// requireAdmin returns ErrUnauthorized unless the user is an admin
+func (s *BackupService) requireAdmin(ctx context.Context, userID int) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if user.Role != models.RoleAdmin {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// RestoreBackup replaces the database file at dbPath with a backup from
+// store: the one stored under key, or the newest one when key is empty. The
+// server must not be running. An existing database file is only replaced
+// with force. The restored copy is integrity checked before it is moved into
+// place.
+func RestoreBackup(ctx context.Context, store storage.Store, cfg config.BackupConfig, key, dbPath string, force bool) (*models.Backup, error) {
+	backups, err := listBackups(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	var backup *models.Backup
+	for i := range backups {
+		if key == "" || backups[i].Key == key {
+			backup = &backups[i]
+			break
+		}
+	}
+	if backup == nil {
+		return nil, ErrBackupNotFound
+	}
+
+	if _, err := os.Stat(dbPath); err == nil && !force {
+		return nil, ErrDatabaseFileExists
+	}
+
+	// Restore next to the database, so the final rename stays on one file system
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dbPath), filepath.Base(dbPath)+".restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restore file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	err = readBackupArchive(ctx, store, backup, cfg.Passphrase, tmp)
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write restore file: %w", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkDatabaseFile(ctx, tmpPath); err != nil {
+		return nil, err
+	}
+
+	// A WAL left by the old database would be replayed into the restored one
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove %s: %w", dbPath+suffix, err)
+		}
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		return nil, fmt.Errorf("failed to move restored database into place: %w", err)
+	}
+
+	return backup, nil
+}
+
+// listBackups returns the backups in store, newest first
+func listBackups(ctx context.Context, store storage.Store) ([]models.Backup, error) {
+	objects, err := store.List(ctx, backupKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]models.Backup, 0, len(objects))
+	for _, obj := range objects {
+		createdAt, encrypted, err := parseBackupKey(obj.Key)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, models.Backup{Key: obj.Key, Size: obj.Size, Encrypted: encrypted, CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+
+	return backups, nil
+}
+
+// retainedBackups returns the keys of the backups the retention policy keeps:
+// the newest backup of each of the most recent KeepDaily days, KeepWeekly
+// weeks and KeepMonthly months that have backups, and always the newest
+// backup. backups must be sorted newest first.
+func retainedBackups(backups []models.Backup, cfg config.BackupConfig) map[string]bool {
+	keep := make(map[string]bool)
+	if len(backups) > 0 {
+		keep[backups[0].Key] = true
+	}
+
+	periods := []struct {
+		count  int
+		period func(t time.Time) string
+	}{
+		{cfg.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{cfg.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{cfg.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, p := range periods {
+		seen := make(map[string]bool)
+		for _, backup := range backups {
+			if len(seen) >= p.count {
+				break
+			}
+			period := p.period(backup.CreatedAt.UTC())
+			if !seen[period] {
+				seen[period] = true
+				keep[backup.Key] = true
+			}
+		}
+	}
+
+	return keep
+}
+
+// backupKey returns the storage key of a backup taken at t
+func backupKey(t time.Time, encrypted bool) string {
+	key := backupKeyPrefix + backupNamePrefix + t.UTC().Format(backupTimeFormat) + backupExtension
+	if encrypted {
+		key += backupEncryptedSuffix
+	}
+	return key
+}
+
+// parseBackupKey returns when the backup stored under key was taken and
+// whether it is encrypted
+func parseBackupKey(key string) (time.Time, bool, error) {
+	name, ok := strings.CutPrefix(key, backupKeyPrefix+backupNamePrefix)
+	if !ok {
+		return time.Time{}, false, errInvalidBackupKey
+	}
+	name, encrypted := strings.CutSuffix(name, backupEncryptedSuffix)
+	name, ok = strings.CutSuffix(name, backupExtension)
+	if !ok {
+		return time.Time{}, false, errInvalidBackupKey
+	}
+
+	t, err := time.Parse(backupTimeFormat, name)
+	if err != nil {
+		return time.Time{}, false, errInvalidBackupKey
+	}
+
+	return t, encrypted, nil
+}
+
+// writeBackupArchive gzips the snapshot file into dst, encrypting it with the
+// passphrase when one is given
+func writeBackupArchive(snapshot, dst, passphrase string) error {
+	var recipient age.Recipient
+	if passphrase != "" {
+		r, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return fmt.Errorf("invalid backup passphrase: %w", err)
+		}
+		recipient = r
+	}
+
+	in, err := os.Open(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create backup archive: %w", err)
+	}
+	defer out.Close()
+
+	w, err := newArchiveWriter(out, recipient)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		return fmt.Errorf("failed to compress backup: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// readBackupArchive downloads a backup, decrypting and decompressing it into w
+func readBackupArchive(ctx context.Context, store storage.Store, backup *models.Backup, passphrase string, w io.Writer) error {
+	obj, err := store.Get(ctx, backup.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrBackupNotFound
+		}
+		return err
+	}
+	defer obj.Close()
+
+	var identity age.Identity
+	if backup.Encrypted {
+		if passphrase == "" {
+			return ErrBackupPassphrase
+		}
+		if identity, err = age.NewScryptIdentity(passphrase); err != nil {
+			return ErrBackupPassphrase
+		}
+	}
+
+	r, err := openArchive(obj, identity)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	return r.Close()
+}
+
+// checkDatabaseFile opens the SQLite file at path and runs its integrity check
+func checkDatabaseFile(ctx context.Context, path string) error {
+	db, err := database.NewSQLiteDB(path)
+	if err != nil {
+		return fmt.Errorf("restored database cannot be opened: %w", err)
+	}
+	defer database.Close(db)
+
+	return repository.NewSchemaRepository(db).IntegrityCheck(ctx)
+}
+
+// archiveWriter gzips what is written to it and, with a recipient, encrypts
+// the compressed stream with age
+type archiveWriter struct {
+	gz  *gzip.Writer
+	enc io.WriteCloser
+}
+
+// newArchiveWriter creates an archive writing to w. recipient may be nil, in
+// which case the archive is not encrypted.
+func newArchiveWriter(w io.Writer, recipient age.Recipient) (*archiveWriter, error) {
+	var enc io.WriteCloser = nopWriteCloser{w}
+	if recipient != nil {
+		var err error
+		if enc, err = age.Encrypt(w, recipient); err != nil {
+			return nil, fmt.Errorf("failed to encrypt archive: %w", err)
+		}
+	}
+
+	return &archiveWriter{gz: gzip.NewWriter(enc), enc: enc}, nil
+}
+
+func (a *archiveWriter) Write(p []byte) (int, error) {
+	return a.gz.Write(p)
+}
+
+// Close flushes the archive. The underlying writer is left open.
+func (a *archiveWriter) Close() error {
+	if err := a.gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress archive: %w", err)
+	}
+	if err := a.enc.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt archive: %w", err)
+	}
+
+	return nil
+}
+
+// openArchive reads an archive written by archiveWriter. identity may be nil
+// for an archive that is not encrypted.
+func openArchive(r io.Reader, identity age.Identity) (*gzip.Reader, error) {
+	if identity != nil {
+		var err error
+		if r, err = age.Decrypt(r, identity); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackupPassphrase, err)
+		}
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+
+	return gz, nil
+}
+
+// nopWriteCloser adds a Close method that does nothing to a writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
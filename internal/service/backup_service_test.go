@@ -0,0 +1,350 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/storage"
+)
+
+// fakeS3 is an S3-compatible server holding the objects of one bucket in
+// memory. It serves the path-style requests S3Store makes and does not check
+// signatures.
+type fakeS3 struct {
+	bucket  string
+	mu      sync.Mutex
+	objects map[string]fakeS3Object
+}
+
+type fakeS3Object struct {
+	data    []byte
+	modTime time.Time
+}
+
+// newFakeS3 starts a fake S3 server and returns it with a store for its bucket
+func newFakeS3(t *testing.T, prefix string) (*fakeS3, storage.Store) {
+	t.Helper()
+
+	fake := &fakeS3{bucket: "worktrack", objects: make(map[string]fakeS3Object)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	store, err := storage.NewS3Store(config.S3Config{
+		Endpoint:  srv.URL,
+		Bucket:    fake.bucket,
+		AccessKey: "access",
+		SecretKey: "secret",
+		Region:    "us-east-1",
+		Prefix:    prefix,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fake, store
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		f.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, r.URL.Query().Get("prefix"))
+	case key == "" && r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut:
+		data, err := readS3Payload(r)
+		if err != nil {
+			f.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = fakeS3Object{data: data, modTime: time.Now().UTC()}
+		w.Header().Set("ETag", strconv.Quote(key))
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", strconv.Quote(key))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, key, obj.modTime, bytes.NewReader(obj.data))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// list answers a ListObjectsV2 request with every object under prefix
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		MaxKeys     int
+		IsTruncated bool
+		Contents    []content
+	}{Name: f.bucket, Prefix: prefix, MaxKeys: 1000}
+
+	for key, obj := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, content{
+				Key:          key,
+				LastModified: obj.modTime.Format("2006-01-02T15:04:05.000Z"),
+				ETag:         strconv.Quote(key),
+				Size:         len(obj.data),
+			})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// put stores an object as if it was uploaded earlier
+func (f *fakeS3) put(key string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[key] = fakeS3Object{data: data, modTime: time.Now().UTC()}
+}
+
+// object returns the stored object under key
+func (f *fakeS3) object(key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[key]
+	return obj.data, ok
+}
+
+// keys returns the keys of the stored objects, sorted
+func (f *fakeS3) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// readS3Payload reads the body of an upload, decoding the aws-chunked
+// encoding clients use to sign the payload over plain HTTP
+func readS3Payload(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, size+2) // The chunk ends with CRLF
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+func TestBackupS3(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	admin := newTestUser(t, db, "admin", models.RoleAdmin)
+	user := newTestUser(t, db, "anna", models.RoleUser)
+
+	fake, store := newFakeS3(t, "/worktrack/")
+	cfg := config.BackupConfig{Passphrase: "correct horse battery staple", KeepDaily: 2, KeepMonthly: 3}
+	s := NewBackupService(repository.NewSchemaRepository(db), userRepo, store, cfg)
+
+	// Backups taken earlier, and an object that is not a backup
+	for _, key := range []string{
+		"backups/worktrack-20240110T000000Z.db.gz.age",
+		"backups/worktrack-20240120T000000Z.db.gz.age",
+		"backups/worktrack-20240205T000000Z.db.gz",
+		"backups/worktrack-20240205T120000Z.db.gz.age",
+		"backups/notes.txt",
+	} {
+		fake.put("worktrack/"+key, []byte("old backup"))
+	}
+
+	if _, err := s.CreateBackup(ctx, user.ID); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("backup by a user: got error %v, want %v", err, ErrUnauthorized)
+	}
+	backup, err := s.CreateBackup(ctx, admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !backup.Encrypted || !strings.HasSuffix(backup.Key, ".db.gz.age") {
+		t.Errorf("got backup %+v, want it encrypted", backup)
+	}
+
+	// The upload is stored under the prefix, encrypted
+	data, ok := fake.object("worktrack/" + backup.Key)
+	if !ok {
+		t.Fatalf("%s is not in the bucket, got %v", backup.Key, fake.keys())
+	}
+	if int64(len(data)) != backup.Size || !bytes.HasPrefix(data, []byte("age-encryption.org/")) {
+		t.Errorf("got an object of %d bytes, want an age file of %d", len(data), backup.Size)
+	}
+
+	// The newest backup of the two latest days and three latest months is kept
+	want := []string{
+		"worktrack/backups/notes.txt",
+		"worktrack/backups/worktrack-20240120T000000Z.db.gz.age",
+		"worktrack/backups/worktrack-20240205T120000Z.db.gz.age",
+		"worktrack/" + backup.Key,
+	}
+	slices.Sort(want)
+	if got := fake.keys(); !slices.Equal(got, want) {
+		t.Errorf("after pruning got objects %v, want %v", got, want)
+	}
+
+	if _, err := s.ListBackups(ctx, user.ID); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("listing by a user: got error %v, want %v", err, ErrUnauthorized)
+	}
+	backups, err := s.ListBackups(ctx, admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, b := range backups {
+		keys = append(keys, b.Key)
+	}
+	wantKeys := []string{backup.Key, "backups/worktrack-20240205T120000Z.db.gz.age", "backups/worktrack-20240120T000000Z.db.gz.age"}
+	if !slices.Equal(keys, wantKeys) {
+		t.Errorf("got backups %v, want %v newest first", keys, wantKeys)
+	}
+	if backups[0].Size != backup.Size || !backups[0].CreatedAt.Equal(backup.CreatedAt) {
+		t.Errorf("listed %+v, want %+v", backups[0], *backup)
+	}
+
+	// The newest backup is decrypted into a new database
+	dbPath := filepath.Join(t.TempDir(), "restored.db")
+	restored, err := RestoreBackup(ctx, store, cfg, "", dbPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Key != backup.Key {
+		t.Errorf("restored %s, want the newest backup %s", restored.Key, backup.Key)
+	}
+	if got := backupLogins(t, dbPath); !slices.Equal(got, []string{"admin", "anna"}) {
+		t.Errorf("restored users %v, want admin and anna", got)
+	}
+
+	tests := []struct {
+		name       string
+		passphrase string
+		key        string
+		force      bool
+		want       error
+	}{
+		{name: "existing database without force", passphrase: cfg.Passphrase, want: ErrDatabaseFileExists},
+		{name: "wrong passphrase", passphrase: "wrong", force: true, want: ErrBackupPassphrase},
+		{name: "no passphrase", force: true, want: ErrBackupPassphrase},
+		{name: "unknown key", passphrase: cfg.Passphrase, key: "backups/worktrack-20200101T000000Z.db.gz", force: true, want: ErrBackupNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.Passphrase = tt.passphrase
+			if _, err := RestoreBackup(ctx, store, cfg, tt.key, dbPath, tt.force); !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+			// A failed restore leaves the database as it was
+			if got := backupLogins(t, dbPath); !slices.Equal(got, []string{"admin", "anna"}) {
+				t.Errorf("after the failed restore got users %v", got)
+			}
+		})
+	}
+}
+
+// backupLogins returns the logins of the users in the database file at path
+func backupLogins(t *testing.T, path string) []string {
+	t.Helper()
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.NewSQLiteDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT login FROM users ORDER BY login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var logins []string
+	for rows.Next() {
+		var login string
+		if err := rows.Scan(&login); err != nil {
+			t.Fatal(err)
+		}
+		logins = append(logins, login)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return logins
+}
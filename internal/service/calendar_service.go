@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// The feed covers recent history and everything planned ahead
+const (
+	calendarPastDays   = 180
+	calendarFutureDays = 2 * 366
+)
+
+// CalendarFeed is the content of a user's calendar feed
+type CalendarFeed struct {
+	User     *models.User
+	Location *time.Location
+	Items    []models.TrackItem
+}
+
+// CalendarService handles calendar feed tokens and feeds
+type CalendarService struct {
+	tokenRepo     *repository.CalendarTokenRepository
+	trackItemRepo *repository.TrackItemRepository
+	userRepo      *repository.UserRepository
+}
+
+// NewCalendarService creates a new calendar service
+func NewCalendarService(tokenRepo *repository.CalendarTokenRepository, trackItemRepo *repository.TrackItemRepository, userRepo *repository.UserRepository) *CalendarService {
+	return &CalendarService{
+		tokenRepo:     tokenRepo,
+		trackItemRepo: trackItemRepo,
+		userRepo:      userRepo,
+	}
+}
+
+// RegenerateToken creates a new feed token for a user. The previous token, if
+// any, stops working. The token is returned only here; just its hash is stored.
+func (s *CalendarService) RegenerateToken(ctx context.Context, userID int) (string, *models.CalendarToken, error) {
+	token, err := newSecretToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	stored := &models.CalendarToken{UserID: userID, TokenHash: hashToken(token)}
+	if err := s.tokenRepo.Replace(ctx, stored); err != nil {
+		return "", nil, err
+	}
+
+	return token, stored, nil
+}
+
+// GetTokenStatus reports whether a user has a feed token
+func (s *CalendarService) GetTokenStatus(ctx context.Context, userID int) (*models.CalendarTokenStatus, error) {
+	token, err := s.tokenRepo.FindByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrCalendarTokenNotFound) {
+		return &models.CalendarTokenStatus{Enabled: false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CalendarTokenStatus{Enabled: true, CalendarToken: token}, nil
+}
+
+// RevokeToken disables a user's feed
+func (s *CalendarService) RevokeToken(ctx context.Context, userID int) error {
+	return s.tokenRepo.DeleteByUserID(ctx, userID)
+}
+
+// GetFeed returns the track items of the feed identified by token, optionally
+// limited to the given types. Unknown tokens, and tokens of accounts that are
+// disabled or scheduled for deletion, return repository.ErrCalendarTokenNotFound.
+func (s *CalendarService) GetFeed(ctx context.Context, token string, types []string) (*CalendarFeed, error) {
+	stored, err := s.tokenRepo.FindByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user.IsDisabled() || user.DeletionScheduledAt != nil {
+		return nil, repository.ErrCalendarTokenNotFound
+	}
+	loc, err := loadLocation(user.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start := now.AddDate(0, 0, -calendarPastDays)
+	end := now.AddDate(0, 0, calendarFutureDays)
+	items, err := s.trackItemRepo.FindPage(ctx, user.ID, &repository.TrackItemQuery{
+		StartDate: &start,
+		EndDate:   &end,
+		Types:     types,
+		SortField: repository.SortByDate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get track items: %w", err)
+	}
+
+	// Usage tracking is informational; a failure must not break the feed
+	if err := s.tokenRepo.Touch(ctx, stored.ID); err != nil {
+		slog.Warn("Failed to record calendar feed use", "user_id", user.ID, "err", err)
+	}
+
+	return &CalendarFeed{User: user, Location: loc, Items: items}, nil
+}
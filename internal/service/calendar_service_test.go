@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+func TestGetFeedInactiveAccount(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	s := NewCalendarService(repository.NewCalendarTokenRepository(db), repository.NewTrackItemRepository(db), userRepo)
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		deactivate func(userID int) error
+		restore    func(userID int) error
+	}{
+		{
+			name:       "disabled",
+			deactivate: func(userID int) error { return userRepo.SetDisabledAt(ctx, userID, &now) },
+			restore:    func(userID int) error { return userRepo.SetDisabledAt(ctx, userID, nil) },
+		},
+		{
+			name:       "scheduled for deletion",
+			deactivate: func(userID int) error { return userRepo.SetDeletionScheduledAt(ctx, userID, &now) },
+			restore:    func(userID int) error { return userRepo.SetDeletionScheduledAt(ctx, userID, nil) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := newTestUser(t, db, "user-"+tt.name, models.RoleUser)
+			token, _, err := s.RegenerateToken(ctx, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.deactivate(user.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.GetFeed(ctx, token, nil); !errors.Is(err, repository.ErrCalendarTokenNotFound) {
+				t.Errorf("got error %v, want %v", err, repository.ErrCalendarTokenNotFound)
+			}
+
+			// The same feed URL works again once the account is reactivated
+			if err := tt.restore(user.ID); err != nil {
+				t.Fatal(err)
+			}
+			feed, err := s.GetFeed(ctx, token, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if feed.User.ID != user.ID {
+				t.Errorf("got the feed of user %d, want %d", feed.User.ID, user.ID)
+			}
+		})
+	}
+}
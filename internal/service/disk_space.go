@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package service
+
+import "syscall"
+
+// freeDiskSpace returns the number of bytes available to the server on the
+// file system holding dir
+func freeDiskSpace(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
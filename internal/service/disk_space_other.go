@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package service
+
+// freeDiskSpace is not supported on this platform
+func freeDiskSpace(dir string) (uint64, error) {
+	return 0, errDiskSpaceUnsupported
+}
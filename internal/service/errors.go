@@ -0,0 +1,86 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// ErrValidation is the kind of validation errors of requests that have no
+// more specific kind
+var ErrValidation = errors.New("invalid request")
+
+// ValidationError reports the invalid fields of a request. It wraps the kind
+// of request that was invalid, such as ErrInvalidListQuery, so that errors.Is
+// still matches it.
+type ValidationError struct {
+	Kind   error
+	Fields []models.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+
+	return fmt.Sprintf("%v: %s", e.Kind, strings.Join(messages, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Kind
+}
+
+// invalidField returns a validation error of a single field
+func invalidField(kind error, field, code, message string) error {
+	return &ValidationError{
+		Kind:   kind,
+		Fields: []models.FieldError{{Field: field, Message: message, Code: code}},
+	}
+}
+
+// validation collects the invalid fields of a request, so that all of them
+// are reported at once
+type validation struct {
+	kind   error
+	fields []models.FieldError
+}
+
+// newValidation starts collecting the invalid fields of a kind of request
+func newValidation(kind error) *validation {
+	return &validation{kind: kind}
+}
+
+// add records an invalid field
+func (v *validation) add(field, code, message string) {
+	v.fields = append(v.fields, models.FieldError{Field: field, Message: message, Code: code})
+}
+
+// err returns the collected fields as a *ValidationError, or nil when every
+// field was valid
+func (v *validation) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Kind: v.kind, Fields: v.fields}
+}
+
+// prefixFields returns err with the fields of a *ValidationError prefixed,
+// for requests nested in a larger one. Other errors are returned as they are.
+func prefixFields(err error, prefix string) error {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+
+	fields := make([]models.FieldError, len(verr.Fields))
+	for i, field := range verr.Fields {
+		field.Field = prefix + field.Field
+		fields[i] = field
+	}
+
+	return &ValidationError{Kind: verr.Kind, Fields: fields}
+}
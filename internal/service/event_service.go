@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sergey/work-track-backend/internal/events"
+	"github.com/sergey/work-track-backend/internal/metrics"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// EventService distributes changes: it queues them for webhooks and pushes
+// them to clients connected to the live event stream
+type EventService struct {
+	webhooks *WebhookService
+	hub      *events.Hub
+	userRepo *repository.UserRepository
+}
+
+// NewEventService creates a new event service
+func NewEventService(webhooks *WebhookService, hub *events.Hub, userRepo *repository.UserRepository) *EventService {
+	return &EventService{
+		webhooks: webhooks,
+		hub:      hub,
+		userRepo: userRepo,
+	}
+}
+
+// Subscribe subscribes to the live events about a user or, with team set, about
+// all users, which only supervisors may do. See events.Hub.Subscribe for the
+// replay and resumed results.
+func (s *EventService) Subscribe(ctx context.Context, userID int, team bool, lastEventID string) (*events.Subscription, []events.Event, bool, error) {
+	filter := func(e events.Event) bool { return e.UserID == userID }
+	if team {
+		user, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to find user: %w", err)
+		}
+		if !user.IsSupervisor() {
+			return nil, nil, false, ErrUnauthorized
+		}
+		filter = func(events.Event) bool { return true }
+	}
+
+	sub, replay, resumed := s.hub.Subscribe(lastEventID, filter)
+	return sub, replay, resumed, nil
+}
+
+// trackItemChanged queues an event about a track item for webhooks in the
+// transaction of repo, and publishes it to live subscribers once that
+// transaction has been committed
+func (s *EventService) trackItemChanged(ctx context.Context, repo *repository.TrackItemRepository, eventType string, item *models.TrackItem) error {
+	if err := s.webhooks.record(ctx, s.webhooks.repo.BoundTo(repo), item.UserID, eventType, item); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	repo.AfterCommit(func() {
+		metrics.TrackItemChanges.WithLabelValues(eventType).Inc()
+		s.webhooks.notify()
+		s.hub.Publish(events.Event{Type: eventType, UserID: item.UserID, Data: data})
+	})
+
+	return nil
+}
+
+// timesheetApproved queues a timesheet.approved event for webhooks in the
+// transaction of repo, and publishes it to live subscribers once that
+// transaction has been committed
+func (s *EventService) timesheetApproved(ctx context.Context, repo *repository.TrackItemRepository, approval *models.TimesheetApproval) error {
+	if err := s.webhooks.record(ctx, s.webhooks.repo.BoundTo(repo), approval.UserID, models.EventTimesheetApproved, approval); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(approval)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", models.EventTimesheetApproved, err)
+	}
+	repo.AfterCommit(func() {
+		s.webhooks.notify()
+		s.hub.Publish(events.Event{Type: models.EventTimesheetApproved, UserID: approval.UserID, Data: data})
+	})
+
+	return nil
+}
+
+// timerChanged publishes an event about a timer to live subscribers once the
+// transaction of repo has been committed. Timers are not sent to webhooks.
+func (s *EventService) timerChanged(repo *repository.TrackItemRepository, eventType string, timer *models.Timer) error {
+	data, err := json.Marshal(timer)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	repo.AfterCommit(func() {
+		s.hub.Publish(events.Event{Type: eventType, UserID: timer.UserID, Data: data})
+	})
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/storage"
+)
+
+// errDiskSpaceUnsupported is returned where free disk space cannot be read
+var errDiskSpaceUnsupported = errors.New("free disk space is not available on this platform")
+
+// errNotReady is returned by a check that does not pass but is not a failure,
+// such as one that cannot tell
+var errNotReady = errors.New("not ready")
+
+// HealthService checks whether the server and its dependencies are ready to
+// handle requests
+type HealthService struct {
+	schemaRepo *repository.SchemaRepository
+	dbDir      string
+	blobStore  storage.Store
+	cfg        config.HealthConfig
+	draining   atomic.Bool
+}
+
+// NewHealthService creates a new health service. dbPath is the SQLite
+// database file, whose directory is checked for free space. blobStore is the
+// backup store; when it is nil the local export directory is checked instead.
+func NewHealthService(schemaRepo *repository.SchemaRepository, dbPath string, blobStore storage.Store, exportDir string, cfg config.HealthConfig) *HealthService {
+	if blobStore == nil {
+		blobStore = storage.NewLocalStore(exportDir)
+	}
+
+	return &HealthService{
+		schemaRepo: schemaRepo,
+		dbDir:      filepath.Dir(dbPath),
+		blobStore:  blobStore,
+		cfg:        cfg,
+	}
+}
+
+// Drain makes readiness fail from now on, so that load balancers stop sending
+// requests before the server shuts down
+func (s *HealthService) Drain() {
+	s.draining.Store(true)
+}
+
+// healthCheck is a single readiness check. It returns a message describing
+// what it found, and an error when the check failed; errNotReady wrapped in
+// the error makes the check unknown rather than failed.
+type healthCheck func(ctx context.Context) (string, error)
+
+// Ready runs the readiness checks concurrently, each with the configured
+// timeout. The report's status is "fail" when any check failed.
+func (s *HealthService) Ready(ctx context.Context) *models.HealthReport {
+	if s.draining.Load() {
+		return &models.HealthReport{
+			Status: models.HealthStatusFail,
+			Checks: map[string]models.HealthCheck{
+				"shutdown": {Status: models.HealthStatusFail, Message: "server is shutting down"},
+			},
+		}
+	}
+
+	checks := map[string]healthCheck{
+		"database":     s.checkDatabase,
+		"migrations":   s.checkMigrations,
+		"disk":         s.checkDisk,
+		"blob_storage": s.checkBlobStorage,
+	}
+
+	report := &models.HealthReport{
+		Status: models.HealthStatusOK,
+		Checks: make(map[string]models.HealthCheck, len(checks)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := s.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status == models.HealthStatusFail {
+				report.Status = models.HealthStatusFail
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// run runs check with the check timeout
+func (s *HealthService) run(ctx context.Context, check healthCheck) models.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.CheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	message, err := check(ctx)
+	result := models.HealthCheck{
+		Status:     models.HealthStatusOK,
+		Message:    message,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	switch {
+	case errors.Is(err, errNotReady):
+		result.Status = models.HealthStatusUnknown
+	case errors.Is(err, context.DeadlineExceeded):
+		result.Status = models.HealthStatusFail
+		result.Message = fmt.Sprintf("timed out after %s", s.cfg.CheckTimeout)
+	case err != nil:
+		result.Status = models.HealthStatusFail
+		result.Message = err.Error()
+	}
+
+	return result
+}
+
+// checkDatabase verifies the database file can be read
+func (s *HealthService) checkDatabase(ctx context.Context) (string, error) {
+	return "", s.schemaRepo.Ping(ctx)
+}
+
+// checkMigrations verifies the database schema is not older than the one
+// this build expects. A newer schema is accepted, so that a release can be
+// rolled back after migrating.
+func (s *HealthService) checkMigrations(ctx context.Context) (string, error) {
+	version, dirty, err := s.schemaRepo.Version(ctx)
+	if errors.Is(err, repository.ErrSchemaVersionUnknown) {
+		return "the database does not record its schema version", errNotReady
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if dirty {
+		return "", fmt.Errorf("migration %d failed part way", version)
+	}
+	if version < database.SchemaVersion {
+		return "", fmt.Errorf("schema version is %d, %d is required", version, database.SchemaVersion)
+	}
+
+	return fmt.Sprintf("version %d", version), nil
+}
+
+// checkDisk verifies the database directory has the configured free space
+func (s *HealthService) checkDisk(ctx context.Context) (string, error) {
+	free, err := freeDiskSpace(s.dbDir)
+	if errors.Is(err, errDiskSpaceUnsupported) {
+		return err.Error(), errNotReady
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read free disk space: %w", err)
+	}
+
+	message := fmt.Sprintf("%d MB free", free>>20)
+	if free < s.cfg.MinFreeDisk {
+		return "", fmt.Errorf("%s, %d MB required", message, s.cfg.MinFreeDisk>>20)
+	}
+
+	return message, nil
+}
+
+// checkBlobStorage verifies the blob store can be reached
+func (s *HealthService) checkBlobStorage(ctx context.Context) (string, error) {
+	if err := s.blobStore.Ping(ctx); err != nil {
+		return "", err
+	}
+
+	return s.blobStore.String(), nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+var (
+	ErrInvalidIdempotencyKey    = errors.New("idempotency key must be 1 to 255 printable characters")
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+const (
+	// maxIdempotencyKeyLength limits the length of an Idempotency-Key header
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTimeout is how long a key stays reserved for a request
+	// that never completed, such as one interrupted by a restart
+	idempotencyLockTimeout = time.Minute
+	// idempotencyPurgeInterval is how often expired keys are removed
+	idempotencyPurgeInterval = time.Hour
+)
+
+// IdempotencyService stores the responses of requests sent with an
+// Idempotency-Key header, so that retried requests are not applied twice
+type IdempotencyService struct {
+	repo *repository.IdempotencyRepository
+	cfg  config.IdempotencyConfig
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService(repo *repository.IdempotencyRepository, cfg config.IdempotencyConfig) *IdempotencyService {
+	return &IdempotencyService{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// Begin reserves a user's key for a request. It returns nil when the request
+// should be processed, or the stored key whose response should be replayed
+// when the same request was already completed. A key reused with a different
+// request fails with ErrIdempotencyKeyMismatch, and one whose first request is
+// still running with ErrIdempotencyKeyInProgress.
+func (s *IdempotencyService) Begin(ctx context.Context, userID int, key, requestHash string) (*models.IdempotencyKey, error) {
+	if !validIdempotencyKey(key) {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	now := time.Now()
+	if err := s.repo.DeleteStale(ctx, userID, key, now, now.Add(-idempotencyLockTimeout)); err != nil {
+		return nil, err
+	}
+
+	err := s.repo.Create(ctx, &models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(s.cfg.TTL),
+	})
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, repository.ErrIdempotencyKeyExists) {
+		return nil, err
+	}
+
+	stored, err := s.repo.Find(ctx, userID, key)
+	if err != nil {
+		return nil, err
+	}
+	if stored.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	if !stored.Completed() {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return stored, nil
+}
+
+// Complete stores the response of the request holding a user's key, with the
+// headers to replay along with it
+func (s *IdempotencyService) Complete(ctx context.Context, userID int, key string, statusCode int, header http.Header, body []byte) error {
+	return s.repo.Complete(ctx, &models.IdempotencyKey{
+		UserID:         userID,
+		Key:            key,
+		StatusCode:     statusCode,
+		ResponseHeader: header,
+		ResponseBody:   body,
+	})
+}
+
+// Release frees a user's key without storing a response, so the request can
+// be retried
+func (s *IdempotencyService) Release(ctx context.Context, userID int, key string) error {
+	return s.repo.Delete(ctx, userID, key)
+}
+
+// Run removes expired keys until ctx is cancelled
+func (s *IdempotencyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.repo.DeleteExpired(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			slog.Error("Idempotency key purge failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// validIdempotencyKey reports whether key is a usable Idempotency-Key value
+func validIdempotencyKey(key string) bool {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for _, c := range key {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+func TestIdempotencyReplaysResponseHeaders(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	idempotency := NewIdempotencyService(repository.NewIdempotencyRepository(db), config.IdempotencyConfig{TTL: time.Hour})
+	user := newTestUser(t, db, "anna", models.RoleUser)
+
+	if replay, err := idempotency.Begin(ctx, user.ID, "key-1", "hash"); err != nil || replay != nil {
+		t.Fatalf("Begin() = %+v, %v, want the request to be processed", replay, err)
+	}
+	if _, err := idempotency.Begin(ctx, user.ID, "key-1", "hash"); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Errorf("got error %v while the request runs, want %v", err, ErrIdempotencyKeyInProgress)
+	}
+
+	header := http.Header{
+		"Content-Type": {"application/json"},
+		"Location":     {"/api/timesheet-approvals/1"},
+	}
+	if err := idempotency.Complete(ctx, user.ID, "key-1", http.StatusCreated, header, []byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := idempotency.Begin(ctx, user.ID, "key-1", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay == nil || replay.StatusCode != http.StatusCreated || string(replay.ResponseBody) != `{"id":1}` {
+		t.Fatalf("got replay %+v, want the stored response", replay)
+	}
+	if got := replay.ResponseHeader.Get("Location"); got != "/api/timesheet-approvals/1" {
+		t.Errorf("replayed Location = %q, want the stored one", got)
+	}
+	if got := replay.ResponseHeader.Get("Content-Type"); got != "application/json" {
+		t.Errorf("replayed Content-Type = %q, want the stored one", got)
+	}
+	if _, err := idempotency.Begin(ctx, user.ID, "key-1", "other hash"); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+		t.Errorf("got error %v for a different request, want %v", err, ErrIdempotencyKeyMismatch)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/util"
+)
+
+// ErrImpersonationNotAllowed is returned when an admin would act as
+// themselves, another admin or a disabled user
+var ErrImpersonationNotAllowed = errors.New("only enabled accounts of users and supervisors can be impersonated")
+
+// ImpersonationService lets admins act as a user for support, to see exactly
+// what the user sees. Issuing a token and every request made with it are
+// written to the audit log.
+type ImpersonationService struct {
+	userRepo  *repository.UserRepository
+	auditRepo *repository.AuditRepository
+	jwtSecret string
+	ttl       time.Duration
+}
+
+// NewImpersonationService creates a new impersonation service
+func NewImpersonationService(userRepo *repository.UserRepository, auditRepo *repository.AuditRepository, jwtSecret string, ttl time.Duration) *ImpersonationService {
+	return &ImpersonationService{
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+		jwtSecret: jwtSecret,
+		ttl:       ttl,
+	}
+}
+
+// Impersonate issues a token with which an admin acts as a user until it
+// expires. The session is read-only unless the request allows writes.
+func (s *ImpersonationService) Impersonate(ctx context.Context, actorID, userID int, req *models.ImpersonationRequest) (*models.ImpersonationResponse, error) {
+	ctx, span := tracer.Start(ctx, "ImpersonationService.Impersonate")
+	defer span.End()
+
+	actor, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if actor.Role != models.RoleAdmin {
+		return nil, ErrUnauthorized
+	}
+	if actorID == userID {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Acting as another admin would hand out their rights, and a disabled
+	// user's token is rejected anyway
+	if user.Role == models.RoleAdmin || user.IsDisabled() {
+		return nil, ErrImpersonationNotAllowed
+	}
+
+	readOnly := !req.AllowWrites
+	token, expiresAt, err := util.GenerateImpersonationToken(actorID, actor.TokenVersion, userID, user.TokenVersion, readOnly, s.ttl, s.jwtSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	mode := "read-only"
+	if !readOnly {
+		mode = "read-write"
+	}
+	details := fmt.Sprintf("%s until %s", mode, expiresAt.UTC().Format(time.RFC3339))
+	if req.Reason != "" {
+		details += ": " + req.Reason
+	}
+	if err := s.auditRepo.Create(ctx, &models.AuditEntry{
+		UserID:  &userID,
+		ActorID: &actorID,
+		Action:  models.AuditImpersonation,
+		Details: details,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &models.ImpersonationResponse{
+		Token: token,
+		User:  *user,
+		Impersonation: models.Impersonation{
+			ActorID:   actorID,
+			UserID:    userID,
+			ReadOnly:  readOnly,
+			ExpiresAt: expiresAt.UTC(),
+		},
+	}, nil
+}
+
+// AuditRequest records a request an admin made as a user, with its outcome
+func (s *ImpersonationService) AuditRequest(ctx context.Context, actorID, userID int, method, path string, status int) error {
+	return s.auditRepo.Create(ctx, &models.AuditEntry{
+		UserID:  &userID,
+		ActorID: &actorID,
+		Action:  models.AuditImpersonatedCall,
+		Details: fmt.Sprintf("%s %s %d", method, path, status),
+	})
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+var (
+	// ErrSchemaDirty is returned when a migration run by another tool failed
+	// part way; the schema has to be repaired and its version forced
+	ErrSchemaDirty = errors.New("schema is dirty, a migration failed part way")
+	// ErrUnknownSchemaVersion is returned when the database records a schema
+	// version that has no migration
+	ErrUnknownSchemaVersion = errors.New("schema version has no migration")
+	// ErrNoDownMigration is returned when a migration cannot be reverted
+	ErrNoDownMigration = errors.New("migration has no down script")
+)
+
+// MigrationService applies and reverts the migrations in migrations/
+type MigrationService struct {
+	schemaRepo *repository.SchemaRepository
+	migrations []database.Migration
+}
+
+// NewMigrationService creates a new migration service for migrations, which
+// are ordered by version
+func NewMigrationService(schemaRepo *repository.SchemaRepository, migrations []database.Migration) *MigrationService {
+	return &MigrationService{
+		schemaRepo: schemaRepo,
+		migrations: migrations,
+	}
+}
+
+// Migrations returns the known migrations, ordered by version
+func (s *MigrationService) Migrations() []database.Migration {
+	return s.migrations
+}
+
+// Version returns the version of the newest applied migration, 0 when none
+// is, and whether a migration failed part way
+func (s *MigrationService) Version(ctx context.Context) (int, bool, error) {
+	version, dirty, err := s.schemaRepo.Version(ctx)
+	if errors.Is(err, repository.ErrSchemaVersionUnknown) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+// Up applies the pending migrations in order and returns them. Each one is
+// applied in its own transaction; on failure the migrations before it stay
+// applied.
+func (s *MigrationService) Up(ctx context.Context) ([]database.Migration, error) {
+	current, err := s.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []database.Migration
+	for _, migration := range s.migrations[current+1:] {
+		script, err := os.ReadFile(migration.Up)
+		if err != nil {
+			return applied, fmt.Errorf("failed to read migration %s: %w", migration.String(), err)
+		}
+		if err := s.schemaRepo.Migrate(ctx, string(script), migration.Version); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", migration.String(), err)
+		}
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the newest steps applied migrations, newest first, and
+// returns them
+func (s *MigrationService) Down(ctx context.Context, steps int) ([]database.Migration, error) {
+	current, err := s.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []database.Migration
+	for i := current; i >= 0 && len(reverted) < steps; i-- {
+		migration := s.migrations[i]
+		if migration.Down == "" {
+			return reverted, fmt.Errorf("migration %s: %w", migration.String(), ErrNoDownMigration)
+		}
+		script, err := os.ReadFile(migration.Down)
+		if err != nil {
+			return reverted, fmt.Errorf("failed to read migration %s: %w", migration.String(), err)
+		}
+
+		previous := 0
+		if i > 0 {
+			previous = s.migrations[i-1].Version
+		}
+		if err := s.schemaRepo.Migrate(ctx, string(script), previous); err != nil {
+			return reverted, fmt.Errorf("migration %s: %w", migration.String(), err)
+		}
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
+
+// Force records version as the schema version without running migrations,
+// for a database whose schema was created or repaired by hand
+func (s *MigrationService) Force(ctx context.Context, version int) error {
+	if version != 0 && s.find(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownSchemaVersion, version)
+	}
+
+	return s.schemaRepo.ForceVersion(ctx, version)
+}
+
+// index returns the position of the applied schema version in the
+// migrations, -1 when no migration is applied
+func (s *MigrationService) index(ctx context.Context) (int, error) {
+	version, dirty, err := s.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d", ErrSchemaDirty, version)
+	}
+	if version == 0 {
+		return -1, nil
+	}
+
+	i := s.find(version)
+	if i < 0 {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownSchemaVersion, version)
+	}
+
+	return i, nil
+}
+
+// find returns the position of the migration with version, or -1
+func (s *MigrationService) find(version int) int {
+	for i := range s.migrations {
+		if s.migrations[i].Version == version {
+			return i
+		}
+	}
+
+	return -1
+}
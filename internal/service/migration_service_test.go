@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+func TestMigrateRebuildsSerialIDs(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "worktrack.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// One connection, so the foreign key enforcement seen below is that of
+	// the connection the migrations ran on
+	db.SetMaxOpenConns(1)
+
+	migrations, err := database.LoadMigrations(filepath.Join("..", "..", "migrations"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Migrate to the version before the rebuild
+	const rebuild = 19
+	schemaRepo := repository.NewSchemaRepository(db)
+	if _, err := NewMigrationService(schemaRepo, migrations[:rebuild-1]).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows written before the rebuild: SERIAL ids are NULL, and were reported
+	// and referenced by their rowid
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exec("PRAGMA foreign_keys = OFF")
+	exec("INSERT INTO users (first_name, last_name, login, password_hash) VALUES ('Anna', 'A', 'anna', 'x')")
+	exec("INSERT INTO users (id, first_name, last_name, login, password_hash) VALUES (7, 'Bob', 'B', 'bob', 'x')")
+	exec("INSERT INTO track_items (user_id, type, date) VALUES (1, 'regular', '2024-03-01 08:00:00+00:00')")
+	exec("INSERT INTO shift_templates (user_id, name, type, anchor_date) VALUES (7, 'Early', 'regular', '2024-03-01')")
+	exec("PRAGMA foreign_keys = ON")
+
+	migration := NewMigrationService(schemaRepo, migrations)
+	if _, err := migration.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	rows, err := db.QueryContext(ctx, "SELECT id FROM users ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 7 {
+		t.Errorf("got user ids %v, want [1 7]", ids)
+	}
+	var items, templates int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM track_items WHERE id = 1 AND user_id = 1").Scan(&items); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM shift_templates WHERE user_id = 7").Scan(&templates); err != nil {
+		t.Fatal(err)
+	}
+	if items != 1 || templates != 1 {
+		t.Errorf("got %d track items and %d shift templates after the rebuild, want both kept", items, templates)
+	}
+	for _, table := range []string{"users", "track_items"} {
+		var schema string
+		if err := db.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&schema); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(schema, "id INTEGER PRIMARY KEY AUTOINCREMENT") {
+			t.Errorf("%s was not rebuilt with rowid ids: %s", table, schema)
+		}
+	}
+
+	// New rows get ids, and foreign keys are enforced again
+	user := newTestUser(t, db, "carol", models.RoleUser)
+	if user.ID != 8 {
+		t.Errorf("got id %d for a new user, want 8", user.ID)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO track_items (user_id, type, date) VALUES (99, 'regular', '2024-03-01')"); err == nil {
+		t.Error("inserted a track item of a missing user after migrating")
+	}
+
+	// A migration leaving rows that reference missing rows is rolled back
+	err = schemaRepo.Migrate(ctx, "DELETE FROM users WHERE id = 7", 99)
+	if !errors.Is(err, repository.ErrForeignKeyViolation) {
+		t.Errorf("got error %v, want ErrForeignKeyViolation", err)
+	}
+	if version, _, err := migration.Version(ctx); err != nil || version != migrations[len(migrations)-1].Version {
+		t.Errorf("got version %d, %v after the failed migration, want %d", version, err, migrations[len(migrations)-1].Version)
+	}
+
+	// The rebuild can be reverted and applied again
+	if _, err := migration.Down(ctx, len(migrations)-rebuild+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migration.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var users int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&users); err != nil {
+		t.Fatal(err)
+	}
+	if users != 3 {
+		t.Errorf("got %d users after reverting and reapplying the rebuild, want 3", users)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrence is a parsed recurrence rule. It supports a subset of RFC 5545 RRULE:
+//
+//	FREQ=DAILY;INTERVAL=3            every third day
+//	FREQ=WEEKLY;BYDAY=MO,WE,FR       given weekdays, optionally every INTERVAL weeks
+//	FREQ=DAILY;X-ON=2;X-OFF=2        rotation: 2 days on, 2 days off
+//
+// UNTIL=YYYYMMDD may be added to any rule. Days are counted from the anchor
+// date, so the same rule produces the same days whatever range it is applied to.
+type recurrence struct {
+	freq     string
+	interval int
+	byDay    map[time.Weekday]bool
+	on, off  int
+	until    *time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// parseRecurrence parses a recurrence rule, with or without the "RRULE:" prefix
+func parseRecurrence(rule string) (*recurrence, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return nil, errors.New("recurrence is required")
+	}
+
+	rc := &recurrence{interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid recurrence part %q", part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rc.freq = strings.ToUpper(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, errors.New("recurrence INTERVAL must be a positive integer")
+			}
+			rc.interval = n
+		case "BYDAY":
+			rc.byDay = make(map[time.Weekday]bool)
+			for _, day := range strings.Split(strings.ToUpper(value), ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("invalid recurrence weekday %q", day)
+				}
+				rc.byDay[weekday] = true
+			}
+		case "X-ON", "X-OFF":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("recurrence %s must be a positive integer", strings.ToUpper(name))
+			}
+			if strings.ToUpper(name) == "X-ON" {
+				rc.on = n
+			} else {
+				rc.off = n
+			}
+		case "UNTIL":
+			until, err := time.Parse("20060102", value[:min(len(value), 8)])
+			if err != nil {
+				return nil, errors.New("recurrence UNTIL must be a date in YYYYMMDD format")
+			}
+			rc.until = &until
+		default:
+			return nil, fmt.Errorf("unsupported recurrence part %q", name)
+		}
+	}
+
+	switch rc.freq {
+	case "DAILY":
+		if rc.byDay != nil {
+			return nil, errors.New("recurrence BYDAY is only supported with FREQ=WEEKLY")
+		}
+		if (rc.on > 0) != (rc.off > 0) {
+			return nil, errors.New("recurrence X-ON and X-OFF must be used together")
+		}
+		if rc.on > 0 && rc.interval > 1 {
+			return nil, errors.New("recurrence INTERVAL cannot be combined with X-ON and X-OFF")
+		}
+	case "WEEKLY":
+		if len(rc.byDay) == 0 {
+			return nil, errors.New("recurrence BYDAY is required with FREQ=WEEKLY")
+		}
+		if rc.on > 0 || rc.off > 0 {
+			return nil, errors.New("recurrence X-ON and X-OFF are only supported with FREQ=DAILY")
+		}
+	case "":
+		return nil, errors.New("recurrence FREQ is required")
+	default:
+		return nil, fmt.Errorf("unsupported recurrence FREQ %q, use DAILY or WEEKLY", rc.freq)
+	}
+
+	return rc, nil
+}
+
+// occurs reports whether the rule includes day. anchor and day must both be
+// midnight dates in the same location.
+func (rc *recurrence) occurs(anchor, day time.Time) bool {
+	if day.Before(anchor) {
+		return false
+	}
+	if rc.until != nil && daysBetween(*rc.until, day) > 0 {
+		return false
+	}
+
+	days := daysBetween(anchor, day)
+
+	switch {
+	case rc.freq == "WEEKLY":
+		if !rc.byDay[day.Weekday()] {
+			return false
+		}
+		// Weeks start on Monday (WKST=MO)
+		weeks := daysBetween(startOfWeek(anchor), startOfWeek(day)) / 7
+		return weeks%rc.interval == 0
+	case rc.on > 0:
+		return days%(rc.on+rc.off) < rc.on
+	default:
+		return days%rc.interval == 0
+	}
+}
+
+// daysBetween returns the number of calendar days from a to b
+func daysBetween(a, b time.Time) int {
+	a = time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	b = time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// startOfWeek returns the Monday of the week containing day
+func startOfWeek(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
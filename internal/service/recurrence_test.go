@@ -0,0 +1,203 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule    string
+		wantErr string // Part of the error, empty for a valid rule
+	}{
+		{rule: "FREQ=DAILY"},
+		{rule: "RRULE:FREQ=DAILY;INTERVAL=3"},
+		{rule: " freq=weekly;byday=mo,we,fr "},
+		{rule: "FREQ=WEEKLY;BYDAY=SA,SU;INTERVAL=2;UNTIL=20241231"},
+		{rule: "FREQ=DAILY;X-ON=2;X-OFF=2;UNTIL=20241231T235959Z"},
+		{rule: "", wantErr: "required"},
+		{rule: "RRULE:", wantErr: "required"},
+		{rule: "INTERVAL=2", wantErr: "FREQ is required"},
+		{rule: "FREQ=MONTHLY", wantErr: "unsupported recurrence FREQ"},
+		{rule: "FREQ=DAILY;COUNT=3", wantErr: "unsupported recurrence part"},
+		{rule: "FREQ=DAILY;INTERVAL", wantErr: "invalid recurrence part"},
+		{rule: "FREQ=DAILY;INTERVAL=", wantErr: "invalid recurrence part"},
+		{rule: "FREQ=DAILY;INTERVAL=0", wantErr: "INTERVAL must be a positive integer"},
+		{rule: "FREQ=DAILY;INTERVAL=two", wantErr: "INTERVAL must be a positive integer"},
+		{rule: "FREQ=DAILY;BYDAY=MO", wantErr: "BYDAY is only supported with FREQ=WEEKLY"},
+		{rule: "FREQ=WEEKLY", wantErr: "BYDAY is required"},
+		{rule: "FREQ=WEEKLY;BYDAY=MO,XX", wantErr: "invalid recurrence weekday"},
+		{rule: "FREQ=WEEKLY;BYDAY=MO;X-ON=1;X-OFF=1", wantErr: "only supported with FREQ=DAILY"},
+		{rule: "FREQ=DAILY;X-ON=2", wantErr: "must be used together"},
+		{rule: "FREQ=DAILY;X-ON=2;X-OFF=-1", wantErr: "X-OFF must be a positive integer"},
+		{rule: "FREQ=DAILY;X-ON=2;X-OFF=2;INTERVAL=2", wantErr: "cannot be combined"},
+		{rule: "FREQ=DAILY;UNTIL=2024-12-31", wantErr: "UNTIL must be a date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			_, err := parseRecurrence(tt.rule)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("got error %v, want the rule parsed", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("got error %v, want one about %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecurrenceOccurs(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		zone     string
+		anchor   string
+		from, to string
+		want     []string
+	}{
+		{
+			name:   "every day",
+			rule:   "FREQ=DAILY",
+			anchor: "2024-03-01",
+			from:   "2024-03-01", to: "2024-03-04",
+			want: []string{"2024-03-01", "2024-03-02", "2024-03-03", "2024-03-04"},
+		},
+		{
+			name:   "every third day counted from the anchor",
+			rule:   "FREQ=DAILY;INTERVAL=3",
+			anchor: "2024-02-27",
+			from:   "2024-03-01", to: "2024-03-10",
+			want: []string{"2024-03-01", "2024-03-04", "2024-03-07", "2024-03-10"},
+		},
+		{
+			name:   "anchor after the start date",
+			rule:   "FREQ=DAILY;INTERVAL=2",
+			anchor: "2024-03-05",
+			from:   "2024-03-01", to: "2024-03-10",
+			want: []string{"2024-03-05", "2024-03-07", "2024-03-09"},
+		},
+		{
+			name:   "anchor after the end date",
+			rule:   "FREQ=DAILY",
+			anchor: "2024-04-01",
+			from:   "2024-03-01", to: "2024-03-31",
+		},
+		{
+			name:   "weekdays",
+			rule:   "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+			anchor: "2024-01-01",
+			from:   "2024-03-04", to: "2024-03-10",
+			want: []string{"2024-03-04", "2024-03-06", "2024-03-08"},
+		},
+		{
+			name:   "every other week counted from the week of the anchor",
+			rule:   "FREQ=WEEKLY;BYDAY=TU,SU;INTERVAL=2",
+			anchor: "2024-03-07", // Thursday; its week starts on Monday the 4th
+			from:   "2024-03-01", to: "2024-03-31",
+			want: []string{"2024-03-10", "2024-03-19", "2024-03-24"},
+		},
+		{
+			name:   "weekdays of the anchor week before the anchor",
+			rule:   "FREQ=WEEKLY;BYDAY=MO,FR",
+			anchor: "2024-03-06",
+			from:   "2024-03-04", to: "2024-03-11",
+			want: []string{"2024-03-08", "2024-03-11"},
+		},
+		{
+			name:   "rotation across a leap day",
+			rule:   "FREQ=DAILY;X-ON=2;X-OFF=3",
+			anchor: "2024-02-28",
+			from:   "2024-03-01", to: "2024-03-10",
+			want: []string{"2024-03-04", "2024-03-05", "2024-03-09", "2024-03-10"},
+		},
+		{
+			name:   "until the last day included",
+			rule:   "FREQ=DAILY;UNTIL=20240303",
+			anchor: "2024-03-01",
+			from:   "2024-03-01", to: "2024-03-10",
+			want: []string{"2024-03-01", "2024-03-02", "2024-03-03"},
+		},
+		{
+			name:   "daily across the start of daylight saving time",
+			rule:   "FREQ=DAILY;INTERVAL=2",
+			zone:   "Europe/Berlin",
+			anchor: "2024-03-29",
+			from:   "2024-03-29", to: "2024-04-04",
+			want: []string{"2024-03-29", "2024-03-31", "2024-04-02", "2024-04-04"},
+		},
+		{
+			name:   "rotation across the end of daylight saving time",
+			rule:   "FREQ=DAILY;X-ON=1;X-OFF=1",
+			zone:   "Europe/Berlin",
+			anchor: "2024-10-25",
+			from:   "2024-10-25", to: "2024-10-31",
+			want: []string{"2024-10-25", "2024-10-27", "2024-10-29", "2024-10-31"},
+		},
+		{
+			name:   "weekly across the start of daylight saving time",
+			rule:   "FREQ=WEEKLY;BYDAY=SU,MO;INTERVAL=2",
+			zone:   "America/New_York",
+			anchor: "2024-03-04",
+			from:   "2024-03-01", to: "2024-03-25",
+			want: []string{"2024-03-04", "2024-03-10", "2024-03-18", "2024-03-24"},
+		},
+		{
+			name:   "west of UTC across the end of daylight saving time",
+			rule:   "FREQ=DAILY;INTERVAL=3",
+			zone:   "America/Los_Angeles",
+			anchor: "2024-11-01",
+			from:   "2024-10-30", to: "2024-11-10",
+			want: []string{"2024-11-01", "2024-11-04", "2024-11-07", "2024-11-10"},
+		},
+		{
+			name:   "east of UTC until a date",
+			rule:   "FREQ=DAILY;UNTIL=20240102",
+			zone:   "Pacific/Auckland",
+			anchor: "2023-12-31",
+			from:   "2023-12-30", to: "2024-01-04",
+			want: []string{"2023-12-31", "2024-01-01", "2024-01-02"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := time.UTC
+			if tt.zone != "" {
+				var err error
+				if loc, err = time.LoadLocation(tt.zone); err != nil {
+					t.Skipf("time zone %s is not available: %v", tt.zone, err)
+				}
+			}
+			rc, err := parseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Days are walked the way ApplyTemplate walks them
+			anchor, from, to := recurrenceDate(t, tt.anchor, loc), recurrenceDate(t, tt.from, loc), recurrenceDate(t, tt.to, loc)
+			var got []string
+			for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+				if rc.occurs(anchor, day) {
+					got = append(got, day.Format("2006-01-02"))
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// recurrenceDate returns midnight of a YYYY-MM-DD date in loc
+func recurrenceDate(t *testing.T, date string, loc *time.Location) time.Time {
+	t.Helper()
+
+	day, err := parseLocalDate(date, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return day
+}
@@ -0,0 +1,752 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/metrics"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/storage"
+)
+
+var (
+	ErrReplicaNotFound = errors.New("no replica covers the requested point in time")
+	ErrReplicaCorrupt  = errors.New("the replica is incomplete or corrupt")
+	// errWALContinuity is returned when the log was restarted and frames not
+	// replicated yet may have been overwritten
+	errWALContinuity     = errors.New("write-ahead log was restarted before it was replicated")
+	errInvalidSegmentKey = errors.New("not a WAL segment key")
+)
+
+const (
+	// walCheckpointSize is the size the log grows to before the replicator
+	// checkpoints it. While the replicator pins the log, automatic
+	// checkpoints can copy frames but not restart it.
+	walCheckpointSize = 4 << 20
+
+	// The replica is a series of generations, each a copy of the database
+	// and the log written after it, stored as
+	//
+	//	replica/<generation>/key.age
+	//	replica/<generation>/snapshot.db.gz
+	//	replica/<generation>/wal/<index>-<offset>-<time>.wal.gz
+	//
+	// A generation is named after the time it started. Its segments are
+	// numbered by the restarts of the log since then and where they start in
+	// that log, and named after the time they were copied. When backups are
+	// encrypted, each generation has a key of its own, encrypted with the
+	// passphrase, and its objects have .age appended.
+	replicaKeyPrefix    = "replica/"
+	replicaTimeFormat   = "20060102T150405.000Z"
+	replicaIdentityName = "key.age"
+	replicaSnapshotName = "snapshot.db.gz"
+	replicaWALPrefix    = "wal/"
+	replicaWALExtension = ".wal.gz"
+)
+
+// walPosition is how far the log has been replicated
+type walPosition struct {
+	hdr      *database.WALHeader // Header of the log being replicated
+	index    int                 // Number of restarts of the log in the generation
+	offset   int64               // Bytes of the log replicated
+	checksum [2]uint32           // Checksum of the last frame replicated
+}
+
+// ReplicationService continuously copies the SQLite write-ahead log to the
+// backup store, so the database can be restored to any point in time
+type ReplicationService struct {
+	replicationRepo *repository.ReplicationRepository
+	dbPath          string
+	store           storage.Store // nil when there is no backup store
+	passphrase      string
+	cfg             config.ReplicationConfig
+	done            chan struct{}
+
+	// State of the generation being written, owned by Run
+	generation string
+	startedAt  time.Time
+	recipient  age.Recipient // nil when the replica is not encrypted
+	pos        walPosition
+}
+
+// NewReplicationService creates a new replication service. Replication is
+// disabled when store is nil or the interval is 0. Objects are encrypted
+// when a passphrase is given.
+func NewReplicationService(replicationRepo *repository.ReplicationRepository, dbPath string, store storage.Store, passphrase string, cfg config.ReplicationConfig) *ReplicationService {
+	return &ReplicationService{
+		replicationRepo: replicationRepo,
+		dbPath:          dbPath,
+		store:           store,
+		passphrase:      passphrase,
+		cfg:             cfg,
+		done:            make(chan struct{}),
+	}
+}
+
+// Run replicates the log every interval until ctx is cancelled, then copies
+// the last frames and lets go of the log. It does nothing when replication
+// is disabled. Failures are logged and retried on the next round.
+func (s *ReplicationService) Run(ctx context.Context) {
+	defer close(s.done)
+	if s.store == nil || s.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sync(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("WAL replication failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.stop()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait blocks until Run has returned
+func (s *ReplicationService) Wait() {
+	<-s.done
+}
+
+// stop replicates what was written since the last round and unpins the log
+func (s *ReplicationService) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if s.generation != "" {
+		if err := s.follow(ctx); err != nil {
+			slog.Error("WAL replication failed", "err", err)
+		}
+	}
+	if err := s.replicationRepo.Unpin(); err != nil {
+		slog.Error("Failed to unpin write-ahead log", "err", err)
+	}
+}
+
+// sync runs one round of replication: it starts a new generation when there
+// is none or the current one is older than the snapshot interval, otherwise
+// copies the new frames of the log and checkpoints it once it is large
+func (s *ReplicationService) sync(ctx context.Context) error {
+	if s.generation == "" || time.Since(s.startedAt) >= s.cfg.SnapshotInterval {
+		return s.startGeneration(ctx)
+	}
+
+	if err := s.follow(ctx); err != nil {
+		return err
+	}
+	if s.pos.offset < walCheckpointSize {
+		return nil
+	}
+
+	if err := s.checkpoint(ctx); err != nil {
+		return err
+	}
+	return s.follow(ctx)
+}
+
+// follow copies the frames written since the last round, following the log
+// when it was restarted. It starts a new generation when the log cannot be
+// followed.
+func (s *ReplicationService) follow(ctx context.Context) error {
+	hdr, err := database.ReadWALHeader(s.walPath())
+	if errors.Is(err, database.ErrNoWAL) {
+		// The header is written with the first frame
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !hdr.SameLog(s.pos.hdr) {
+		err = s.followRestart(ctx)
+		if errors.Is(err, errWALContinuity) {
+			slog.Warn("The write-ahead log was restarted before it was replicated, starting a new generation")
+			return s.startGeneration(ctx)
+		}
+		return err
+	}
+
+	frames, end, checksum, err := database.ReadWALFrames(s.walPath(), s.pos.hdr, s.pos.offset, s.pos.checksum)
+	if err != nil || len(frames) == 0 {
+		return err
+	}
+	if err := s.putSegment(ctx, s.pos.index, s.pos.offset, time.Now(), frames); err != nil {
+		return err
+	}
+	s.pos.offset, s.pos.checksum = end, checksum
+
+	metrics.ReplicationLastSync.SetToCurrentTime()
+	return nil
+}
+
+// followRestart continues replication in the restarted log. A restart
+// writes the new log from the start of the file, so the frames of the old
+// one that were not copied yet are still at the end of the file unless the
+// new log has grown over them; writers are locked out while both are read.
+func (s *ReplicationService) followRestart(ctx context.Context) error {
+	lock, err := s.replicationRepo.LockWrites(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	hdr, err := database.ReadWALHeader(s.walPath())
+	if err != nil {
+		return err
+	}
+	if hdr.Salt1 != s.pos.hdr.Salt1+1 {
+		return errWALContinuity
+	}
+
+	frames, end, checksum, err := database.ReadWALFrames(s.walPath(), hdr, 0, [2]uint32{})
+	if err != nil {
+		return err
+	}
+	if end > s.pos.offset {
+		return errWALContinuity
+	}
+	tail, _, _, err := database.ReadWALFrames(s.walPath(), s.pos.hdr, s.pos.offset, s.pos.checksum)
+	if err != nil {
+		return err
+	}
+
+	// Pin the new log before writers can restart it again
+	if err := s.replicationRepo.Pin(ctx); err != nil {
+		return err
+	}
+	lock.Release()
+
+	now := time.Now()
+	if len(tail) > 0 {
+		if err := s.putSegment(ctx, s.pos.index, s.pos.offset, now, tail); err != nil {
+			return err
+		}
+	}
+	s.pos = walPosition{hdr: hdr, index: s.pos.index + 1}
+	if len(frames) > 0 {
+		if err := s.putSegment(ctx, s.pos.index, 0, now, frames); err != nil {
+			return err
+		}
+		s.pos.offset, s.pos.checksum = end, checksum
+	}
+
+	metrics.ReplicationLastSync.SetToCurrentTime()
+	return nil
+}
+
+// checkpoint copies the replicated log into the database file and writes to
+// it, which restarts the log when every frame was copied. The restart is
+// followed by the next call to follow.
+func (s *ReplicationService) checkpoint(ctx context.Context) error {
+	if err := s.replicationRepo.Unpin(); err != nil {
+		return err
+	}
+	if _, _, err := s.replicationRepo.Checkpoint(ctx, repository.CheckpointPassive); err != nil {
+		return err
+	}
+	if err := s.replicationRepo.Bump(ctx); err != nil {
+		return err
+	}
+
+	return s.replicationRepo.Pin(ctx)
+}
+
+// startGeneration copies the database and the whole log while writers are
+// locked out, and stores the copy as the start of a new generation
+func (s *ReplicationService) startGeneration(ctx context.Context) error {
+	s.generation = ""
+
+	// Make sure the log has a header, so the generation has a log to follow
+	if err := s.replicationRepo.Bump(ctx); err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "worktrack-replica-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	snapshot := filepath.Join(tmpDir, "snapshot.db")
+
+	lock, err := s.replicationRepo.LockWrites(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	hdr, err := database.ReadWALHeader(s.walPath())
+	if err != nil {
+		return err
+	}
+	frames, end, checksum, err := database.ReadWALFrames(s.walPath(), hdr, 0, [2]uint32{})
+	if err != nil {
+		return err
+	}
+	if err := s.replicationRepo.CopyPages(ctx, snapshot); err != nil {
+		return err
+	}
+	if err := s.replicationRepo.Pin(ctx); err != nil {
+		return err
+	}
+	lock.Release()
+
+	startedAt := time.Now().UTC()
+	generation := startedAt.Format(replicaTimeFormat)
+	if err := s.newGenerationKey(ctx, generation); err != nil {
+		return err
+	}
+
+	f, err := os.Open(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+	if err := s.put(ctx, replicaKeyPrefix+generation+"/"+replicaSnapshotName, f); err != nil {
+		return err
+	}
+
+	s.generation, s.startedAt = generation, startedAt
+	s.pos = walPosition{hdr: hdr}
+	if len(frames) > 0 {
+		if err := s.putSegment(ctx, 0, 0, startedAt, frames); err != nil {
+			s.generation = ""
+			return err
+		}
+		s.pos.offset, s.pos.checksum = end, checksum
+	}
+	slog.Info("Started replica generation", "generation", generation, "store", s.store.String())
+	metrics.ReplicationLastSync.SetToCurrentTime()
+
+	// The new generation is stored; a failed cleanup is retried after the next one
+	if err := s.prune(ctx); err != nil {
+		slog.Error("Failed to delete old replica generations", "err", err)
+	}
+
+	return nil
+}
+
+// newGenerationKey creates the key the objects of a generation are encrypted
+// with and stores it encrypted with the passphrase. Without a passphrase
+// the generation is not encrypted.
+func (s *ReplicationService) newGenerationKey(ctx context.Context, generation string) error {
+	s.recipient = nil
+	if s.passphrase == "" {
+		return nil
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return fmt.Errorf("failed to generate replica key: %w", err)
+	}
+	recipient, err := age.NewScryptRecipient(s.passphrase)
+	if err != nil {
+		return fmt.Errorf("invalid backup passphrase: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt replica key: %w", err)
+	}
+	if _, err := io.WriteString(w, identity.String()); err != nil {
+		return fmt.Errorf("failed to encrypt replica key: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to encrypt replica key: %w", err)
+	}
+	if err := s.store.Put(ctx, replicaKeyPrefix+generation+"/"+replicaIdentityName, &buf, int64(buf.Len())); err != nil {
+		return err
+	}
+
+	s.recipient = identity.Recipient()
+	return nil
+}
+
+// putSegment stores frames of the log as a segment of the current generation
+func (s *ReplicationService) putSegment(ctx context.Context, index int, offset int64, copiedAt time.Time, frames []byte) error {
+	return s.put(ctx, segmentKey(s.generation, index, offset, copiedAt), bytes.NewReader(frames))
+}
+
+// put compresses and, when the generation is encrypted, encrypts what r
+// holds and stores it under key
+func (s *ReplicationService) put(ctx context.Context, key string, r io.Reader) error {
+	if s.recipient != nil {
+		key += backupEncryptedSuffix
+	}
+
+	var buf bytes.Buffer
+	w, err := newArchiveWriter(&buf, s.recipient)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("failed to compress %s: %w", key, err)
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return s.store.Put(ctx, key, &buf, int64(buf.Len()))
+}
+
+// prune deletes the generations that are not needed to restore any point in
+// time within the retention period. The current generation is always kept.
+func (s *ReplicationService) prune(ctx context.Context) error {
+	generations, err := listGenerations(ctx, s.store)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-s.cfg.Retention)
+	for i := 0; i+1 < len(generations); i++ {
+		// A generation is needed until the one after it started
+		if generations[i+1].startedAt.After(cutoff) {
+			break
+		}
+		objects, err := s.store.List(ctx, replicaKeyPrefix+generations[i].name+"/")
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			if err := s.store.Delete(ctx, obj.Key); err != nil {
+				return err
+			}
+		}
+		slog.Info("Deleted old replica generation", "generation", generations[i].name)
+	}
+
+	return nil
+}
+
+func (s *ReplicationService) walPath() string {
+	return database.WALPath(s.dbPath)
+}
+
+// RestoreReplica restores the database file at dbPath, which must not
+// exist, from the replica in store as it was at the given time, or as
+// recent as the replica goes when at is zero. Changes are restored to
+// within the replication interval. It returns the time of the newest
+// change restored. The restored copy is integrity checked before it is
+// moved into place.
+func RestoreReplica(ctx context.Context, store storage.Store, passphrase, dbPath string, at time.Time) (time.Time, error) {
+	generations, err := listGenerations(ctx, store)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var gen *replicaGeneration
+	for i := len(generations) - 1; i >= 0; i-- {
+		if at.IsZero() || !generations[i].startedAt.After(at) {
+			gen = &generations[i]
+			break
+		}
+	}
+	if gen == nil {
+		return time.Time{}, ErrReplicaNotFound
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		return time.Time{}, ErrDatabaseFileExists
+	}
+
+	var identity age.Identity
+	if gen.encrypted {
+		if identity, err = readGenerationKey(ctx, store, gen.name, passphrase); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	// Restore next to the database, so the final rename stays on one file system
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return time.Time{}, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dbPath), filepath.Base(dbPath)+".restore-")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpPath := filepath.Join(tmpDir, filepath.Base(dbPath))
+
+	if err := downloadReplicaObject(ctx, store, gen.snapshotKey, identity, tmpPath); err != nil {
+		return time.Time{}, err
+	}
+
+	restoredAt := gen.startedAt
+	segments := gen.segments
+	for len(segments) > 0 {
+		// Apply the segments of one log at a time, up to the requested time
+		n := 0
+		for n < len(segments) && segments[n].index == segments[0].index {
+			n++
+		}
+		var log []walSegment
+		for _, seg := range segments[:n] {
+			if !at.IsZero() && seg.copiedAt.After(at) {
+				break
+			}
+			log = append(log, seg)
+		}
+		if len(log) == 0 {
+			break
+		}
+		if err := applyWALSegments(ctx, store, identity, log, tmpPath); err != nil {
+			return time.Time{}, err
+		}
+		restoredAt = log[len(log)-1].copiedAt
+		if len(log) < n {
+			break
+		}
+		segments = segments[n:]
+	}
+
+	if err := checkDatabaseFile(ctx, tmpPath); err != nil {
+		return time.Time{}, err
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		return time.Time{}, fmt.Errorf("failed to move restored database into place: %w", err)
+	}
+
+	return restoredAt, nil
+}
+
+// applyWALSegments writes the segments of one log next to the database at
+// dbPath and checkpoints them into it
+func applyWALSegments(ctx context.Context, store storage.Store, identity age.Identity, segments []walSegment, dbPath string) error {
+	var log bytes.Buffer
+	for _, seg := range segments {
+		if seg.offset != int64(log.Len()) {
+			return fmt.Errorf("%w: segment %s does not follow the one before it", ErrReplicaCorrupt, seg.key)
+		}
+		if err := readReplicaObject(ctx, store, seg.key, identity, &log); err != nil {
+			return err
+		}
+	}
+
+	hdr, err := database.ParseWALHeader(log.Bytes())
+	if err != nil {
+		return fmt.Errorf("%w: log %d has no valid header", ErrReplicaCorrupt, segments[0].index)
+	}
+	frames := (int64(log.Len()) - database.WALHeaderSize) / hdr.FrameSize()
+
+	if err := os.WriteFile(database.WALPath(dbPath), log.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write log: %w", err)
+	}
+	os.Remove(dbPath + "-shm")
+
+	db, err := database.NewSQLiteDB(dbPath)
+	if err != nil {
+		return fmt.Errorf("restored database cannot be opened: %w", err)
+	}
+	defer database.Close(db)
+
+	// SQLite only takes the frames of a log whose checksums match
+	logFrames, complete, err := repository.NewReplicationRepository(db).Checkpoint(ctx, repository.CheckpointPassive)
+	if err != nil {
+		return err
+	}
+	if !complete || int64(logFrames) != frames {
+		return fmt.Errorf("%w: log %d could not be applied", ErrReplicaCorrupt, segments[0].index)
+	}
+
+	// Closing the last connection removes the log
+	return db.Close()
+}
+
+// replicaGeneration is a generation found in the backup store
+type replicaGeneration struct {
+	name        string
+	startedAt   time.Time
+	encrypted   bool
+	snapshotKey string
+	segments    []walSegment // Sorted by log and offset
+}
+
+// walSegment is a stored segment of a log
+type walSegment struct {
+	key      string
+	index    int
+	offset   int64
+	copiedAt time.Time
+}
+
+// listGenerations returns the generations in store that have a snapshot,
+// oldest first
+func listGenerations(ctx context.Context, store storage.Store) ([]replicaGeneration, error) {
+	objects, err := store.List(ctx, replicaKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*replicaGeneration)
+	for _, obj := range objects {
+		name, rest, ok := strings.Cut(strings.TrimPrefix(obj.Key, replicaKeyPrefix), "/")
+		if !ok {
+			continue
+		}
+		startedAt, err := time.Parse(replicaTimeFormat, name)
+		if err != nil {
+			continue
+		}
+		gen := byName[name]
+		if gen == nil {
+			gen = &replicaGeneration{name: name, startedAt: startedAt}
+			byName[name] = gen
+		}
+
+		switch {
+		case rest == replicaIdentityName:
+			gen.encrypted = true
+		case strings.TrimSuffix(rest, backupEncryptedSuffix) == replicaSnapshotName:
+			gen.snapshotKey = obj.Key
+		default:
+			if seg, err := parseSegmentKey(obj.Key, rest); err == nil {
+				gen.segments = append(gen.segments, seg)
+			}
+		}
+	}
+
+	generations := make([]replicaGeneration, 0, len(byName))
+	for _, gen := range byName {
+		if gen.snapshotKey == "" {
+			continue
+		}
+		sort.Slice(gen.segments, func(i, j int) bool {
+			a, b := gen.segments[i], gen.segments[j]
+			if a.index != b.index {
+				return a.index < b.index
+			}
+			return a.offset < b.offset
+		})
+		generations = append(generations, *gen)
+	}
+	sort.Slice(generations, func(i, j int) bool { return generations[i].startedAt.Before(generations[j].startedAt) })
+
+	return generations, nil
+}
+
+// segmentKey returns the storage key of a segment of a generation's log
+func segmentKey(generation string, index int, offset int64, copiedAt time.Time) string {
+	return fmt.Sprintf("%s%s/%s%08d-%016d-%s%s", replicaKeyPrefix, generation, replicaWALPrefix,
+		index, offset, copiedAt.UTC().Format(replicaTimeFormat), replicaWALExtension)
+}
+
+// parseSegmentKey parses the key of a segment; rest is the key without the
+// generation prefix
+func parseSegmentKey(key, rest string) (walSegment, error) {
+	name, ok := strings.CutPrefix(rest, replicaWALPrefix)
+	if !ok {
+		return walSegment{}, errInvalidSegmentKey
+	}
+	name = strings.TrimSuffix(name, backupEncryptedSuffix)
+	name, ok = strings.CutSuffix(name, replicaWALExtension)
+	if !ok {
+		return walSegment{}, errInvalidSegmentKey
+	}
+
+	parts := strings.Split(name, "-")
+	if len(parts) != 3 {
+		return walSegment{}, errInvalidSegmentKey
+	}
+	index, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return walSegment{}, errInvalidSegmentKey
+	}
+	offset, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return walSegment{}, errInvalidSegmentKey
+	}
+	copiedAt, err := time.Parse(replicaTimeFormat, parts[2])
+	if err != nil {
+		return walSegment{}, errInvalidSegmentKey
+	}
+
+	return walSegment{key: key, index: index, offset: offset, copiedAt: copiedAt}, nil
+}
+
+// readGenerationKey downloads the key of an encrypted generation and
+// decrypts it with the passphrase
+func readGenerationKey(ctx context.Context, store storage.Store, generation, passphrase string) (age.Identity, error) {
+	if passphrase == "" {
+		return nil, ErrBackupPassphrase
+	}
+	scrypt, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, ErrBackupPassphrase
+	}
+
+	obj, err := store.Get(ctx, replicaKeyPrefix+generation+"/"+replicaIdentityName)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	r, err := age.Decrypt(obj, scrypt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackupPassphrase, err)
+	}
+	key, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replica key: %w", err)
+	}
+	identity, err := age.ParseX25519Identity(string(key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid key: %v", ErrReplicaCorrupt, err)
+	}
+
+	return identity, nil
+}
+
+// downloadReplicaObject decrypts and decompresses the object stored under
+// key into a new file at path
+func downloadReplicaObject(ctx context.Context, store storage.Store, key string, identity age.Identity, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create restore file: %w", err)
+	}
+	defer f.Close()
+
+	if err := readReplicaObject(ctx, store, key, identity, f); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// readReplicaObject decrypts and decompresses the object stored under key
+// into w
+func readReplicaObject(ctx context.Context, store storage.Store, key string, identity age.Identity, w io.Writer) error {
+	obj, err := store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	r, err := openArchive(obj, identity)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", key, err)
+	}
+
+	return r.Close()
+}
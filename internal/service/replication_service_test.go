@@ -0,0 +1,306 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/storage"
+)
+
+// replicationTest is a database replicated to a store in a directory
+type replicationTest struct {
+	t      *testing.T
+	db     *sql.DB
+	dbPath string
+	store  *storage.LocalStore
+	pass   string
+}
+
+func newReplicationTest(t *testing.T, passphrase string) *replicationTest {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "worktrack.db")
+	return &replicationTest{
+		t:      t,
+		db:     newTestDBAt(t, dbPath),
+		dbPath: dbPath,
+		store:  storage.NewLocalStore(filepath.Join(dir, "replica")),
+		pass:   passphrase,
+	}
+}
+
+// start creates a replicator, as the server does when it starts, that is
+// stopped when the test ends
+func (rt *replicationTest) start() *ReplicationService {
+	s := NewReplicationService(repository.NewReplicationRepository(rt.db), rt.dbPath, rt.store, rt.pass, config.ReplicationConfig{
+		Interval:         time.Second,
+		SnapshotInterval: time.Hour,
+		Retention:        time.Hour,
+	})
+	rt.t.Cleanup(func() { s.replicationRepo.Unpin() })
+	return s
+}
+
+// sync runs a round of replication and waits, so the next round is copied
+// at a later time
+func (rt *replicationTest) sync(s *ReplicationService) {
+	rt.t.Helper()
+
+	if err := s.sync(context.Background()); err != nil {
+		rt.t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+}
+
+// now returns the time between the round before and the next one
+func (rt *replicationTest) now() time.Time {
+	at := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	return at
+}
+
+// addUser writes a user in a transaction of its own
+func (rt *replicationTest) addUser(login string) {
+	rt.t.Helper()
+	newTestUser(rt.t, rt.db, login, models.RoleUser)
+}
+
+// restore restores the replica as it was at a point in time and returns
+// the logins of its users
+func (rt *replicationTest) restore(at time.Time) ([]string, error) {
+	rt.t.Helper()
+
+	path := filepath.Join(rt.t.TempDir(), "restored.db")
+	if _, err := RestoreReplica(context.Background(), rt.store, rt.pass, path, at); err != nil {
+		if _, statErr := os.Stat(path); statErr == nil {
+			rt.t.Errorf("a failed restore left a database at %s", path)
+		}
+		return nil, err
+	}
+
+	db, err := database.NewSQLiteDB(path)
+	if err != nil {
+		rt.t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT login FROM users ORDER BY id")
+	if err != nil {
+		rt.t.Fatal(err)
+	}
+	defer rows.Close()
+	var logins []string
+	for rows.Next() {
+		var login string
+		if err := rows.Scan(&login); err != nil {
+			rt.t.Fatal(err)
+		}
+		logins = append(logins, login)
+	}
+	if err := rows.Err(); err != nil {
+		rt.t.Fatal(err)
+	}
+
+	return logins, nil
+}
+
+// checkRestore restores the replica at a point in time and compares its users
+func (rt *replicationTest) checkRestore(at time.Time, want ...string) {
+	rt.t.Helper()
+
+	got, err := rt.restore(at)
+	if err != nil {
+		rt.t.Fatalf("restore at %v: %v", at, err)
+	}
+	if !slices.Equal(got, want) {
+		rt.t.Errorf("restore at %v: got users %v, want %v", at, got, want)
+	}
+}
+
+// generations lists the generations in the store
+func (rt *replicationTest) generations() []replicaGeneration {
+	rt.t.Helper()
+
+	generations, err := listGenerations(context.Background(), rt.store)
+	if err != nil {
+		rt.t.Fatal(err)
+	}
+	return generations
+}
+
+func TestReplicationPointInTimeRestore(t *testing.T) {
+	for _, passphrase := range []string{"", "correct horse battery staple"} {
+		t.Run("encrypted="+strconv.FormatBool(passphrase != ""), func(t *testing.T) {
+			rt := newReplicationTest(t, passphrase)
+			s := rt.start()
+
+			beforeStart := rt.now()
+			rt.addUser("alice")
+			rt.sync(s)
+			rt.addUser("bob")
+			rt.sync(s)
+			afterBob := rt.now()
+			rt.addUser("carol")
+			rt.addUser("dave")
+			rt.sync(s)
+
+			if _, err := rt.restore(beforeStart); !errors.Is(err, ErrReplicaNotFound) {
+				t.Errorf("restore before the first generation: got error %v, want %v", err, ErrReplicaNotFound)
+			}
+			rt.checkRestore(afterBob, "alice", "bob")
+			rt.checkRestore(time.Time{}, "alice", "bob", "carol", "dave")
+
+			gens := rt.generations()
+			if len(gens) != 1 || gens[0].encrypted != (passphrase != "") || len(gens[0].segments) < 2 {
+				t.Fatalf("got generations %+v, want one with at least two segments", gens)
+			}
+		})
+	}
+}
+
+func TestReplicationWrongPassphrase(t *testing.T) {
+	rt := newReplicationTest(t, "correct horse battery staple")
+	s := rt.start()
+	rt.addUser("alice")
+	rt.sync(s)
+
+	rt.pass = "wrong"
+	if _, err := rt.restore(time.Time{}); !errors.Is(err, ErrBackupPassphrase) {
+		t.Errorf("got error %v, want %v", err, ErrBackupPassphrase)
+	}
+}
+
+func TestReplicationCheckpointAndRestart(t *testing.T) {
+	rt := newReplicationTest(t, "")
+	s := rt.start()
+
+	rt.addUser("alice")
+	rt.sync(s)
+	rt.addUser("bob")
+
+	// A checkpoint restarts the log; the frames written before it and the
+	// ones of the new log are both replicated
+	if err := s.checkpoint(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rt.addUser("carol")
+	rt.sync(s)
+	afterCarol := rt.now()
+
+	gens := rt.generations()
+	if len(gens) != 1 {
+		t.Fatalf("got %d generations after the checkpoint, want the log followed in one", len(gens))
+	}
+	if last := gens[0].segments[len(gens[0].segments)-1]; last.index != 1 {
+		t.Errorf("the last segment is of log %d, want 1 after the restart", last.index)
+	}
+	rt.checkRestore(time.Time{}, "alice", "bob", "carol")
+
+	// A restarted server cannot know what its log held, so it starts a new
+	// generation
+	s.stop()
+	s = rt.start()
+	rt.addUser("dave")
+	rt.sync(s)
+	rt.addUser("erin")
+	rt.sync(s)
+
+	if gens := rt.generations(); len(gens) != 2 {
+		t.Fatalf("got %d generations after the restart, want 2", len(gens))
+	}
+	rt.checkRestore(afterCarol, "alice", "bob", "carol")
+	rt.checkRestore(time.Time{}, "alice", "bob", "carol", "dave", "erin")
+}
+
+func TestReplicationCorruptReplica(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(t *testing.T, rt *replicationTest, seg walSegment)
+	}{
+		{
+			name: "frame that does not match its checksum",
+			damage: func(t *testing.T, rt *replicationTest, seg walSegment) {
+				var frames bytes.Buffer
+				if err := readReplicaObject(context.Background(), rt.store, seg.key, nil, &frames); err != nil {
+					t.Fatal(err)
+				}
+				data := frames.Bytes()
+				data[len(data)-100] ^= 0xff
+				putReplicaObject(t, rt.store, seg.key, data)
+			},
+		},
+		{
+			name: "torn frame",
+			damage: func(t *testing.T, rt *replicationTest, seg walSegment) {
+				var frames bytes.Buffer
+				if err := readReplicaObject(context.Background(), rt.store, seg.key, nil, &frames); err != nil {
+					t.Fatal(err)
+				}
+				putReplicaObject(t, rt.store, seg.key, frames.Bytes()[:frames.Len()-100])
+			},
+		},
+		{
+			name: "missing segment",
+			damage: func(t *testing.T, rt *replicationTest, seg walSegment) {
+				if err := rt.store.Delete(context.Background(), seg.key); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := newReplicationTest(t, "")
+			s := rt.start()
+			rt.addUser("alice")
+			rt.sync(s)
+			rt.addUser("bob")
+			rt.sync(s)
+			rt.addUser("carol")
+			rt.sync(s)
+
+			// Damage the segment with bob, which carol's follows
+			segments := rt.generations()[0].segments
+			if len(segments) < 3 {
+				t.Fatalf("got %d segments, want at least 3", len(segments))
+			}
+			tt.damage(t, rt, segments[len(segments)-2])
+
+			if _, err := rt.restore(time.Time{}); !errors.Is(err, ErrReplicaCorrupt) {
+				t.Errorf("got error %v, want %v", err, ErrReplicaCorrupt)
+			}
+		})
+	}
+}
+
+// putReplicaObject compresses data and stores it under key, unencrypted
+func putReplicaObject(t *testing.T, store storage.Store, key string, data []byte) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := newArchiveWriter(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(context.Background(), key, &buf, int64(buf.Len())); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/sergey/work-track-backend/internal/database"
+	"github.com/sergey/work-track-backend/internal/events"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// newTestDB opens a database in a temporary directory with all migrations
+// applied
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	return newTestDBAt(t, filepath.Join(t.TempDir(), "worktrack.db"))
+}
+
+// newTestDBAt opens the database at path with all migrations applied
+func newTestDBAt(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	db, err := database.NewSQLiteDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := database.LoadMigrations(filepath.Join("..", "..", "migrations"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewMigrationService(repository.NewSchemaRepository(db), migrations).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// newTestUser creates a user with a role and the UTC time zone
+func newTestUser(t *testing.T, db *sql.DB, login, role string) *models.User {
+	t.Helper()
+
+	user := &models.User{
+		FirstName:    "Test",
+		LastName:     login,
+		Login:        login,
+		PasswordHash: "x",
+		TimeZone:     "UTC",
+		Role:         role,
+	}
+	if err := repository.NewUserRepository(db).Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+// newTestTrackItemService creates a track item service whose events are
+// queued for webhooks
+func newTestTrackItemService(db *sql.DB, webhooks *WebhookService) *TrackItemService {
+	userRepo := repository.NewUserRepository(db)
+	eventService := NewEventService(webhooks, events.NewHub(100), userRepo)
+	return NewTrackItemService(repository.NewTrackItemRepository(db), userRepo, eventService)
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// maxApplyDays limits the range a template can be applied to in one request
+const maxApplyDays = 366
+
+// ShiftTemplateService handles shift template business logic
+type ShiftTemplateService struct {
+	templateRepo  *repository.ShiftTemplateRepository
+	trackItemRepo *repository.TrackItemRepository
+	userRepo      *repository.UserRepository
+	events        *EventService
+}
+
+// NewShiftTemplateService creates a new shift template service. Track items
+// created from templates are published as events.
+func NewShiftTemplateService(templateRepo *repository.ShiftTemplateRepository, trackItemRepo *repository.TrackItemRepository, userRepo *repository.UserRepository, events *EventService) *ShiftTemplateService {
+	return &ShiftTemplateService{
+		templateRepo:  templateRepo,
+		trackItemRepo: trackItemRepo,
+		userRepo:      userRepo,
+		events:        events,
+	}
+}
+
+// CreateTemplate creates a new shift template for a user
+func (s *ShiftTemplateService) CreateTemplate(ctx context.Context, userID int, req *models.CreateShiftTemplateRequest) (*models.ShiftTemplate, error) {
+	tmpl := &models.ShiftTemplate{
+		UserID:        userID,
+		Name:          req.Name,
+		Type:          req.Type,
+		EmergencyCall: req.EmergencyCall,
+		HolidayCall:   req.HolidayCall,
+		WorkingHours:  req.WorkingHours,
+		WorkingShifts: req.WorkingShifts,
+		StartTime:     req.StartTime,
+		Recurrence:    req.Recurrence,
+		AnchorDate:    req.AnchorDate,
+	}
+	if tmpl.StartTime == "" {
+		tmpl.StartTime = "00:00"
+	}
+	if tmpl.AnchorDate == "" {
+		loc, err := userLocation(ctx, s.userRepo, userID, "")
+		if err != nil {
+			return nil, err
+		}
+		tmpl.AnchorDate = time.Now().In(loc).Format("2006-01-02")
+	}
+
+	if err := validateShiftTemplate(tmpl); err != nil {
+		return nil, err
+	}
+
+	if err := s.templateRepo.Create(ctx, tmpl); err != nil {
+		return nil, fmt.Errorf("failed to create shift template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// GetUserTemplates retrieves all shift templates of a user
+func (s *ShiftTemplateService) GetUserTemplates(ctx context.Context, userID int) ([]models.ShiftTemplate, error) {
+	templates, err := s.templateRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shift templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// GetTemplate retrieves a specific shift template, ensuring it belongs to the user
+func (s *ShiftTemplateService) GetTemplate(ctx context.Context, userID, templateID int) (*models.ShiftTemplate, error) {
+	tmpl, err := s.templateRepo.FindByID(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify ownership
+	if tmpl.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	return tmpl, nil
+}
+
+// UpdateTemplate updates a shift template, ensuring it belongs to the user
+func (s *ShiftTemplateService) UpdateTemplate(ctx context.Context, userID, templateID int, req *models.UpdateShiftTemplateRequest) (*models.ShiftTemplate, error) {
+	tmpl, err := s.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update fields if provided
+	if req.Name != nil {
+		tmpl.Name = *req.Name
+	}
+	if req.Type != nil {
+		tmpl.Type = *req.Type
+	}
+	if req.EmergencyCall != nil {
+		tmpl.EmergencyCall = *req.EmergencyCall
+	}
+	if req.HolidayCall != nil {
+		tmpl.HolidayCall = *req.HolidayCall
+	}
+	if req.WorkingHours != nil {
+		tmpl.WorkingHours = *req.WorkingHours
+	}
+	if req.WorkingShifts != nil {
+		tmpl.WorkingShifts = *req.WorkingShifts
+	}
+	if req.StartTime != nil {
+		tmpl.StartTime = *req.StartTime
+	}
+	if req.Recurrence != nil {
+		tmpl.Recurrence = *req.Recurrence
+	}
+	if req.AnchorDate != nil {
+		tmpl.AnchorDate = *req.AnchorDate
+	}
+
+	if err := validateShiftTemplate(tmpl); err != nil {
+		return nil, err
+	}
+
+	if err := s.templateRepo.Update(ctx, tmpl); err != nil {
+		return nil, fmt.Errorf("failed to update shift template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// DeleteTemplate deletes a shift template, ensuring it belongs to the user.
+// Track items generated from the template are kept.
+func (s *ShiftTemplateService) DeleteTemplate(ctx context.Context, userID, templateID int) error {
+	if _, err := s.GetTemplate(ctx, userID, templateID); err != nil {
+		return err
+	}
+
+	if err := s.templateRepo.Delete(ctx, templateID); err != nil {
+		return fmt.Errorf("failed to delete shift template: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyTemplate generates track items from a template for every day of the range
+// matched by its recurrence rule. Days that already have an item of the template's
+// type are skipped, so applying the same template to the same range twice creates
+// nothing the second time. With DryRun set, the result is returned without storing it.
+func (s *ShiftTemplateService) ApplyTemplate(ctx context.Context, userID, templateID int, req *models.ApplyShiftTemplateRequest) (*models.ApplyShiftTemplateResponse, error) {
+	tmpl, err := s.GetTemplate(ctx, userID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := userLocation(ctx, s.userRepo, userID, req.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	v := newValidation(ErrValidation)
+	startDate, err := parseLocalDate(req.StartDate, loc)
+	if err != nil {
+		v.add("start_date", models.FieldInvalidFormat, "invalid start date format, use YYYY-MM-DD")
+	}
+	endDate, endErr := parseLocalDate(req.EndDate, loc)
+	if endErr != nil {
+		v.add("end_date", models.FieldInvalidFormat, "invalid end date format, use YYYY-MM-DD")
+	}
+	if err == nil && endErr == nil {
+		if endDate.Before(startDate) {
+			v.add("end_date", models.FieldConflict, "end date must not be before start date")
+		} else if daysBetween(startDate, endDate) >= maxApplyDays {
+			v.add("end_date", models.FieldOutOfRange, fmt.Sprintf("date range must not exceed %d days", maxApplyDays))
+		}
+	}
+
+	rule := tmpl.Recurrence
+	if req.Recurrence != "" {
+		rule = req.Recurrence
+	}
+	rc, err := parseRecurrence(rule)
+	if err != nil {
+		v.add("recurrence", models.FieldInvalidValue, err.Error())
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	anchor, _ := parseLocalDate(tmpl.AnchorDate, loc)
+	startTime, _ := time.Parse("15:04", tmpl.StartTime)
+
+	resp := &models.ApplyShiftTemplateResponse{
+		DryRun:  req.DryRun,
+		Created: []models.TrackItem{},
+		Skipped: []models.TrackItem{},
+	}
+
+	err = s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		existing, err := repo.FindByDateRange(ctx, userID, startDate, nextDay(endDate))
+		if err != nil {
+			return err
+		}
+		taken := make(map[string]bool, len(existing))
+		for _, item := range existing {
+			taken[item.Date.In(loc).Format("2006-01-02")+"|"+item.Type] = true
+		}
+
+		for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+			if !rc.occurs(anchor, day) {
+				continue
+			}
+
+			// Start time is wall-clock time in the user's zone
+			date := time.Date(day.Year(), day.Month(), day.Day(), startTime.Hour(), startTime.Minute(), 0, 0, loc)
+			item, err := newTrackItem(userID, &models.CreateTrackItemRequest{
+				Type:          tmpl.Type,
+				EmergencyCall: tmpl.EmergencyCall,
+				HolidayCall:   tmpl.HolidayCall,
+				WorkingHours:  tmpl.WorkingHours,
+				WorkingShifts: tmpl.WorkingShifts,
+				Date:          date.Format(time.RFC3339),
+			})
+			if err != nil {
+				return err
+			}
+
+			if taken[day.Format("2006-01-02")+"|"+item.Type] {
+				resp.Skipped = append(resp.Skipped, *item)
+				continue
+			}
+
+			if !req.DryRun {
+				if err := repo.Create(ctx, item); err != nil {
+					return fmt.Errorf("failed to create track item: %w", err)
+				}
+				if err := s.events.trackItemChanged(ctx, repo, models.EventTrackItemCreated, item); err != nil {
+					return err
+				}
+			}
+			resp.Created = append(resp.Created, *item)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply shift template: %w", err)
+	}
+
+	return resp, nil
+}
+
+// validateShiftTemplate checks the fields of a template before it is stored
+func validateShiftTemplate(tmpl *models.ShiftTemplate) error {
+	v := newValidation(ErrValidation)
+	if tmpl.Name == "" {
+		v.add("name", models.FieldRequired, "name is required")
+	}
+	if tmpl.Type == "" {
+		v.add("type", models.FieldRequired, "type is required")
+	}
+	if _, err := time.Parse("15:04", tmpl.StartTime); err != nil {
+		v.add("start_time", models.FieldInvalidFormat, "invalid start time format, use HH:MM")
+	}
+	if _, err := time.Parse("2006-01-02", tmpl.AnchorDate); err != nil {
+		v.add("anchor_date", models.FieldInvalidFormat, "invalid anchor date format, use YYYY-MM-DD")
+	}
+	if tmpl.Recurrence != "" {
+		if _, err := parseRecurrence(tmpl.Recurrence); err != nil {
+			v.add("recurrence", models.FieldInvalidValue, err.Error())
+		}
+	}
+
+	return v.err()
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// TimerService handles clocking in and out of shifts
+type TimerService struct {
+	timerRepo     *repository.TimerRepository
+	trackItemRepo *repository.TrackItemRepository
+	events        *EventService
+}
+
+// NewTimerService creates a new timer service. Timers starting and stopping,
+// and the track items they record, are published as events.
+func NewTimerService(timerRepo *repository.TimerRepository, trackItemRepo *repository.TrackItemRepository, events *EventService) *TimerService {
+	return &TimerService{
+		timerRepo:     timerRepo,
+		trackItemRepo: trackItemRepo,
+		events:        events,
+	}
+}
+
+// GetTimer retrieves the user's running timer
+func (s *TimerService) GetTimer(ctx context.Context, userID int) (*models.Timer, error) {
+	ctx, span := tracer.Start(ctx, "TimerService.GetTimer")
+	defer span.End()
+
+	return s.timerRepo.FindByUserID(ctx, userID)
+}
+
+// StartTimer starts a timer for the user from now
+func (s *TimerService) StartTimer(ctx context.Context, userID int, req *models.StartTimerRequest) (*models.Timer, error) {
+	ctx, span := tracer.Start(ctx, "TimerService.StartTimer")
+	defer span.End()
+
+	if req.Type == "" {
+		return nil, invalidField(ErrValidation, "type", models.FieldRequired, "type is required")
+	}
+
+	timer := &models.Timer{
+		UserID:        userID,
+		Type:          req.Type,
+		EmergencyCall: req.EmergencyCall,
+		HolidayCall:   req.HolidayCall,
+		WorkingShifts: req.WorkingShifts,
+	}
+	err := s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		if err := s.timerRepo.BoundTo(repo).Create(ctx, timer); err != nil {
+			return err
+		}
+		return s.events.timerChanged(repo, models.EventTimerStarted, timer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return timer, nil
+}
+
+// StopTimer stops the user's running timer and records the time worked, to
+// the hundredth of an hour, as a track item dated when the timer started
+func (s *TimerService) StopTimer(ctx context.Context, userID int) (*models.TrackItem, error) {
+	ctx, span := tracer.Start(ctx, "TimerService.StopTimer")
+	defer span.End()
+
+	var item *models.TrackItem
+	err := s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		timers := s.timerRepo.BoundTo(repo)
+		timer, err := timers.FindByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := timers.DeleteByUserID(ctx, userID); err != nil {
+			return err
+		}
+
+		stoppedAt := time.Now().UTC()
+		item = &models.TrackItem{
+			UserID:        userID,
+			Type:          timer.Type,
+			EmergencyCall: timer.EmergencyCall,
+			HolidayCall:   timer.HolidayCall,
+			WorkingHours:  math.Round(stoppedAt.Sub(timer.StartedAt).Hours()*100) / 100,
+			WorkingShifts: timer.WorkingShifts,
+			Date:          timer.StartedAt.UTC(),
+		}
+		if err := repo.Create(ctx, item); err != nil {
+			return fmt.Errorf("failed to create track item: %w", err)
+		}
+		if err := s.events.trackItemChanged(ctx, repo, models.EventTrackItemCreated, item); err != nil {
+			return err
+		}
+
+		timer.StoppedAt = &stoppedAt
+		timer.TrackItemID = &item.ID
+		return s.events.timerChanged(repo, models.EventTimerStopped, timer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/sergey/work-track-backend/internal/events"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+func TestTimer(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	hub := events.NewHub(100)
+	eventService := NewEventService(newTestWebhookService(db, localWebhookConfig), hub, userRepo)
+	timers := NewTimerService(repository.NewTimerRepository(db), repository.NewTrackItemRepository(db), eventService)
+	user := newTestUser(t, db, "anna", models.RoleUser)
+
+	sub, _, _, err := eventService.Subscribe(ctx, user.ID, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	if _, err := timers.StopTimer(ctx, user.ID); !errors.Is(err, repository.ErrTimerNotFound) {
+		t.Errorf("got error %v stopping no timer, want ErrTimerNotFound", err)
+	}
+	if _, err := timers.StartTimer(ctx, user.ID, &models.StartTimerRequest{}); !errors.Is(err, ErrValidation) {
+		t.Errorf("got error %v starting a timer without a type, want ErrValidation", err)
+	}
+
+	started, err := timers.StartTimer(ctx, user.ID, &models.StartTimerRequest{Type: "regular", EmergencyCall: true, WorkingShifts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := timers.StartTimer(ctx, user.ID, &models.StartTimerRequest{Type: "regular"}); !errors.Is(err, repository.ErrTimerRunning) {
+		t.Errorf("got error %v starting a second timer, want ErrTimerRunning", err)
+	}
+	running, err := timers.GetTimer(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if running.Type != "regular" || !running.EmergencyCall || !running.StartedAt.Equal(started.StartedAt) {
+		t.Errorf("got timer %+v, want %+v", running, started)
+	}
+
+	// Clock in two hours ago
+	if _, err := db.Exec("UPDATE timers SET started_at = datetime('now', '-2 hours') WHERE user_id = ?", user.ID); err != nil {
+		t.Fatal(err)
+	}
+	item, err := timers.StopTimer(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Type != "regular" || !item.EmergencyCall || item.WorkingHours != 2 || item.WorkingShifts != 1 {
+		t.Errorf("got track item %+v, want 2 hours of regular work", item)
+	}
+	if _, err := timers.GetTimer(ctx, user.ID); !errors.Is(err, repository.ErrTimerNotFound) {
+		t.Errorf("got error %v after stopping, want ErrTimerNotFound", err)
+	}
+
+	var got []string
+	var stopped models.Timer
+	for len(got) < 3 {
+		select {
+		case e := <-sub.C:
+			got = append(got, e.Type)
+			if e.Type == models.EventTimerStopped {
+				if err := json.Unmarshal(e.Data, &stopped); err != nil {
+					t.Fatal(err)
+				}
+			}
+		default:
+			t.Fatalf("got events %v, want 3", got)
+		}
+	}
+	want := []string{models.EventTimerStarted, models.EventTrackItemCreated, models.EventTimerStopped}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got events %v, want %v", got, want)
+		}
+	}
+	if stopped.StoppedAt == nil || stopped.TrackItemID == nil || *stopped.TrackItemID != item.ID {
+		t.Errorf("got stopped timer %+v, want it to name track item %d", stopped, item.ID)
+	}
+}
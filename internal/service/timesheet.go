@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// GetTimesheet lays out a user's track items day by day over an inclusive
+// YYYY-MM-DD range in the user's time zone, or in tz when it is given
+func (s *TrackItemService) GetTimesheet(ctx context.Context, userID int, startDateStr, endDateStr, tz string) (*models.Timesheet, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.GetTimesheet")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return s.buildTimesheet(ctx, user, startDateStr, endDateStr, tz)
+}
+
+// GetTeamTimesheets returns a timesheet for every user over the range,
+// leaving out disabled accounts and those scheduled for deletion. Only
+// supervisors and admins may request it. Each timesheet uses its user's time
+// zone unless tz is given.
+func (s *TrackItemService) GetTeamTimesheets(ctx context.Context, actorID int, startDateStr, endDateStr, tz string) ([]models.Timesheet, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.GetTeamTimesheets")
+	defer span.End()
+
+	actor, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if !actor.IsSupervisor() {
+		return nil, ErrUnauthorized
+	}
+
+	users, err := s.userRepo.FindActive(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	timesheets := make([]models.Timesheet, 0, len(users))
+	for i := range users {
+		timesheet, err := s.buildTimesheet(ctx, &users[i], startDateStr, endDateStr, tz)
+		if err != nil {
+			return nil, err
+		}
+		timesheets = append(timesheets, *timesheet)
+	}
+
+	return timesheets, nil
+}
+
+// buildTimesheet groups a user's track items by local day
+func (s *TrackItemService) buildTimesheet(ctx context.Context, user *models.User, startDateStr, endDateStr, tz string) (*models.Timesheet, error) {
+	if tz == "" {
+		tz = user.TimeZone
+	}
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return nil, err
+	}
+	dr, err := newDateRange(startDateStr, endDateStr, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	timesheet := &models.Timesheet{
+		User:      *user,
+		StartDate: dr.Start,
+		EndDate:   dr.LastDay(),
+		TimeZone:  loc.String(),
+	}
+	for day := dr.Start; day.Before(dr.End); day = day.AddDate(0, 0, 1) {
+		timesheet.Days = append(timesheet.Days, models.TimesheetDay{Date: day, Items: []models.TrackItem{}})
+	}
+
+	err = s.StreamTrackItems(ctx, user.ID, dr, func(item *models.TrackItem) error {
+		local := item.Date.In(loc)
+		day := &timesheet.Days[daysBetween(dr.Start, local)]
+		day.Items = append(day.Items, *item)
+		day.Add(item)
+		timesheet.Totals.Add(item)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build timesheet: %w", err)
+	}
+
+	return timesheet, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// ErrOwnTimesheet is returned when supervisors would approve their own timesheet
+var ErrOwnTimesheet = errors.New("supervisors cannot approve their own timesheet")
+
+// TimesheetApprovalService handles supervisors' approvals of monthly timesheets
+type TimesheetApprovalService struct {
+	approvalRepo  *repository.TimesheetApprovalRepository
+	trackItemRepo *repository.TrackItemRepository
+	userRepo      *repository.UserRepository
+	events        *EventService
+}
+
+// NewTimesheetApprovalService creates a new timesheet approval service.
+// Approvals are published as events.
+func NewTimesheetApprovalService(approvalRepo *repository.TimesheetApprovalRepository, trackItemRepo *repository.TrackItemRepository, userRepo *repository.UserRepository, events *EventService) *TimesheetApprovalService {
+	return &TimesheetApprovalService{
+		approvalRepo:  approvalRepo,
+		trackItemRepo: trackItemRepo,
+		userRepo:      userRepo,
+		events:        events,
+	}
+}
+
+// ApproveTimesheet signs off a user's timesheet for a month with its current
+// totals in the user's time zone. Only supervisors may approve timesheets,
+// and not their own.
+func (s *TimesheetApprovalService) ApproveTimesheet(ctx context.Context, actorID int, req *models.ApproveTimesheetRequest) (*models.TimesheetApproval, error) {
+	ctx, span := tracer.Start(ctx, "TimesheetApprovalService.ApproveTimesheet")
+	defer span.End()
+
+	actor, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if !actor.IsSupervisor() {
+		return nil, ErrUnauthorized
+	}
+
+	v := newValidation(ErrValidation)
+	if req.UserID == 0 {
+		v.add("user_id", models.FieldRequired, "user_id is required")
+	}
+	first, err := time.Parse("2006-01", req.Month)
+	switch {
+	case req.Month == "":
+		v.add("month", models.FieldRequired, "month is required")
+	case err != nil:
+		v.add("month", models.FieldInvalidFormat, "invalid month format, use YYYY-MM")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	if req.UserID == actorID {
+		return nil, ErrOwnTimesheet
+	}
+
+	user, err := s.userRepo.FindByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := loadLocation(user.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	dr, err := newDateRange(first.Format("2006-01-02"), first.AddDate(0, 1, -1).Format("2006-01-02"), loc)
+	if err != nil {
+		return nil, err
+	}
+
+	approval := &models.TimesheetApproval{
+		UserID:     user.ID,
+		Month:      first.Format("2006-01"),
+		ApprovedBy: &actorID,
+	}
+	err = s.trackItemRepo.StreamByDateRange(ctx, user.ID, dr.Start, dr.End, func(item *models.TrackItem) error {
+		approval.Totals.Add(item)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize track items: %w", err)
+	}
+
+	err = s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		if err := s.approvalRepo.BoundTo(repo).Create(ctx, approval); err != nil {
+			return err
+		}
+		return s.events.timesheetApproved(ctx, repo, approval)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+// GetApprovals returns the approvals of a user's timesheets or, with team set,
+// of all users' timesheets, which only supervisors may see. An empty month
+// returns the approvals of all months.
+func (s *TimesheetApprovalService) GetApprovals(ctx context.Context, userID int, team bool, month string) ([]models.TimesheetApproval, error) {
+	ctx, span := tracer.Start(ctx, "TimesheetApprovalService.GetApprovals")
+	defer span.End()
+
+	if month != "" {
+		if _, err := time.Parse("2006-01", month); err != nil {
+			return nil, invalidField(ErrValidation, "month", models.FieldInvalidFormat, "invalid month format, use YYYY-MM")
+		}
+	}
+
+	if !team {
+		return s.approvalRepo.Find(ctx, userID, month)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if !user.IsSupervisor() {
+		return nil, ErrUnauthorized
+	}
+
+	return s.approvalRepo.Find(ctx, 0, month)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/sergey/work-track-backend/internal/events"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+func TestApproveTimesheet(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	trackItemRepo := repository.NewTrackItemRepository(db)
+	webhooks := newTestWebhookService(db, localWebhookConfig)
+	hub := events.NewHub(100)
+	eventService := NewEventService(webhooks, hub, userRepo)
+	trackItems := NewTrackItemService(trackItemRepo, userRepo, eventService)
+	approvals := NewTimesheetApprovalService(repository.NewTimesheetApprovalRepository(db), trackItemRepo, userRepo, eventService)
+	rcv := newWebhookReceiver(t)
+
+	supervisor := newTestUser(t, db, "sam", models.RoleSupervisor)
+	anna := newTestUser(t, db, "anna", models.RoleUser)
+
+	sub, _, _ := hub.Subscribe("", func(e events.Event) bool { return e.Type == models.EventTimesheetApproved })
+	defer sub.Close()
+	if _, err := webhooks.CreateWebhook(ctx, anna.ID, &models.CreateWebhookRequest{
+		URL:    rcv.URL,
+		Events: []string{models.EventTimesheetApproved},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []models.CreateTrackItemRequest{
+		{Type: "regular", WorkingHours: 8, WorkingShifts: 1, Date: "2024-03-01T09:00:00Z"},
+		{Type: "on-call", WorkingHours: 3.5, WorkingShifts: 0.5, EmergencyCall: true, Date: "2024-03-31T22:00:00Z"},
+		// Outside the month
+		{Type: "regular", WorkingHours: 8, WorkingShifts: 1, Date: "2024-04-01T00:00:00Z"},
+	} {
+		if _, err := trackItems.CreateTrackItem(ctx, anna.ID, &req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Creating track items is not subscribed to
+	if n, err := webhooks.DeliverDue(ctx); err != nil || n != 0 {
+		t.Fatalf("DeliverDue() = %d, %v, want nothing to deliver", n, err)
+	}
+
+	approval, err := approvals.ApproveTimesheet(ctx, supervisor.ID, &models.ApproveTimesheetRequest{UserID: anna.ID, Month: "2024-03"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.TrackItemSummary{Items: 2, WorkingHours: 11.5, WorkingShifts: 1.5, EmergencyCalls: 1}
+	if approval.Totals != want || approval.ApprovedBy == nil || *approval.ApprovedBy != supervisor.ID || approval.ApprovedAt.IsZero() {
+		t.Errorf("got approval %+v, want totals %+v", approval, want)
+	}
+
+	select {
+	case e := <-sub.C:
+		var published models.TimesheetApproval
+		if err := json.Unmarshal(e.Data, &published); err != nil {
+			t.Fatal(err)
+		}
+		if e.UserID != anna.ID || published.ID != approval.ID {
+			t.Errorf("got live event %+v, want the approval", e)
+		}
+	default:
+		t.Error("the approval was not published to live subscribers")
+	}
+
+	if n, err := webhooks.DeliverDue(ctx); err != nil || n != 1 {
+		t.Fatalf("DeliverDue() = %d, %v, want 1 delivery", n, err)
+	}
+	received := rcv.received()
+	if len(received) != 1 {
+		t.Fatalf("the receiver got %d requests, want 1", len(received))
+	}
+	if got := received[0].header.Get(WebhookHeaderEvent); got != models.EventTimesheetApproved {
+		t.Errorf("event header = %q, want %q", got, models.EventTimesheetApproved)
+	}
+	var event models.WebhookEvent
+	if err := json.Unmarshal(received[0].body, &event); err != nil {
+		t.Fatal(err)
+	}
+	var sent models.TimesheetApproval
+	if err := json.Unmarshal(event.Data, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if event.UserID != anna.ID || sent.ID != approval.ID || sent.Month != "2024-03" || sent.Totals != want {
+		t.Errorf("got event %+v with approval %+v", event, sent)
+	}
+
+	errs := []struct {
+		name    string
+		actorID int
+		req     models.ApproveTimesheetRequest
+		want    error
+	}{
+		{"not a supervisor", anna.ID, models.ApproveTimesheetRequest{UserID: supervisor.ID, Month: "2024-03"}, ErrUnauthorized},
+		{"own timesheet", supervisor.ID, models.ApproveTimesheetRequest{UserID: supervisor.ID, Month: "2024-03"}, ErrOwnTimesheet},
+		{"approved twice", supervisor.ID, models.ApproveTimesheetRequest{UserID: anna.ID, Month: "2024-03"}, repository.ErrTimesheetAlreadyApproved},
+		{"unknown user", supervisor.ID, models.ApproveTimesheetRequest{UserID: 999, Month: "2024-03"}, repository.ErrUserNotFound},
+		{"invalid month", supervisor.ID, models.ApproveTimesheetRequest{UserID: anna.ID, Month: "2024-13"}, ErrValidation},
+	}
+	for _, tt := range errs {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := approvals.ApproveTimesheet(ctx, tt.actorID, &tt.req); !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+	// Nothing more was sent
+	if n, err := webhooks.DeliverDue(ctx); err != nil || n != 0 {
+		t.Errorf("DeliverDue() = %d, %v, want nothing to deliver", n, err)
+	}
+
+	own, err := approvals.GetApprovals(ctx, anna.ID, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(own) != 1 || own[0].ID != approval.ID {
+		t.Errorf("got approvals %+v, want the March approval", own)
+	}
+	if _, err := approvals.GetApprovals(ctx, anna.ID, true, ""); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("got error %v for team approvals, want ErrUnauthorized", err)
+	}
+	team, err := approvals.GetApprovals(ctx, supervisor.ID, true, "2024-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(team) != 0 {
+		t.Errorf("got approvals %+v for April, want none", team)
+	}
+}
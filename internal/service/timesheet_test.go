@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+func TestGetTeamTimesheets(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	trackItems := newTestTrackItemService(db, newTestWebhookService(db, localWebhookConfig))
+
+	supervisor := newTestUser(t, db, "sam", models.RoleSupervisor)
+	anna := newTestUser(t, db, "anna", models.RoleUser)
+	disabled := newTestUser(t, db, "dora", models.RoleUser)
+	leaving := newTestUser(t, db, "dan", models.RoleUser)
+
+	items := []models.CreateTrackItemRequest{
+		{Type: "regular", WorkingHours: 8, WorkingShifts: 1, Date: "2024-03-01T09:00:00Z"},
+		{Type: "on-call", WorkingHours: 3.5, WorkingShifts: 0.5, EmergencyCall: true, Date: "2024-03-02T22:00:00Z"},
+		{Type: "regular", WorkingHours: 12, WorkingShifts: 1, HolidayCall: true, Date: "2024-03-31T08:00:00Z"},
+		// Outside the range
+		{Type: "regular", WorkingHours: 8, WorkingShifts: 1, Date: "2024-04-01T09:00:00Z"},
+	}
+	for _, user := range []*models.User{anna, disabled, leaving} {
+		for i := range items {
+			if _, err := trackItems.CreateTrackItem(ctx, user.ID, &items[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	now := time.Now()
+	if err := userRepo.SetDisabledAt(ctx, disabled.ID, &now); err != nil {
+		t.Fatal(err)
+	}
+	if err := userRepo.SetDeletionScheduledAt(ctx, leaving.ID, &now); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := trackItems.GetTeamTimesheets(ctx, anna.ID, "2024-03-01", "2024-03-31", ""); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("team timesheets of a user: got error %v, want %v", err, ErrUnauthorized)
+	}
+
+	timesheets, err := trackItems.GetTeamTimesheets(ctx, supervisor.ID, "2024-03-01", "2024-03-31", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logins []string
+	for _, ts := range timesheets {
+		logins = append(logins, ts.User.Login)
+	}
+	if want := []string{"anna", "sam"}; !slices.Equal(logins, want) {
+		t.Fatalf("got timesheets of %v, want %v without disabled accounts and accounts scheduled for deletion", logins, want)
+	}
+
+	want := models.TrackItemSummary{Items: 3, WorkingHours: 23.5, WorkingShifts: 2.5, EmergencyCalls: 1, HolidayCalls: 1}
+	if got := timesheets[0].Totals; got != want {
+		t.Errorf("got totals %+v, want %+v", got, want)
+	}
+	if days := len(timesheets[0].Days); days != 31 {
+		t.Errorf("got %d days, want 31", days)
+	}
+}
+
+func TestDateRangesIncludeTheWholeLastDay(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	trackItems := newTestTrackItemService(db, newTestWebhookService(db, localWebhookConfig))
+	anna := newTestUser(t, db, "anna", models.RoleUser)
+
+	// After the last whole second of March 31, and at the start of the next day
+	for _, date := range []string{"2024-03-31T23:59:59.5Z", "2024-04-01T00:00:00Z"} {
+		if _, err := trackItems.CreateTrackItem(ctx, anna.ID, &models.CreateTrackItemRequest{Type: "regular", WorkingHours: 8, Date: date}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := trackItems.ListTrackItems(ctx, anna.ID, &models.ListTrackItemsQuery{StartDate: "2024-03-31", EndDate: "2024-03-31"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("listed %d items on March 31, want 1", len(page.Items))
+	}
+
+	dr, err := trackItems.ResolveDateRange(ctx, anna.ID, "2024-03-01", "2024-03-31", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := trackItems.GetSummary(ctx, anna.ID, dr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Items != 1 || summary.EndDate != "2024-03-31" {
+		t.Errorf("got summary %+v, want 1 item up to 2024-03-31", summary)
+	}
+
+	timesheet, err := trackItems.GetTimesheet(ctx, anna.ID, "2024-03-01", "2024-03-31", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timesheet.Days) != 31 || timesheet.Totals.Items != 1 || timesheet.EndDate.Format("2006-01-02") != "2024-03-31" {
+		t.Errorf("got %d days with %d items up to %s, want 31 days with 1 item up to 2024-03-31", len(timesheet.Days), timesheet.Totals.Items, timesheet.EndDate)
+	}
+
+	copied, err := trackItems.CopyTrackItems(ctx, anna.ID, &models.CopyTrackItemsRequest{SourceStartDate: "2024-03-31", SourceEndDate: "2024-03-31", TargetStartDate: "2024-05-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copied.Created) != 1 {
+		t.Errorf("copied %d items of March 31, want 1", len(copied.Created))
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// ErrInvalidTimeZone is returned for time zone names that are not known IANA zones
+var ErrInvalidTimeZone = errors.New("invalid time zone")
+
+// loadLocation loads an IANA time zone, treating an empty name as UTC
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q, use an IANA name such as Europe/Moscow", ErrInvalidTimeZone, name)
+	}
+
+	return loc, nil
+}
+
+// userLocation returns the time zone a user's dates are interpreted in: the
+// override when one is given, otherwise the zone from the user's profile
+func userLocation(ctx context.Context, userRepo *repository.UserRepository, userID int, override string) (*time.Location, error) {
+	if override != "" {
+		return loadLocation(override)
+	}
+
+	user, err := userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return loadLocation(user.TimeZone)
+}
+
+// parseLocalDate parses a YYYY-MM-DD date as midnight in loc
+func parseLocalDate(value string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// MaxBatchOperations limits the number of operations accepted in one batch
+const MaxBatchOperations = 100
+
+// BatchResult is the outcome of a single batch operation
+type BatchResult struct {
+	Index int
+	Op    string
+	Item  *models.TrackItem
+	Err   error
+}
+
+// BatchOperationError reports the operation that aborted an atomic batch
+type BatchOperationError struct {
+	Index int
+	Op    string
+	Err   error
+}
+
+func (e *BatchOperationError) Error() string {
+	return fmt.Sprintf("operation %d (%s) failed: %v", e.Index, e.Op, e.Err)
+}
+
+func (e *BatchOperationError) Unwrap() error {
+	return e.Err
+}
+
+// BatchTrackItems applies a list of create, update and delete operations for a user.
+// In atomic mode all operations run in one transaction and the first failure is
+// returned as a *BatchOperationError with nothing applied. In best-effort mode every
+// operation is applied on its own and its error, if any, is reported in its result.
+func (s *TrackItemService) BatchTrackItems(ctx context.Context, userID int, req *models.BatchTrackItemsRequest) ([]BatchResult, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.BatchTrackItems")
+	defer span.End()
+
+	// Validate input
+	if req.Mode == "" {
+		req.Mode = models.BatchModeAtomic
+	}
+	v := newValidation(ErrValidation)
+	if req.Mode != models.BatchModeAtomic && req.Mode != models.BatchModeBestEffort {
+		v.add("mode", models.FieldInvalidValue, fmt.Sprintf("invalid mode %q, use %q or %q", req.Mode, models.BatchModeAtomic, models.BatchModeBestEffort))
+	}
+	if len(req.Operations) == 0 {
+		v.add("operations", models.FieldRequired, "operations are required")
+	}
+	if len(req.Operations) > MaxBatchOperations {
+		v.add("operations", models.FieldOutOfRange, fmt.Sprintf("too many operations, at most %d are allowed per batch", MaxBatchOperations))
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, 0, len(req.Operations))
+
+	if req.Mode == models.BatchModeBestEffort {
+		for i, op := range req.Operations {
+			var item *models.TrackItem
+			err := s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+				var err error
+				item, err = s.applyBatchOperation(ctx, repo, userID, &op)
+				return err
+			})
+			results = append(results, BatchResult{Index: i, Op: op.Op, Item: item, Err: err})
+		}
+		return results, nil
+	}
+
+	err := s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		for i, op := range req.Operations {
+			item, err := s.applyBatchOperation(ctx, repo, userID, &op)
+			if err != nil {
+				return &BatchOperationError{Index: i, Op: op.Op, Err: prefixFields(err, fmt.Sprintf("operations[%d].", i))}
+			}
+			results = append(results, BatchResult{Index: i, Op: op.Op, Item: item})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// applyBatchOperation runs a single batch operation against repo using the same
+// validation and ownership rules as the single-item methods, queueing its event
+// in the same transaction
+func (s *TrackItemService) applyBatchOperation(ctx context.Context, repo *repository.TrackItemRepository, userID int, op *models.BatchOperation) (*models.TrackItem, error) {
+	switch op.Op {
+	case models.BatchOpCreate:
+		if op.Item == nil {
+			return nil, invalidField(ErrValidation, "item", models.FieldRequired, "item is required for create")
+		}
+
+		item, err := newTrackItem(userID, op.Item)
+		if err != nil {
+			return nil, prefixFields(err, "item.")
+		}
+		if err := repo.Create(ctx, item); err != nil {
+			return nil, fmt.Errorf("failed to create track item: %w", err)
+		}
+		return item, s.events.trackItemChanged(ctx, repo, models.EventTrackItemCreated, item)
+
+	case models.BatchOpUpdate:
+		if op.Changes == nil {
+			return nil, invalidField(ErrValidation, "changes", models.FieldRequired, "changes are required for update")
+		}
+
+		item, err := findOwnedTrackItem(ctx, repo, userID, op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyTrackItemUpdate(item, op.Changes); err != nil {
+			return nil, prefixFields(err, "changes.")
+		}
+		if err := repo.Update(ctx, item); err != nil {
+			return nil, fmt.Errorf("failed to update track item: %w", err)
+		}
+		return item, s.events.trackItemChanged(ctx, repo, models.EventTrackItemUpdated, item)
+
+	case models.BatchOpDelete:
+		item, err := findOwnedTrackItem(ctx, repo, userID, op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := repo.Delete(ctx, op.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete track item: %w", err)
+		}
+		return nil, s.events.trackItemChanged(ctx, repo, models.EventTrackItemDeleted, item)
+
+	default:
+		return nil, invalidField(ErrValidation, "op", models.FieldInvalidValue, fmt.Sprintf("unknown operation %q", op.Op))
+	}
+}
+
+// findOwnedTrackItem loads a track item and verifies it belongs to the user
+func findOwnedTrackItem(ctx context.Context, repo *repository.TrackItemRepository, userID, itemID int) (*models.TrackItem, error) {
+	if itemID <= 0 {
+		return nil, invalidField(ErrValidation, "id", models.FieldRequired, "id is required")
+	}
+
+	item, err := repo.FindByID(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify ownership
+	if item.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	return item, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// ErrCopyConflict is returned when a copy with the "fail" strategy would
+// place an item on a day that already has an item of the same type
+var ErrCopyConflict = errors.New("target range already has conflicting track items")
+
+// maxCopyDays limits the size of the source range of a copy
+const maxCopyDays = 366
+
+// CopyTrackItems duplicates a user's track items from a source range to a range
+// starting at the target date. Items keep their flags and local time of day and
+// move by whole calendar days in the user's time zone. An item conflicts when
+// the target day already has an item of the same type. Everything happens in one
+// transaction, so a failed copy leaves no partial result behind.
+func (s *TrackItemService) CopyTrackItems(ctx context.Context, userID int, req *models.CopyTrackItemsRequest) (*models.CopyTrackItemsResponse, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.CopyTrackItems")
+	defer span.End()
+
+	loc, err := userLocation(ctx, s.userRepo, userID, req.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	v := newValidation(ErrValidation)
+	sourceStart, err := parseLocalDate(req.SourceStartDate, loc)
+	if err != nil {
+		v.add("source_start_date", models.FieldInvalidFormat, "invalid source start date format, use YYYY-MM-DD")
+	}
+	sourceEnd, err := parseLocalDate(req.SourceEndDate, loc)
+	if err != nil {
+		v.add("source_end_date", models.FieldInvalidFormat, "invalid source end date format, use YYYY-MM-DD")
+	}
+	targetStart, err := parseLocalDate(req.TargetStartDate, loc)
+	if err != nil {
+		v.add("target_start_date", models.FieldInvalidFormat, "invalid target start date format, use YYYY-MM-DD")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if sourceEnd.Before(sourceStart) {
+		return nil, invalidField(ErrValidation, "source_end_date", models.FieldConflict, "source end date must not be before source start date")
+	}
+	length := daysBetween(sourceStart, sourceEnd) + 1
+	if length > maxCopyDays {
+		return nil, invalidField(ErrValidation, "source_end_date", models.FieldOutOfRange, fmt.Sprintf("source range must not exceed %d days", maxCopyDays))
+	}
+	shift := daysBetween(sourceStart, targetStart)
+	if shift > -length && shift < length {
+		return nil, invalidField(ErrValidation, "target_start_date", models.FieldConflict, "target range must not overlap the source range")
+	}
+	targetEnd := targetStart.AddDate(0, 0, length-1)
+
+	conflict := req.Conflict
+	if conflict == "" {
+		conflict = models.CopyConflictSkip
+	}
+	if conflict != models.CopyConflictSkip && conflict != models.CopyConflictOverwrite && conflict != models.CopyConflictFail {
+		return nil, invalidField(ErrValidation, "conflict", models.FieldInvalidValue, fmt.Sprintf("invalid conflict strategy %q, use skip, overwrite or fail", conflict))
+	}
+
+	resp := &models.CopyTrackItemsResponse{
+		Created: []models.TrackItem{},
+		Skipped: []models.TrackItem{},
+	}
+
+	err = s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		source, err := repo.FindByDateRange(ctx, userID, sourceStart, nextDay(sourceEnd))
+		if err != nil {
+			return err
+		}
+		existing, err := repo.FindByDateRange(ctx, userID, targetStart, nextDay(targetEnd))
+		if err != nil {
+			return err
+		}
+
+		// Index the target range by local day and type
+		taken := make(map[string][]models.TrackItem, len(existing))
+		for _, item := range existing {
+			key := item.Date.In(loc).Format("2006-01-02") + "|" + item.Type
+			taken[key] = append(taken[key], item)
+		}
+
+		// Oldest first, so created items come back in date order
+		for i := len(source) - 1; i >= 0; i-- {
+			item := source[i]
+			date := item.Date.In(loc).AddDate(0, 0, shift)
+			key := date.Format("2006-01-02") + "|" + item.Type
+
+			if conflicting := taken[key]; len(conflicting) > 0 {
+				switch conflict {
+				case models.CopyConflictFail:
+					return fmt.Errorf("%w: %s on %s", ErrCopyConflict, item.Type, date.Format("2006-01-02"))
+				case models.CopyConflictSkip:
+					resp.Skipped = append(resp.Skipped, item)
+					continue
+				case models.CopyConflictOverwrite:
+					for _, old := range conflicting {
+						if err := repo.Delete(ctx, old.ID); err != nil {
+							return fmt.Errorf("failed to delete track item: %w", err)
+						}
+						if err := s.events.trackItemChanged(ctx, repo, models.EventTrackItemDeleted, &old); err != nil {
+							return err
+						}
+						resp.Overwritten++
+					}
+					delete(taken, key)
+				}
+			}
+
+			copied := &models.TrackItem{
+				UserID:        userID,
+				Type:          item.Type,
+				EmergencyCall: item.EmergencyCall,
+				HolidayCall:   item.HolidayCall,
+				WorkingHours:  item.WorkingHours,
+				WorkingShifts: item.WorkingShifts,
+				Date:          date,
+			}
+			if err := repo.Create(ctx, copied); err != nil {
+				return fmt.Errorf("failed to create track item: %w", err)
+			}
+			if err := s.events.trackItemChanged(ctx, repo, models.EventTrackItemCreated, copied); err != nil {
+				return err
+			}
+			resp.Created = append(resp.Created, *copied)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// nextDay returns the midnight after the day starting at midnight day, the
+// exclusive end of that day in its time zone
+func nextDay(day time.Time) time.Time {
+	return day.AddDate(0, 0, 1)
+}
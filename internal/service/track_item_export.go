@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+)
+
+// ErrInvalidDateRange is returned when a report date range is missing or malformed
+var ErrInvalidDateRange = errors.New("invalid date range")
+
+// maxReportDays limits the date range of summaries and exports
+const maxReportDays = 366
+
+// DateRange is a validated range of whole days in a time zone
+type DateRange struct {
+	Start    time.Time // Midnight of the first day in Location
+	End      time.Time // Midnight after the last day in Location, exclusive
+	Location *time.Location
+}
+
+// LastDay returns midnight of the last day of the range
+func (dr *DateRange) LastDay() time.Time {
+	return dr.End.AddDate(0, 0, -1)
+}
+
+// ResolveDateRange parses an inclusive YYYY-MM-DD range in the user's time
+// zone, or in tz when it is given
+func (s *TrackItemService) ResolveDateRange(ctx context.Context, userID int, startDateStr, endDateStr, tz string) (*DateRange, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.ResolveDateRange")
+	defer span.End()
+
+	loc, err := userLocation(ctx, s.userRepo, userID, tz)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDateRange(startDateStr, endDateStr, loc)
+}
+
+// newDateRange parses an inclusive YYYY-MM-DD range in loc
+func newDateRange(startDateStr, endDateStr string, loc *time.Location) (*DateRange, error) {
+	v := newValidation(ErrInvalidDateRange)
+	startDate, err := parseLocalDate(startDateStr, loc)
+	switch {
+	case startDateStr == "":
+		v.add("start_date", models.FieldRequired, "start_date is required")
+	case err != nil:
+		v.add("start_date", models.FieldInvalidFormat, "invalid start date format, use YYYY-MM-DD")
+	}
+	endDate, err := parseLocalDate(endDateStr, loc)
+	switch {
+	case endDateStr == "":
+		v.add("end_date", models.FieldRequired, "end_date is required")
+	case err != nil:
+		v.add("end_date", models.FieldInvalidFormat, "invalid end date format, use YYYY-MM-DD")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if endDate.Before(startDate) {
+		return nil, invalidField(ErrInvalidDateRange, "end_date", models.FieldConflict, "end date must not be before start date")
+	}
+	if daysBetween(startDate, endDate) >= maxReportDays {
+		return nil, invalidField(ErrInvalidDateRange, "end_date", models.FieldOutOfRange, fmt.Sprintf("date range must not exceed %d days", maxReportDays))
+	}
+
+	return &DateRange{Start: startDate, End: nextDay(endDate), Location: loc}, nil
+}
+
+// StreamTrackItems calls fn for each of a user's track items in the range, oldest first
+func (s *TrackItemService) StreamTrackItems(ctx context.Context, userID int, dr *DateRange, fn func(item *models.TrackItem) error) error {
+	ctx, span := tracer.Start(ctx, "TrackItemService.StreamTrackItems")
+	defer span.End()
+
+	return s.trackItemRepo.StreamByDateRange(ctx, userID, dr.Start, dr.End, fn)
+}
+
+// GetSummary totals a user's track items in the range. Exports build their
+// totals with the same models.TrackItemSummary, so the numbers always agree.
+func (s *TrackItemService) GetSummary(ctx context.Context, userID int, dr *DateRange) (*models.SummaryResponse, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.GetSummary")
+	defer span.End()
+
+	resp := &models.SummaryResponse{
+		StartDate: dr.Start.Format("2006-01-02"),
+		EndDate:   dr.LastDay().Format("2006-01-02"),
+		TimeZone:  dr.Location.String(),
+	}
+
+	err := s.StreamTrackItems(ctx, userID, dr, func(item *models.TrackItem) error {
+		resp.Add(item)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize track items: %w", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/importer"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+var (
+	// ErrInvalidImport is returned when the import options or the file layout are invalid
+	ErrInvalidImport = errors.New("invalid import")
+	// ErrImportRejected is returned when an import is confirmed while rows have errors
+	ErrImportRejected = errors.New("import has invalid rows, nothing was imported")
+)
+
+// Track item fields that can be mapped to columns
+const (
+	importFieldDate          = "date"
+	importFieldTime          = "time"
+	importFieldType          = "type"
+	importFieldWorkingHours  = "working_hours"
+	importFieldWorkingShifts = "working_shifts"
+	importFieldEmergencyCall = "emergency_call"
+	importFieldHolidayCall   = "holiday_call"
+)
+
+var importFields = []string{
+	importFieldDate,
+	importFieldTime,
+	importFieldType,
+	importFieldWorkingHours,
+	importFieldWorkingShifts,
+	importFieldEmergencyCall,
+	importFieldHolidayCall,
+}
+
+// ImportTrackItems validates the rows of an imported table and reports per-row
+// errors and duplicates. A row duplicates an existing item, or an earlier row,
+// when it has the same type on the same day in the user's time zone.
+//
+// Without Confirm nothing is stored. With Confirm all valid rows are stored in
+// one transaction, and only when no row has errors; otherwise the preview is
+// returned together with ErrImportRejected.
+func (s *TrackItemService) ImportTrackItems(ctx context.Context, userID int, table *importer.Table, req *models.ImportTrackItemsRequest) (*models.ImportTrackItemsResponse, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.ImportTrackItems")
+	defer span.End()
+
+	loc, err := userLocation(ctx, s.userRepo, userID, req.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	dateFormat := req.DateFormat
+	if dateFormat == "" {
+		dateFormat = models.ImportDateISO
+	}
+	if !importer.ValidDateFormat(dateFormat) {
+		return nil, invalidField(ErrInvalidImport, "date_format", models.FieldInvalidValue, fmt.Sprintf("invalid date format %q, use iso, dmy or mdy", dateFormat))
+	}
+
+	duplicates := req.Duplicates
+	if duplicates == "" {
+		duplicates = models.ImportDuplicatesSkip
+	}
+	if duplicates != models.ImportDuplicatesSkip && duplicates != models.ImportDuplicatesImport {
+		return nil, invalidField(ErrInvalidImport, "duplicates", models.FieldInvalidValue, fmt.Sprintf("invalid duplicates strategy %q, use skip or import", duplicates))
+	}
+
+	columns, err := mapImportColumns(table.Header, req.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.ImportTrackItemsResponse{Rows: []models.ImportRow{}}
+	for i, record := range table.Rows {
+		cell := func(field string) string {
+			if col, ok := columns[field]; ok {
+				return strings.TrimSpace(record[col])
+			}
+			return ""
+		}
+
+		// Skip blank rows and the totals footer of our own CSV export
+		if strings.Join(record, "") == "" || strings.EqualFold(cell(importFieldDate), "total") {
+			continue
+		}
+
+		row := models.ImportRow{Row: i + 2}
+		item, errs := parseImportRow(userID, cell, dateFormat, loc)
+		if len(errs) > 0 {
+			row.Errors = errs
+			resp.Invalid++
+		} else {
+			row.Item = item
+			resp.Valid++
+		}
+		resp.Rows = append(resp.Rows, row)
+	}
+	resp.Total = len(resp.Rows)
+
+	if resp.Valid == 0 {
+		if req.Confirm && resp.Invalid > 0 {
+			return resp, ErrImportRejected
+		}
+		return resp, nil
+	}
+
+	err = s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		if err := markImportDuplicates(ctx, repo, userID, resp, loc); err != nil {
+			return err
+		}
+
+		for i := range resp.Rows {
+			row := &resp.Rows[i]
+			if row.Item == nil || (row.Duplicate && duplicates == models.ImportDuplicatesSkip) {
+				continue
+			}
+			resp.Imported++
+			if !req.Confirm || resp.Invalid > 0 {
+				continue
+			}
+			if err := repo.Create(ctx, row.Item); err != nil {
+				return fmt.Errorf("failed to create track item from row %d: %w", row.Row, err)
+			}
+			if err := s.events.trackItemChanged(ctx, repo, models.EventTrackItemCreated, row.Item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import track items: %w", err)
+	}
+
+	if req.Confirm {
+		if resp.Invalid > 0 {
+			return resp, ErrImportRejected
+		}
+		resp.Confirmed = true
+	}
+
+	return resp, nil
+}
+
+// mapImportColumns resolves the column index of each mapped field. Fields that
+// are not mapped use the column named like the field, if there is one. Header
+// names are matched case-insensitively.
+func mapImportColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		key := strings.ToLower(name)
+		if _, ok := index[key]; !ok && key != "" {
+			index[key] = i
+		}
+	}
+
+	known := make(map[string]bool, len(importFields))
+	for _, field := range importFields {
+		known[field] = true
+	}
+	for field := range mapping {
+		if !known[field] {
+			return nil, invalidField(ErrInvalidImport, "mapping", models.FieldInvalidValue, fmt.Sprintf("unknown field %q in mapping", field))
+		}
+	}
+
+	columns := make(map[string]int)
+	for _, field := range importFields {
+		if column, ok := mapping[field]; ok {
+			col, found := index[strings.ToLower(strings.TrimSpace(column))]
+			if !found {
+				return nil, invalidField(ErrInvalidImport, "mapping", models.FieldInvalidValue, fmt.Sprintf("column %q mapped to %s not found", column, field))
+			}
+			columns[field] = col
+		} else if col, found := index[field]; found {
+			columns[field] = col
+		}
+	}
+
+	for _, field := range []string{importFieldDate, importFieldType} {
+		if _, ok := columns[field]; !ok {
+			return nil, invalidField(ErrInvalidImport, "mapping", models.FieldRequired, fmt.Sprintf("no column for %s, add it to the mapping", field))
+		}
+	}
+
+	return columns, nil
+}
+
+// parseImportRow converts the cells of one row into a track item, collecting
+// every problem of the row rather than stopping at the first
+func parseImportRow(userID int, cell func(field string) string, dateFormat string, loc *time.Location) (*models.TrackItem, []string) {
+	var errs []string
+	collect := func(err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	req := &models.CreateTrackItemRequest{Type: cell(importFieldType)}
+
+	date, err := importer.ParseDate(cell(importFieldDate), cell(importFieldTime), dateFormat, loc)
+	collect(err)
+	req.Date = date.Format(time.RFC3339)
+
+	req.WorkingHours, err = importer.ParseNumber(importFieldWorkingHours, cell(importFieldWorkingHours))
+	collect(err)
+	req.WorkingShifts, err = importer.ParseNumber(importFieldWorkingShifts, cell(importFieldWorkingShifts))
+	collect(err)
+	req.EmergencyCall, err = importer.ParseBool(importFieldEmergencyCall, cell(importFieldEmergencyCall))
+	collect(err)
+	req.HolidayCall, err = importer.ParseBool(importFieldHolidayCall, cell(importFieldHolidayCall))
+	collect(err)
+
+	if len(errs) > 0 {
+		if req.Type == "" {
+			errs = append(errs, "type is required")
+		}
+		return nil, errs
+	}
+
+	// Same rules as items created through the API
+	item, err := newTrackItem(userID, req)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	return item, nil
+}
+
+// markImportDuplicates flags valid rows that repeat an existing item or an
+// earlier row of the import
+func markImportDuplicates(ctx context.Context, repo *repository.TrackItemRepository, userID int, resp *models.ImportTrackItemsResponse, loc *time.Location) error {
+	var first, last time.Time
+	for _, row := range resp.Rows {
+		if row.Item == nil {
+			continue
+		}
+		if first.IsZero() || row.Item.Date.Before(first) {
+			first = row.Item.Date
+		}
+		if row.Item.Date.After(last) {
+			last = row.Item.Date
+		}
+	}
+
+	localDay := func(t time.Time) time.Time {
+		t = t.In(loc)
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+	existing, err := repo.FindByDateRange(ctx, userID, localDay(first), nextDay(localDay(last)))
+	if err != nil {
+		return err
+	}
+
+	existingIDs := make(map[string]int, len(existing))
+	for _, item := range existing {
+		existingIDs[item.Date.In(loc).Format("2006-01-02")+"|"+item.Type] = item.ID
+	}
+
+	seenRows := make(map[string]int)
+	for i := range resp.Rows {
+		row := &resp.Rows[i]
+		if row.Item == nil {
+			continue
+		}
+
+		key := row.Item.Date.In(loc).Format("2006-01-02") + "|" + row.Item.Type
+		if id, ok := existingIDs[key]; ok {
+			row.Duplicate = true
+			row.DuplicateOf = &id
+		} else if earlier, ok := seenRows[key]; ok {
+			row.Duplicate = true
+			row.DuplicateRow = &earlier
+		} else {
+			seenRows[key] = row.Row
+		}
+		if row.Duplicate {
+			resp.Duplicates++
+		}
+	}
+
+	return nil
+}
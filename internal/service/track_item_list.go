@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// ErrInvalidListQuery is returned when list query parameters cannot be parsed
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+	defaultSort     = "-date"
+)
+
+// listCursor is the decoded form of TrackItemPage.NextCursor
+type listCursor struct {
+	Sort         string    `json:"s"`
+	Date         time.Time `json:"d"`
+	WorkingHours float64   `json:"h"`
+	ID           int       `json:"id"`
+}
+
+// ListTrackItems retrieves a filtered and sorted list of a user's track items.
+// A page is limited in size only when limit or cursor is set; otherwise every
+// matching item is returned.
+func (s *TrackItemService) ListTrackItems(ctx context.Context, userID int, req *models.ListTrackItemsQuery) (*models.TrackItemPage, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.ListTrackItems")
+	defer span.End()
+
+	loc, err := userLocation(ctx, s.userRepo, userID, req.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := parseListQuery(req, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	paginated := req.Limit != "" || req.Cursor != ""
+	if paginated {
+		// Fetch one extra row to find out whether another page exists
+		q.Limit++
+	}
+
+	items, err := s.trackItemRepo.FindPage(ctx, userID, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list track items: %w", err)
+	}
+
+	page := &models.TrackItemPage{Items: items}
+	if page.Items == nil {
+		page.Items = []models.TrackItem{}
+	}
+
+	if paginated && len(items) == q.Limit {
+		page.Items = items[:q.Limit-1]
+		page.NextCursor = encodeListCursor(sortParam(req.Sort), &page.Items[len(page.Items)-1])
+	}
+
+	if req.IncludeTotal == "true" {
+		total, err := s.trackItemRepo.Count(ctx, userID, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count track items: %w", err)
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// parseListQuery validates the raw query parameters and converts them into a
+// repository query. Date-only bounds are whole days in loc.
+func parseListQuery(req *models.ListTrackItemsQuery, loc *time.Location) (*repository.TrackItemQuery, error) {
+	q := &repository.TrackItemQuery{}
+	v := newValidation(ErrInvalidListQuery)
+
+	if req.StartDate != "" {
+		if startDate, err := parseLocalDate(req.StartDate, loc); err != nil {
+			v.add("start_date", models.FieldInvalidFormat, "invalid start date format, use YYYY-MM-DD")
+		} else {
+			q.StartDate = &startDate
+		}
+	}
+	if req.EndDate != "" {
+		if endDate, err := parseLocalDate(req.EndDate, loc); err != nil {
+			v.add("end_date", models.FieldInvalidFormat, "invalid end date format, use YYYY-MM-DD")
+		} else {
+			endDate = nextDay(endDate)
+			q.EndDate = &endDate
+		}
+	}
+
+	if req.Type != "" {
+		for _, t := range strings.Split(req.Type, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				q.Types = append(q.Types, t)
+			}
+		}
+	}
+
+	q.EmergencyCall = parseOptionalBool(v, "emergency_call", req.EmergencyCall)
+	q.HolidayCall = parseOptionalBool(v, "holiday_call", req.HolidayCall)
+	q.MinHours = parseOptionalFloat(v, "min_hours", req.MinHours)
+	q.MaxHours = parseOptionalFloat(v, "max_hours", req.MaxHours)
+
+	sort := sortParam(req.Sort)
+	q.SortDesc = strings.HasPrefix(sort, "-")
+	q.SortField = strings.TrimPrefix(sort, "-")
+	if q.SortField != repository.SortByDate && q.SortField != repository.SortByWorkingHours {
+		v.add("sort", models.FieldInvalidValue, fmt.Sprintf("invalid sort %q, use date, -date, working_hours or -working_hours", req.Sort))
+	}
+
+	if req.Limit != "" || req.Cursor != "" {
+		q.Limit = parseLimit(v, req.Limit, defaultPageSize, maxPageSize)
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodeListCursor(req.Cursor)
+		if err != nil || cursor.Sort != sort {
+			v.add("cursor", models.FieldInvalidValue, "invalid cursor")
+		} else {
+			q.After = &models.TrackItem{ID: cursor.ID, Date: cursor.Date, WorkingHours: cursor.WorkingHours}
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// sortParam returns the sort parameter or the default sort when it is empty
+func sortParam(sort string) string {
+	if sort == "" {
+		return defaultSort
+	}
+	return sort
+}
+
+// encodeListCursor returns an opaque cursor pointing just after item
+func encodeListCursor(sort string, item *models.TrackItem) string {
+	data, _ := json.Marshal(listCursor{
+		Sort:         sort,
+		Date:         item.Date,
+		WorkingHours: item.WorkingHours,
+		ID:           item.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor parses a cursor produced by encodeListCursor
+func decodeListCursor(value string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// parseOptionalBool parses a boolean query parameter, returning nil when it is
+// empty or invalid
+func parseOptionalBool(v *validation, name, value string) *bool {
+	if value == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		v.add(name, models.FieldInvalidFormat, name+" must be true or false")
+		return nil
+	}
+
+	return &b
+}
+
+// parseOptionalFloat parses a numeric query parameter, returning nil when it
+// is empty or invalid
+func parseOptionalFloat(v *validation, name, value string) *float64 {
+	if value == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		v.add(name, models.FieldInvalidFormat, name+" must be a number")
+		return nil
+	}
+
+	return &f
+}
+
+// parseLimit parses the limit query parameter of a page, returning
+// defaultLimit when it is empty or invalid
+func parseLimit(v *validation, value string, defaultLimit, maxLimit int) int {
+	if value == "" {
+		return defaultLimit
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxLimit {
+		v.add("limit", models.FieldOutOfRange, fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		return defaultLimit
+	}
+
+	return limit
+}
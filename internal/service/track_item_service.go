@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
@@ -13,82 +12,48 @@ import (
 // TrackItemService handles track item business logic
 type TrackItemService struct {
 	trackItemRepo *repository.TrackItemRepository
+	userRepo      *repository.UserRepository
+	events        *EventService
 }
 
-// NewTrackItemService creates a new track item service
-func NewTrackItemService(trackItemRepo *repository.TrackItemRepository) *TrackItemService {
+// NewTrackItemService creates a new track item service. Changes to track items
+// are published as events.
+func NewTrackItemService(trackItemRepo *repository.TrackItemRepository, userRepo *repository.UserRepository, events *EventService) *TrackItemService {
 	return &TrackItemService{
 		trackItemRepo: trackItemRepo,
+		userRepo:      userRepo,
+		events:        events,
 	}
 }
 
 // CreateTrackItem creates a new track item for a user
 func (s *TrackItemService) CreateTrackItem(ctx context.Context, userID int, req *models.CreateTrackItemRequest) (*models.TrackItem, error) {
-	// Validate input
-	if req.Type == "" {
-		return nil, errors.New("type is required")
-	}
+	ctx, span := tracer.Start(ctx, "TrackItemService.CreateTrackItem")
+	defer span.End()
 
-	// Parse date
-	date, err := time.Parse(time.RFC3339, req.Date)
+	item, err := newTrackItem(userID, req)
 	if err != nil {
-		return nil, fmt.Errorf("invalid date format, use ISO 8601 (RFC3339): %w", err)
-	}
-
-	item := &models.TrackItem{
-		UserID:        userID,
-		Type:          req.Type,
-		EmergencyCall: req.EmergencyCall,
-		HolidayCall:   req.HolidayCall,
-		WorkingHours:  req.WorkingHours,
-		WorkingShifts: req.WorkingShifts,
-		Date:          date,
+		return nil, err
 	}
 
-	err = s.trackItemRepo.Create(ctx, item)
+	err = s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		if err := repo.Create(ctx, item); err != nil {
+			return fmt.Errorf("failed to create track item: %w", err)
+		}
+		return s.events.trackItemChanged(ctx, repo, models.EventTrackItemCreated, item)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create track item: %w", err)
+		return nil, err
 	}
 
 	return item, nil
 }
 
-// GetUserTrackItems retrieves all track items for a user
-func (s *TrackItemService) GetUserTrackItems(ctx context.Context, userID int) ([]models.TrackItem, error) {
-	items, err := s.trackItemRepo.FindByUserID(ctx, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get track items: %w", err)
-	}
-
-	return items, nil
-}
-
-// GetTrackItemsByDateRange retrieves track items for a user within a date range
-func (s *TrackItemService) GetTrackItemsByDateRange(ctx context.Context, userID int, startDateStr, endDateStr string) ([]models.TrackItem, error) {
-	// Parse dates (accept date-only format)
-	startDate, err := time.Parse("2006-01-02", startDateStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid start date format, use YYYY-MM-DD: %w", err)
-	}
-
-	endDate, err := time.Parse("2006-01-02", endDateStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid end date format, use YYYY-MM-DD: %w", err)
-	}
-
-	// Set end date to end of day
-	endDate = endDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
-
-	items, err := s.trackItemRepo.FindByDateRange(ctx, userID, startDate, endDate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get track items by date range: %w", err)
-	}
-
-	return items, nil
-}
-
 // GetTrackItem retrieves a specific track item, ensuring it belongs to the user
 func (s *TrackItemService) GetTrackItem(ctx context.Context, userID, itemID int) (*models.TrackItem, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.GetTrackItem")
+	defer span.End()
+
 	item, err := s.trackItemRepo.FindByID(ctx, itemID)
 	if err != nil {
 		return nil, err
@@ -104,6 +69,9 @@ func (s *TrackItemService) GetTrackItem(ctx context.Context, userID, itemID int)
 
 // UpdateTrackItem updates a track item, ensuring it belongs to the user
 func (s *TrackItemService) UpdateTrackItem(ctx context.Context, userID, itemID int, req *models.UpdateTrackItemRequest) (*models.TrackItem, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.UpdateTrackItem")
+	defer span.End()
+
 	// Get existing item
 	item, err := s.trackItemRepo.FindByID(ctx, itemID)
 	if err != nil {
@@ -115,33 +83,18 @@ func (s *TrackItemService) UpdateTrackItem(ctx context.Context, userID, itemID i
 		return nil, ErrUnauthorized
 	}
 
-	// Update fields if provided
-	if req.Type != nil {
-		item.Type = *req.Type
-	}
-	if req.EmergencyCall != nil {
-		item.EmergencyCall = *req.EmergencyCall
-	}
-	if req.HolidayCall != nil {
-		item.HolidayCall = *req.HolidayCall
-	}
-	if req.WorkingHours != nil {
-		item.WorkingHours = *req.WorkingHours
-	}
-	if req.WorkingShifts != nil {
-		item.WorkingShifts = *req.WorkingShifts
-	}
-	if req.Date != nil {
-		date, err := time.Parse(time.RFC3339, *req.Date)
-		if err != nil {
-			return nil, fmt.Errorf("invalid date format, use ISO 8601 (RFC3339): %w", err)
-		}
-		item.Date = date
+	if err := applyTrackItemUpdate(item, req); err != nil {
+		return nil, err
 	}
 
-	err = s.trackItemRepo.Update(ctx, item)
+	err = s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		if err := repo.Update(ctx, item); err != nil {
+			return fmt.Errorf("failed to update track item: %w", err)
+		}
+		return s.events.trackItemChanged(ctx, repo, models.EventTrackItemUpdated, item)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update track item: %w", err)
+		return nil, err
 	}
 
 	return item, nil
@@ -149,6 +102,9 @@ func (s *TrackItemService) UpdateTrackItem(ctx context.Context, userID, itemID i
 
 // DeleteTrackItem deletes a track item, ensuring it belongs to the user
 func (s *TrackItemService) DeleteTrackItem(ctx context.Context, userID, itemID int) error {
+	ctx, span := tracer.Start(ctx, "TrackItemService.DeleteTrackItem")
+	defer span.End()
+
 	// Get existing item
 	item, err := s.trackItemRepo.FindByID(ctx, itemID)
 	if err != nil {
@@ -160,9 +116,73 @@ func (s *TrackItemService) DeleteTrackItem(ctx context.Context, userID, itemID i
 		return ErrUnauthorized
 	}
 
-	err = s.trackItemRepo.Delete(ctx, itemID)
+	err = s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		if err := repo.Delete(ctx, itemID); err != nil {
+			return fmt.Errorf("failed to delete track item: %w", err)
+		}
+		return s.events.trackItemChanged(ctx, repo, models.EventTrackItemDeleted, item)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete track item: %w", err)
+		return err
+	}
+
+	return nil
+}
+
+// newTrackItem validates a create request and builds the track item it describes
+func newTrackItem(userID int, req *models.CreateTrackItemRequest) (*models.TrackItem, error) {
+	// Validate input
+	v := newValidation(ErrValidation)
+	if req.Type == "" {
+		v.add("type", models.FieldRequired, "type is required")
+	}
+
+	// Parse date
+	date, err := time.Parse(time.RFC3339, req.Date)
+	if req.Date == "" {
+		v.add("date", models.FieldRequired, "date is required")
+	} else if err != nil {
+		v.add("date", models.FieldInvalidFormat, "invalid date format, use ISO 8601 (RFC3339)")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return &models.TrackItem{
+		UserID:        userID,
+		Type:          req.Type,
+		EmergencyCall: req.EmergencyCall,
+		HolidayCall:   req.HolidayCall,
+		WorkingHours:  req.WorkingHours,
+		WorkingShifts: req.WorkingShifts,
+		Date:          date.UTC(),
+	}, nil
+}
+
+// applyTrackItemUpdate copies the fields present in an update request onto item
+func applyTrackItemUpdate(item *models.TrackItem, req *models.UpdateTrackItemRequest) error {
+	// Update fields if provided
+	if req.Type != nil {
+		item.Type = *req.Type
+	}
+	if req.EmergencyCall != nil {
+		item.EmergencyCall = *req.EmergencyCall
+	}
+	if req.HolidayCall != nil {
+		item.HolidayCall = *req.HolidayCall
+	}
+	if req.WorkingHours != nil {
+		item.WorkingHours = *req.WorkingHours
+	}
+	if req.WorkingShifts != nil {
+		item.WorkingShifts = *req.WorkingShifts
+	}
+	if req.Date != nil {
+		date, err := time.Parse(time.RFC3339, *req.Date)
+		if err != nil {
+			return invalidField(ErrValidation, "date", models.FieldInvalidFormat, "invalid date format, use ISO 8601 (RFC3339)")
+		}
+		item.Date = date.UTC()
 	}
 
 	return nil
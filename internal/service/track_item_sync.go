@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+// Sync limits
+const (
+	DefaultSyncLimit  = 500
+	MaxSyncLimit      = 1000
+	MaxSyncChanges    = 500 // Client changes accepted per request
+	maxClientIDLength = 64
+)
+
+// ErrInvalidSyncQuery is returned when sync query parameters cannot be parsed
+var ErrInvalidSyncQuery = errors.New("invalid sync query")
+
+// syncCursor is the decoded form of SyncResponse.Cursor
+type syncCursor struct {
+	Version int64 `json:"v"`
+}
+
+// GetChanges returns a user's track item changes after the since cursor, in
+// the order they were made. Deleted items are returned as tombstones. An empty
+// since returns every item, without tombstones, for a full sync.
+func (s *TrackItemService) GetChanges(ctx context.Context, userID int, since, limitParam string) (*models.SyncResponse, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.GetChanges")
+	defer span.End()
+
+	v := newValidation(ErrInvalidSyncQuery)
+	limit := parseLimit(v, limitParam, DefaultSyncLimit, MaxSyncLimit)
+
+	var after int64
+	if since != "" {
+		if cursor, err := decodeSyncCursor(since); err != nil {
+			v.add("since", models.FieldInvalidValue, "invalid cursor")
+		} else {
+			after = cursor.Version
+		}
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	// Read items and tombstones from the same snapshot, so a change committed
+	// in between cannot be skipped by the cursor
+	var items []models.TrackItem
+	var tombstones []models.TrackItemTombstone
+	err := s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+		var err error
+		items, err = repo.FindChangedSince(ctx, userID, after, limit+1)
+		if err != nil {
+			return err
+		}
+		if since != "" {
+			tombstones, err = repo.FindTombstonesSince(ctx, userID, after, limit+1)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge both lists by version up to the limit
+	resp := &models.SyncResponse{
+		Items:   []models.TrackItem{},
+		Deleted: []models.TrackItemTombstone{},
+	}
+	i, j := 0, 0
+	for len(resp.Items)+len(resp.Deleted) < limit {
+		if i < len(items) && (j == len(tombstones) || items[i].Version < tombstones[j].Version) {
+			resp.Items = append(resp.Items, items[i])
+			after = items[i].Version
+			i++
+		} else if j < len(tombstones) {
+			resp.Deleted = append(resp.Deleted, tombstones[j])
+			after = tombstones[j].Version
+			j++
+		} else {
+			break
+		}
+	}
+	resp.HasMore = i < len(items) || j < len(tombstones)
+	resp.Cursor = encodeSyncCursor(after)
+
+	return resp, nil
+}
+
+// PushChanges applies changes a user's client made, possibly while offline.
+// Each change is applied on its own and its outcome reported in its result.
+//
+// A field the client changed is in conflict when it was also changed on the
+// server after the client's base version. With the "lww" conflict mode the
+// later of the two changes wins, field by field; with "report" the server
+// value is kept. Either way the conflict is reported. A change to an item
+// deleted on the server is not applied.
+func (s *TrackItemService) PushChanges(ctx context.Context, userID int, req *models.SyncRequest) ([]models.SyncChangeResult, error) {
+	ctx, span := tracer.Start(ctx, "TrackItemService.PushChanges")
+	defer span.End()
+
+	// Validate input
+	if req.Conflict == "" {
+		req.Conflict = models.SyncConflictLWW
+	}
+	v := newValidation(ErrValidation)
+	if req.Conflict != models.SyncConflictLWW && req.Conflict != models.SyncConflictReport {
+		v.add("conflict", models.FieldInvalidValue, fmt.Sprintf("invalid conflict mode %q, use %q or %q", req.Conflict, models.SyncConflictLWW, models.SyncConflictReport))
+	}
+	if len(req.Changes) == 0 {
+		v.add("changes", models.FieldRequired, "changes are required")
+	}
+	if len(req.Changes) > MaxSyncChanges {
+		v.add("changes", models.FieldOutOfRange, fmt.Sprintf("too many changes, at most %d are allowed per request", MaxSyncChanges))
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]models.SyncChangeResult, 0, len(req.Changes))
+	for i := range req.Changes {
+		change := &req.Changes[i]
+		result := models.SyncChangeResult{Index: i, ClientID: change.ClientID, ID: change.ID}
+		err := s.trackItemRepo.WithTx(ctx, func(repo *repository.TrackItemRepository) error {
+			return s.applySyncChange(ctx, repo, userID, req.Conflict, change, &result)
+		})
+		if err != nil {
+			result = models.SyncChangeResult{Index: i, ClientID: change.ClientID, ID: change.ID, Status: models.SyncStatusError, Err: err}
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// applySyncChange applies a single client change against repo and fills in
+// its result
+func (s *TrackItemService) applySyncChange(ctx context.Context, repo *repository.TrackItemRepository, userID int, mode string, change *models.SyncChange, result *models.SyncChangeResult) error {
+	if change.Op != models.SyncOpUpsert && change.Op != models.SyncOpDelete {
+		return invalidField(ErrValidation, "op", models.FieldInvalidValue, fmt.Sprintf("unknown operation %q, use %q or %q", change.Op, models.SyncOpUpsert, models.SyncOpDelete))
+	}
+	if change.ClientID == "" && change.ID <= 0 {
+		return invalidField(ErrValidation, "client_id", models.FieldRequired, "client_id or id is required")
+	}
+	if len(change.ClientID) > maxClientIDLength {
+		return invalidField(ErrValidation, "client_id", models.FieldOutOfRange, fmt.Sprintf("client_id must be at most %d characters", maxClientIDLength))
+	}
+
+	// Changes cannot be dated in the future, so a skewed client clock does
+	// not let them win every conflict
+	now := time.Now().UTC().Truncate(time.Second)
+	at := change.ChangedAt.UTC().Truncate(time.Second)
+	if change.ChangedAt.IsZero() || at.After(now) {
+		at = now
+	}
+
+	item, err := findSyncItem(ctx, repo, userID, change)
+	if errors.Is(err, repository.ErrTrackItemNotFound) {
+		return s.applySyncChangeToMissing(ctx, repo, userID, change, at, result)
+	}
+	if err != nil {
+		return err
+	}
+	result.ID = item.ID
+
+	if change.Op == models.SyncOpDelete {
+		// Keep an item changed on the server since the client's base unless
+		// the deletion is the later change
+		if item.Version > change.BaseVersion && (mode == models.SyncConflictReport || !at.After(lastChange(item))) {
+			result.Status = models.SyncStatusConflict
+			result.Item = item
+			return nil
+		}
+
+		if err := repo.Delete(ctx, item.ID); err != nil {
+			return fmt.Errorf("failed to delete track item: %w", err)
+		}
+		result.Status = models.SyncStatusDeleted
+		return s.events.trackItemChanged(ctx, repo, models.EventTrackItemDeleted, item)
+	}
+
+	proposed := *item
+	if err := applyTrackItemUpdate(&proposed, &change.Fields); err != nil {
+		return prefixFields(err, "fields.")
+	}
+
+	apply := change.Fields
+	changed := false
+	for _, field := range models.TrackItemFields {
+		if !syncFieldSet(&apply, field) {
+			continue
+		}
+		serverValue, clientValue := item.FieldValue(field), proposed.FieldValue(field)
+		if serverValue == clientValue {
+			continue
+		}
+
+		last := item.FieldClock[field]
+		if last.Version > change.BaseVersion {
+			conflict := models.SyncConflict{Field: field, ServerValue: serverValue, ClientValue: clientValue, Winner: "client"}
+			if mode == models.SyncConflictReport || !at.After(last.At) {
+				conflict.Winner = "server"
+				clearSyncField(&apply, field)
+			}
+			result.Conflicts = append(result.Conflicts, conflict)
+			if conflict.Winner == "server" {
+				continue
+			}
+		}
+		changed = true
+	}
+
+	result.Status = models.SyncStatusUpdated
+	for _, conflict := range result.Conflicts {
+		if conflict.Winner == "server" {
+			result.Status = models.SyncStatusConflict
+		}
+	}
+	result.Item = item
+
+	if !changed {
+		return nil
+	}
+	if err := applyTrackItemUpdate(item, &apply); err != nil {
+		return err
+	}
+	item.ChangedAt = at
+	if err := repo.Update(ctx, item); err != nil {
+		return fmt.Errorf("failed to update track item: %w", err)
+	}
+	return s.events.trackItemChanged(ctx, repo, models.EventTrackItemUpdated, item)
+}
+
+// applySyncChangeToMissing applies a client change to an item the server does
+// not have: one created offline, or one deleted on the server
+func (s *TrackItemService) applySyncChangeToMissing(ctx context.Context, repo *repository.TrackItemRepository, userID int, change *models.SyncChange, at time.Time, result *models.SyncChangeResult) error {
+	_, err := repo.FindTombstone(ctx, userID, change.ID, change.ClientID)
+	if err != nil && !errors.Is(err, repository.ErrTombstoneNotFound) {
+		return err
+	}
+	deleted := err == nil
+
+	switch {
+	case change.Op == models.SyncOpDelete:
+		// Already gone
+		result.Status = models.SyncStatusDeleted
+		return nil
+	case deleted:
+		result.Status = models.SyncStatusConflict
+		result.Deleted = true
+		return nil
+	case change.ClientID == "":
+		return repository.ErrTrackItemNotFound
+	}
+
+	fields := &change.Fields
+	req := &models.CreateTrackItemRequest{}
+	if fields.Type != nil {
+		req.Type = *fields.Type
+	}
+	if fields.EmergencyCall != nil {
+		req.EmergencyCall = *fields.EmergencyCall
+	}
+	if fields.HolidayCall != nil {
+		req.HolidayCall = *fields.HolidayCall
+	}
+	if fields.WorkingHours != nil {
+		req.WorkingHours = *fields.WorkingHours
+	}
+	if fields.WorkingShifts != nil {
+		req.WorkingShifts = *fields.WorkingShifts
+	}
+	if fields.Date != nil {
+		req.Date = *fields.Date
+	}
+
+	item, err := newTrackItem(userID, req)
+	if err != nil {
+		return prefixFields(err, "fields.")
+	}
+	item.ClientID = change.ClientID
+	item.ChangedAt = at
+	if err := repo.Create(ctx, item); err != nil {
+		return fmt.Errorf("failed to create track item: %w", err)
+	}
+
+	result.ID = item.ID
+	result.Status = models.SyncStatusCreated
+	result.Item = item
+	return s.events.trackItemChanged(ctx, repo, models.EventTrackItemCreated, item)
+}
+
+// findSyncItem loads the user's item a client change refers to, by client ID
+// when it is set
+func findSyncItem(ctx context.Context, repo *repository.TrackItemRepository, userID int, change *models.SyncChange) (*models.TrackItem, error) {
+	if change.ClientID != "" {
+		return repo.FindByClientID(ctx, userID, change.ClientID)
+	}
+	return findOwnedTrackItem(ctx, repo, userID, change.ID)
+}
+
+// lastChange returns when any field of item was last changed
+func lastChange(item *models.TrackItem) time.Time {
+	var last time.Time
+	for _, change := range item.FieldClock {
+		if change.At.After(last) {
+			last = change.At
+		}
+	}
+	return last
+}
+
+// syncFieldSet reports whether an update request sets a field
+func syncFieldSet(req *models.UpdateTrackItemRequest, field string) bool {
+	switch field {
+	case models.FieldType:
+		return req.Type != nil
+	case models.FieldEmergencyCall:
+		return req.EmergencyCall != nil
+	case models.FieldHolidayCall:
+		return req.HolidayCall != nil
+	case models.FieldWorkingHours:
+		return req.WorkingHours != nil
+	case models.FieldWorkingShifts:
+		return req.WorkingShifts != nil
+	case models.FieldDate:
+		return req.Date != nil
+	}
+	return false
+}
+
+// clearSyncField removes a field from an update request
+func clearSyncField(req *models.UpdateTrackItemRequest, field string) {
+	switch field {
+	case models.FieldType:
+		req.Type = nil
+	case models.FieldEmergencyCall:
+		req.EmergencyCall = nil
+	case models.FieldHolidayCall:
+		req.HolidayCall = nil
+	case models.FieldWorkingHours:
+		req.WorkingHours = nil
+	case models.FieldWorkingShifts:
+		req.WorkingShifts = nil
+	case models.FieldDate:
+		req.Date = nil
+	}
+}
+
+// encodeSyncCursor returns an opaque cursor pointing just after version
+func encodeSyncCursor(version int64) string {
+	data, _ := json.Marshal(syncCursor{Version: version})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSyncCursor parses a cursor produced by encodeSyncCursor
+func decodeSyncCursor(value string) (*syncCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor syncCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
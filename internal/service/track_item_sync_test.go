@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestPushChangesConflicts(t *testing.T) {
+	// The item is created at created; server and client changes are dated
+	// relative to it
+	created := time.Now().UTC().Truncate(time.Second).Add(-3 * time.Hour)
+	earlier := created.Add(time.Hour)
+	later := created.Add(2 * time.Hour)
+
+	tests := []struct {
+		name string
+		// Changes made on the server, by another client, after the client's
+		// base version
+		server []models.SyncChange
+		mode   string
+		change models.SyncChange
+
+		wantStatus    string
+		wantDeleted   bool
+		wantConflicts map[string]string // Conflicting fields and their winners
+		want          *models.TrackItem // Fields of the item afterwards, nil when it is deleted
+	}{
+		{
+			name:       "no concurrent change",
+			change:     models.SyncChange{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}},
+			wantStatus: models.SyncStatusUpdated,
+			want:       &models.TrackItem{Type: "regular", WorkingHours: 10, WorkingShifts: 1},
+		},
+		{
+			name:       "concurrent edits of different fields",
+			server:     []models.SyncChange{{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}}},
+			change:     models.SyncChange{Op: models.SyncOpUpsert, ChangedAt: earlier, Fields: models.UpdateTrackItemRequest{Type: ptr("on-call"), HolidayCall: ptr(true)}},
+			wantStatus: models.SyncStatusUpdated,
+			want:       &models.TrackItem{Type: "on-call", HolidayCall: true, WorkingHours: 10, WorkingShifts: 1},
+		},
+		{
+			name:          "concurrent edits of a field, client change later",
+			server:        []models.SyncChange{{Op: models.SyncOpUpsert, ChangedAt: earlier, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}}},
+			change:        models.SyncChange{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(12.0)}},
+			wantStatus:    models.SyncStatusUpdated,
+			wantConflicts: map[string]string{models.FieldWorkingHours: "client"},
+			want:          &models.TrackItem{Type: "regular", WorkingHours: 12, WorkingShifts: 1},
+		},
+		{
+			name:          "concurrent edits of a field, server change later",
+			server:        []models.SyncChange{{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}}},
+			change:        models.SyncChange{Op: models.SyncOpUpsert, ChangedAt: earlier, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(12.0)}},
+			wantStatus:    models.SyncStatusConflict,
+			wantConflicts: map[string]string{models.FieldWorkingHours: "server"},
+			want:          &models.TrackItem{Type: "regular", WorkingHours: 10, WorkingShifts: 1},
+		},
+		{
+			name:          "concurrent edits of a field at the same time",
+			server:        []models.SyncChange{{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}}},
+			change:        models.SyncChange{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(12.0)}},
+			wantStatus:    models.SyncStatusConflict,
+			wantConflicts: map[string]string{models.FieldWorkingHours: "server"},
+			want:          &models.TrackItem{Type: "regular", WorkingHours: 10, WorkingShifts: 1},
+		},
+		{
+			name:          "concurrent edits of a field, reported",
+			server:        []models.SyncChange{{Op: models.SyncOpUpsert, ChangedAt: earlier, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}}},
+			mode:          models.SyncConflictReport,
+			change:        models.SyncChange{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(12.0), Type: ptr("on-call")}},
+			wantStatus:    models.SyncStatusConflict,
+			wantConflicts: map[string]string{models.FieldWorkingHours: "server"},
+			want:          &models.TrackItem{Type: "on-call", WorkingHours: 10, WorkingShifts: 1},
+		},
+		{
+			name:       "concurrent edits to the same value",
+			server:     []models.SyncChange{{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}}},
+			change:     models.SyncChange{Op: models.SyncOpUpsert, ChangedAt: earlier, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}},
+			wantStatus: models.SyncStatusUpdated,
+			want:       &models.TrackItem{Type: "regular", WorkingHours: 10, WorkingShifts: 1},
+		},
+		{
+			name: "field edited twice on the server",
+			server: []models.SyncChange{
+				{Op: models.SyncOpUpsert, ChangedAt: earlier, Fields: models.UpdateTrackItemRequest{WorkingShifts: ptr(0.5)}},
+				{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingShifts: ptr(2.0)}},
+			},
+			change:        models.SyncChange{Op: models.SyncOpUpsert, ChangedAt: earlier.Add(time.Minute), Fields: models.UpdateTrackItemRequest{WorkingShifts: ptr(1.5)}},
+			wantStatus:    models.SyncStatusConflict,
+			wantConflicts: map[string]string{models.FieldWorkingShifts: "server"},
+			want:          &models.TrackItem{Type: "regular", WorkingHours: 8, WorkingShifts: 2},
+		},
+		{
+			name:       "deleting an item not edited since",
+			change:     models.SyncChange{Op: models.SyncOpDelete, ChangedAt: earlier},
+			wantStatus: models.SyncStatusDeleted,
+		},
+		{
+			name:       "deleting an item edited earlier",
+			server:     []models.SyncChange{{Op: models.SyncOpUpsert, ChangedAt: earlier, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}}},
+			change:     models.SyncChange{Op: models.SyncOpDelete, ChangedAt: later},
+			wantStatus: models.SyncStatusDeleted,
+		},
+		{
+			name:       "deleting an item edited later",
+			server:     []models.SyncChange{{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}}},
+			change:     models.SyncChange{Op: models.SyncOpDelete, ChangedAt: earlier},
+			wantStatus: models.SyncStatusConflict,
+			want:       &models.TrackItem{Type: "regular", WorkingHours: 10, WorkingShifts: 1},
+		},
+		{
+			name:       "deleting an edited item, reported",
+			server:     []models.SyncChange{{Op: models.SyncOpUpsert, ChangedAt: earlier, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}}},
+			mode:       models.SyncConflictReport,
+			change:     models.SyncChange{Op: models.SyncOpDelete, ChangedAt: later},
+			wantStatus: models.SyncStatusConflict,
+			want:       &models.TrackItem{Type: "regular", WorkingHours: 10, WorkingShifts: 1},
+		},
+		{
+			name:        "editing an item deleted on the server",
+			server:      []models.SyncChange{{Op: models.SyncOpDelete, ChangedAt: earlier}},
+			change:      models.SyncChange{Op: models.SyncOpUpsert, ChangedAt: later, Fields: models.UpdateTrackItemRequest{WorkingHours: ptr(10.0)}},
+			wantStatus:  models.SyncStatusConflict,
+			wantDeleted: true,
+		},
+		{
+			name:       "deleting an item deleted on the server",
+			server:     []models.SyncChange{{Op: models.SyncOpDelete, ChangedAt: earlier}},
+			change:     models.SyncChange{Op: models.SyncOpDelete, ChangedAt: later},
+			wantStatus: models.SyncStatusDeleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newTestDB(t)
+			s := newTestTrackItemService(db, newTestWebhookService(db, localWebhookConfig))
+			user := newTestUser(t, db, "anna", models.RoleUser)
+
+			base := pushSyncChange(t, s, user.ID, "", models.SyncChange{
+				ClientID:  "item-1",
+				Op:        models.SyncOpUpsert,
+				ChangedAt: created,
+				Fields:    models.UpdateTrackItemRequest{Type: ptr("regular"), WorkingHours: ptr(8.0), WorkingShifts: ptr(1.0), Date: ptr("2024-03-01T09:00:00Z")},
+			})
+			if base.Status != models.SyncStatusCreated {
+				t.Fatalf("creating the item: got status %q, want %q", base.Status, models.SyncStatusCreated)
+			}
+
+			version := base.Item.Version
+			for _, change := range tt.server {
+				change.ClientID = "item-1"
+				change.BaseVersion = version
+				result := pushSyncChange(t, s, user.ID, "", change)
+				if result.Item != nil {
+					version = result.Item.Version
+				}
+			}
+
+			change := tt.change
+			change.ClientID = "item-1"
+			change.BaseVersion = base.Item.Version
+			result := pushSyncChange(t, s, user.ID, tt.mode, change)
+
+			if result.Status != tt.wantStatus || result.Deleted != tt.wantDeleted {
+				t.Errorf("got status %q, deleted %v, want %q, deleted %v", result.Status, result.Deleted, tt.wantStatus, tt.wantDeleted)
+			}
+			conflicts := make(map[string]string)
+			for _, conflict := range result.Conflicts {
+				conflicts[conflict.Field] = conflict.Winner
+			}
+			if len(conflicts) != len(tt.wantConflicts) {
+				t.Errorf("got conflicts %v, want %v", conflicts, tt.wantConflicts)
+			}
+			for field, winner := range tt.wantConflicts {
+				if conflicts[field] != winner {
+					t.Errorf("got conflicts %v, want %v", conflicts, tt.wantConflicts)
+				}
+			}
+
+			item, err := repository.NewTrackItemRepository(db).FindByClientID(ctx, user.ID, "item-1")
+			if tt.want == nil {
+				if !errors.Is(err, repository.ErrTrackItemNotFound) {
+					t.Errorf("got item %+v, error %v, want it deleted", item, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Type != tt.want.Type || item.EmergencyCall != tt.want.EmergencyCall || item.HolidayCall != tt.want.HolidayCall ||
+				item.WorkingHours != tt.want.WorkingHours || item.WorkingShifts != tt.want.WorkingShifts {
+				t.Errorf("got item %s %v/%v %vh %v shifts, want %s %v/%v %vh %v shifts",
+					item.Type, item.EmergencyCall, item.HolidayCall, item.WorkingHours, item.WorkingShifts,
+					tt.want.Type, tt.want.EmergencyCall, tt.want.HolidayCall, tt.want.WorkingHours, tt.want.WorkingShifts)
+			}
+		})
+	}
+}
+
+func TestPushChangesSameClientID(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	s := newTestTrackItemService(db, newTestWebhookService(db, localWebhookConfig))
+	user := newTestUser(t, db, "anna", models.RoleUser)
+
+	create := models.SyncChange{
+		ClientID: "item-1",
+		Op:       models.SyncOpUpsert,
+		Fields:   models.UpdateTrackItemRequest{Type: ptr("regular"), WorkingHours: ptr(8.0), Date: ptr("2024-03-01T09:00:00Z")},
+	}
+
+	// A retried request and a change listed twice update the item created first
+	results, err := s.PushChanges(ctx, user.ID, &models.SyncRequest{Changes: []models.SyncChange{create, create}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retried := pushSyncChange(t, s, user.ID, "", create)
+
+	results = append(results, retried)
+	wantStatuses := []string{models.SyncStatusCreated, models.SyncStatusUpdated, models.SyncStatusUpdated}
+	for i, result := range results {
+		if result.Status != wantStatuses[i] || result.ID != results[0].ID || len(result.Conflicts) != 0 {
+			t.Errorf("push %d: got %+v, want status %q for item %d without conflicts", i, result, wantStatuses[i], results[0].ID)
+		}
+	}
+	if retried.Item.Version != results[0].Item.Version {
+		t.Errorf("the retry changed the item from version %d to %d", results[0].Item.Version, retried.Item.Version)
+	}
+
+	page, err := s.ListTrackItems(ctx, user.ID, &models.ListTrackItemsQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("got %d items, want the client ID to create one", len(page.Items))
+	}
+}
+
+// pushSyncChange pushes a single change and returns its result, failing the
+// test when the change could not be applied
+func pushSyncChange(t *testing.T, s *TrackItemService, userID int, mode string, change models.SyncChange) models.SyncChangeResult {
+	t.Helper()
+
+	results, err := s.PushChanges(context.Background(), userID, &models.SyncRequest{Conflict: mode, Changes: []models.SyncChange{change}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil {
+		t.Fatalf("change %+v failed: %v", change, results[0].Err)
+	}
+	return results[0]
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+)
+
+var (
+	// ErrInvalidRole is returned for a role that is not one of the user roles
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrOwnAccount is returned when admins would lock themselves out
+	ErrOwnAccount = errors.New("admins cannot disable, delete or demote their own account")
+)
+
+// UserAdminService manages the accounts of other users. Requests with an
+// actor are only allowed for admins; without one they come from the command
+// line. Every change, and every user listed or viewed by an admin, is written
+// to the audit log with its actor.
+type UserAdminService struct {
+	userRepo   *repository.UserRepository
+	exportRepo *repository.DataExportRepository
+	auditRepo  *repository.AuditRepository
+}
+
+// NewUserAdminService creates a new user administration service
+func NewUserAdminService(userRepo *repository.UserRepository, exportRepo *repository.DataExportRepository, auditRepo *repository.AuditRepository) *UserAdminService {
+	return &UserAdminService{
+		userRepo:   userRepo,
+		exportRepo: exportRepo,
+		auditRepo:  auditRepo,
+	}
+}
+
+// ListUsers retrieves a page of users matching the query, ordered by ID
+func (s *UserAdminService) ListUsers(ctx context.Context, actorID *int, req *models.ListUsersQuery) (*models.UserPage, error) {
+	ctx, span := tracer.Start(ctx, "UserAdminService.ListUsers")
+	defer span.End()
+
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	q, err := parseUsersQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to find out whether another page exists
+	q.Limit++
+	users, err := s.userRepo.FindPage(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	page := &models.UserPage{Items: users}
+	if page.Items == nil {
+		page.Items = []models.User{}
+	}
+	if len(users) == q.Limit {
+		page.Items = users[:q.Limit-1]
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(page.Items[len(page.Items)-1].ID)))
+	}
+
+	if page.Total, err = s.userRepo.Count(ctx, q); err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+	if err := s.audit(ctx, actorID, nil, models.AuditUsersListed, usersQueryDetails(q)); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// GetUser retrieves a user by ID
+func (s *UserAdminService) GetUser(ctx context.Context, actorID *int, userID int) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserAdminService.GetUser")
+	defer span.End()
+
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, actorID, &userID, models.AuditUserViewed, ""); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByLogin retrieves a user by login
+func (s *UserAdminService) GetUserByLogin(ctx context.Context, actorID *int, login string) (*models.User, error) {
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.FindByLogin(ctx, login)
+}
+
+// CreateUser creates an account with a role
+func (s *UserAdminService) CreateUser(ctx context.Context, actorID *int, req *models.UserRegistration, role string) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserAdminService.CreateUser")
+	defer span.End()
+
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	if !models.ValidRole(role) {
+		return nil, invalidField(ErrInvalidRole, "role", models.FieldInvalidValue, "role must be user, supervisor or admin")
+	}
+	user, err := newUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	user.Role = role
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrUserAlreadyExists) {
+			return nil, ErrEmailAlreadyExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := s.audit(ctx, actorID, &user.ID, models.AuditUserCreated, "role "+role); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResetPassword replaces the password of a user and revokes the user's
+// tokens. Without a password a temporary one is generated and returned, for
+// the admin to pass on; the user has to change it before logging in.
+func (s *UserAdminService) ResetPassword(ctx context.Context, actorID *int, userID int, password string) (string, error) {
+	ctx, span := tracer.Start(ctx, "UserAdminService.ResetPassword")
+	defer span.End()
+
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return "", err
+	}
+
+	generated := ""
+	if password == "" {
+		generated = rand.Text()
+		password = generated
+	}
+	if err := validatePassword("password", password); err != nil {
+		return "", err
+	}
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, userID, hashedPassword, generated != ""); err != nil {
+		return "", err
+	}
+	details := ""
+	if generated != "" {
+		details = "temporary password"
+	}
+	if err := s.audit(ctx, actorID, &userID, models.AuditPasswordReset, details); err != nil {
+		return "", err
+	}
+
+	return generated, nil
+}
+
+// SetRole changes the role of a user. The tokens issued to the user before
+// are revoked, so a demoted admin cannot go on with an impersonation.
+func (s *UserAdminService) SetRole(ctx context.Context, actorID *int, userID int, role string) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserAdminService.SetRole")
+	defer span.End()
+
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	if !models.ValidRole(role) {
+		return nil, invalidField(ErrInvalidRole, "role", models.FieldInvalidValue, "role must be user, supervisor or admin")
+	}
+	if isActor(actorID, userID) && role != models.RoleAdmin {
+		return nil, ErrOwnAccount
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, actorID, &userID, models.AuditRoleChanged, fmt.Sprintf("role %s to %s", user.Role, role)); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	user.TokenVersion++
+
+	return user, nil
+}
+
+// SetDisabled disables a user, who can then no longer log in or use issued
+// tokens, or enables the user again
+func (s *UserAdminService) SetDisabled(ctx context.Context, actorID *int, userID int, disabled bool) (*models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserAdminService.SetDisabled")
+	defer span.End()
+
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return nil, err
+	}
+	if isActor(actorID, userID) && disabled {
+		return nil, ErrOwnAccount
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDisabled() == disabled {
+		return user, nil
+	}
+
+	var at *time.Time
+	action := models.AuditUserEnabled
+	if disabled {
+		now := time.Now().UTC()
+		at = &now
+		action = models.AuditUserDisabled
+	}
+	if err := s.userRepo.SetDisabledAt(ctx, userID, at); err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, actorID, &userID, action, ""); err != nil {
+		return nil, err
+	}
+	user.DisabledAt = at
+
+	return user, nil
+}
+
+// DeleteUser deletes a user and all their data right away. The audit entry
+// names the user instead of referencing the account, which would delete it
+// along with the user's own entries.
+func (s *UserAdminService) DeleteUser(ctx context.Context, actorID *int, userID int) error {
+	ctx, span := tracer.Start(ctx, "UserAdminService.DeleteUser")
+	defer span.End()
+
+	if err := s.requireAdmin(ctx, actorID); err != nil {
+		return err
+	}
+	if isActor(actorID, userID) {
+		return ErrOwnAccount
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := deleteAccount(ctx, s.userRepo, s.exportRepo, userID); err != nil {
+		return err
+	}
+
+	return s.audit(ctx, actorID, nil, models.AuditUserDeleted, fmt.Sprintf("user %d (%s)", user.ID, user.Login))
+}
+
+// requireAdmin returns ErrUnauthorized unless the actor is an admin.
+// Requests without an actor come from the command line and are allowed.
+func (s *UserAdminService) requireAdmin(ctx context.Context, actorID *int) error {
+	if actorID == nil {
+		return nil
+	}
+
+	actor, err := s.userRepo.FindByID(ctx, *actorID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if actor.Role != models.RoleAdmin {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// audit records an action on a user's account
+func (s *UserAdminService) audit(ctx context.Context, actorID, userID *int, action, details string) error {
+	return s.auditRepo.Create(ctx, &models.AuditEntry{
+		UserID:  userID,
+		ActorID: actorID,
+		Action:  action,
+		Details: details,
+	})
+}
+
+// isActor reports whether userID is the actor's own account
+func isActor(actorID *int, userID int) bool {
+	return actorID != nil && *actorID == userID
+}
+
+// usersQueryDetails describes the filters of a user list for the audit log
+func usersQueryDetails(q *repository.UserQuery) string {
+	var filters []string
+	if q.Search != "" {
+		filters = append(filters, "q "+strconv.Quote(q.Search))
+	}
+	if q.Role != "" {
+		filters = append(filters, "role "+q.Role)
+	}
+	if q.Disabled != nil {
+		filters = append(filters, "disabled "+strconv.FormatBool(*q.Disabled))
+	}
+	if q.AfterID > 0 {
+		filters = append(filters, "after user "+strconv.Itoa(q.AfterID))
+	}
+
+	return strings.Join(filters, ", ")
+}
+
+// parseUsersQuery validates the raw query parameters of the user list and
+// converts them into a repository query
+func parseUsersQuery(req *models.ListUsersQuery) (*repository.UserQuery, error) {
+	q := &repository.UserQuery{Search: req.Search, Role: req.Role}
+	v := newValidation(ErrInvalidListQuery)
+
+	if q.Role != "" && !models.ValidRole(q.Role) {
+		v.add("role", models.FieldInvalidValue, "role must be user, supervisor or admin")
+	}
+	q.Disabled = parseOptionalBool(v, "disabled", req.Disabled)
+	q.Limit = parseLimit(v, req.Limit, defaultPageSize, maxPageSize)
+
+	if req.Cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(req.Cursor)
+		if err == nil {
+			q.AfterID, err = strconv.Atoi(string(data))
+		}
+		if err != nil || q.AfterID < 1 {
+			v.add("cursor", models.FieldInvalidValue, "invalid cursor")
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/util"
+)
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	admins := NewUserAdminService(userRepo, repository.NewDataExportRepository(db), repository.NewAuditRepository(db))
+	users := NewUserService(userRepo)
+	auth := NewAuthService(userRepo, "secret")
+
+	admin := newTestUser(t, db, "admin", models.RoleAdmin)
+	if _, err := auth.Register(ctx, &models.UserRegistration{FirstName: "Anna", LastName: "Schmidt", Login: "anna", Password: "old password"}); err != nil {
+		t.Fatal(err)
+	}
+	login, err := auth.Login(ctx, &models.UserLogin{Login: "anna", Password: "old password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := login.User.ID
+	oldVersion := tokenVersion(t, login.Token)
+
+	temporary, err := admins.ResetPassword(ctx, &admin.ID, userID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if temporary == "" {
+		t.Fatal("no temporary password was generated")
+	}
+
+	// Tokens issued before the reset are revoked
+	if _, err := users.AccountActive(ctx, userID, oldVersion); !errors.Is(err, util.ErrRevokedToken) {
+		t.Errorf("old token: got error %v, want %v", err, util.ErrRevokedToken)
+	}
+
+	// The temporary password only lets the user change it
+	if _, err := auth.Login(ctx, &models.UserLogin{Login: "anna", Password: "old password"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("login with the old password: got error %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := auth.Login(ctx, &models.UserLogin{Login: "anna", Password: temporary}); !errors.Is(err, ErrPasswordChangeRequired) {
+		t.Errorf("login with the temporary password: got error %v, want %v", err, ErrPasswordChangeRequired)
+	}
+	if _, err := auth.ChangePassword(ctx, &models.PasswordChange{Login: "anna", Password: temporary, NewPassword: temporary}); !errors.Is(err, ErrValidation) {
+		t.Errorf("keeping the temporary password: got error %v, want %v", err, ErrValidation)
+	}
+
+	changed, err := auth.ChangePassword(ctx, &models.PasswordChange{Login: "anna", Password: temporary, NewPassword: "new password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.User.PasswordChangeRequired {
+		t.Error("a password change is still required after the change")
+	}
+	if active, err := users.AccountActive(ctx, userID, tokenVersion(t, changed.Token)); err != nil || !active {
+		t.Errorf("token after the change: got %v, %v, want it accepted", active, err)
+	}
+	if _, err := auth.Login(ctx, &models.UserLogin{Login: "anna", Password: "new password"}); err != nil {
+		t.Errorf("login with the new password: %v", err)
+	}
+
+	// A password set by the admin does not have to be changed
+	if _, err := admins.ResetPassword(ctx, &admin.ID, userID, "set by admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.AccountActive(ctx, userID, tokenVersion(t, changed.Token)); !errors.Is(err, util.ErrRevokedToken) {
+		t.Errorf("token before the second reset: got error %v, want %v", err, util.ErrRevokedToken)
+	}
+	if _, err := auth.Login(ctx, &models.UserLogin{Login: "anna", Password: "set by admin"}); err != nil {
+		t.Errorf("login with the password set by the admin: %v", err)
+	}
+}
+
+func tokenVersion(t *testing.T, token string) int {
+	t.Helper()
+
+	claims, err := util.ValidateToken(token, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims.TokenVersion
+}
+
+func TestSetRoleRevokesImpersonation(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	admins := NewUserAdminService(userRepo, repository.NewDataExportRepository(db), repository.NewAuditRepository(db))
+	users := NewUserService(userRepo)
+	impersonations := NewImpersonationService(userRepo, repository.NewAuditRepository(db), "secret", time.Hour)
+
+	admin := newTestUser(t, db, "admin", models.RoleAdmin)
+	demoted := newTestUser(t, db, "demoted", models.RoleAdmin)
+	user := newTestUser(t, db, "user", models.RoleUser)
+
+	session, err := impersonations.Impersonate(ctx, demoted.ID, user.ID, &models.ImpersonationRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := util.ValidateToken(session.Token, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active, err := users.AccountActive(ctx, claims.ActorID, claims.ActorTokenVersion); err != nil || !active {
+		t.Fatalf("actor before the role change: got %v, %v, want it accepted", active, err)
+	}
+
+	if _, err := admins.SetRole(ctx, &admin.ID, demoted.ID, models.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+
+	// The impersonation token names the demoted admin at the old token version
+	if _, err := users.AccountActive(ctx, claims.ActorID, claims.ActorTokenVersion); !errors.Is(err, util.ErrRevokedToken) {
+		t.Errorf("actor after the role change: got error %v, want %v", err, util.ErrRevokedToken)
+	}
+	if active, err := users.AccountActive(ctx, claims.UserID, claims.TokenVersion); err != nil || !active {
+		t.Errorf("impersonated user: got %v, %v, want it accepted", active, err)
+	}
+}
+
+func TestAdminReadsAreAudited(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	admins := NewUserAdminService(userRepo, repository.NewDataExportRepository(db), auditRepo)
+
+	admin := newTestUser(t, db, "admin", models.RoleAdmin)
+	user := newTestUser(t, db, "user", models.RoleUser)
+
+	if _, err := admins.ListUsers(ctx, &admin.ID, &models.ListUsersQuery{Search: "us", Role: models.RoleUser, Disabled: "false"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admins.GetUser(ctx, &admin.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Failed reads are not recorded
+	if _, err := admins.GetUser(ctx, &user.ID, admin.ID); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("got error %v for a non-admin, want %v", err, ErrUnauthorized)
+	}
+	if _, err := admins.GetUser(ctx, &admin.ID, 999); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("got error %v for an unknown user, want %v", err, repository.ErrUserNotFound)
+	}
+
+	var actorID int
+	var action, details string
+	err := db.QueryRowContext(ctx, "SELECT actor_id, action, details FROM audit_log WHERE user_id IS NULL").
+		Scan(&actorID, &action, &details)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actorID != admin.ID || action != models.AuditUsersListed || details != `q "us", role user, disabled false` {
+		t.Errorf("got list entry by %d: %s %q", actorID, action, details)
+	}
+
+	entries, err := auditRepo.FindByUserID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Action != models.AuditUserViewed || entries[0].ActorID == nil || *entries[0].ActorID != admin.ID {
+		t.Errorf("got entries %+v about the user, want one view by the admin", entries)
+	}
+	if entries, err := auditRepo.FindByUserID(ctx, admin.ID); err != nil || len(entries) != 0 {
+		t.Errorf("got entries %+v, %v about the admin, want none", entries, err)
+	}
+}
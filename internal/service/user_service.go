@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/util"
+)
+
+// UserService handles user profile business logic
+type UserService struct {
+	userRepo *repository.UserRepository
+}
+
+// NewUserService creates a new user service
+func NewUserService(userRepo *repository.UserRepository) *UserService {
+	return &UserService{
+		userRepo: userRepo,
+	}
+}
+
+// GetProfile retrieves the profile of a user
+func (s *UserService) GetProfile(ctx context.Context, userID int) (*models.User, error) {
+	return s.userRepo.FindByID(ctx, userID)
+}
+
+// AccountActive reports whether a user still exists and is not disabled, so
+// that tokens issued to the user are accepted. A token issued at another
+// token version, before the password was reset, is rejected with
+// util.ErrRevokedToken.
+func (s *UserService) AccountActive(ctx context.Context, userID, tokenVersion int) (bool, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if user.TokenVersion != tokenVersion {
+		return false, util.ErrRevokedToken
+	}
+
+	return !user.IsDisabled(), nil
+}
+
+// UpdateProfile updates the profile fields of a user
+func (s *UserService) UpdateProfile(ctx context.Context, userID int, req *models.UpdateProfileRequest) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update fields if provided
+	if req.FirstName != nil {
+		user.FirstName = *req.FirstName
+	}
+	if req.LastName != nil {
+		user.LastName = *req.LastName
+	}
+	if req.Avatar != nil {
+		user.Avatar = *req.Avatar
+	}
+	if req.TimeZone != nil {
+		user.TimeZone = *req.TimeZone
+	}
+
+	v := newValidation(ErrValidation)
+	if user.FirstName == "" {
+		v.add("first_name", models.FieldRequired, "first name is required")
+	}
+	if user.LastName == "" {
+		v.add("last_name", models.FieldRequired, "last name is required")
+	}
+	if _, err := loadLocation(user.TimeZone); err != nil {
+		v.add("time_zone", models.FieldInvalidValue, err.Error())
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,544 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	mrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+	"github.com/sergey/work-track-backend/internal/models"
+	"github.com/sergey/work-track-backend/internal/repository"
+	"github.com/sergey/work-track-backend/internal/util"
+)
+
+var (
+	ErrInvalidWebhook     = errors.New("invalid webhook")
+	ErrDeliveryNotRetried = errors.New("only delivered or dead deliveries can be retried")
+)
+
+const (
+	// webhookPollInterval is how often the dispatcher looks for due deliveries
+	// when it is not woken up by a new event
+	webhookPollInterval = 15 * time.Second
+	// webhookBatchSize is the number of deliveries attempted per round
+	webhookBatchSize = 50
+	// webhookConcurrency bounds the number of requests in flight, so one slow
+	// endpoint does not hold up the others
+	webhookConcurrency = 4
+	// webhookMaxAttempts is the number of attempts before a delivery is dead.
+	// With the backoff below the last attempt is made about 4 hours after the first.
+	webhookMaxAttempts = 10
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+
+	// Deliveries listed by the delivery log
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// Signature headers sent with every delivery
+const (
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderID        = "X-Webhook-ID"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// WebhookService manages webhooks and delivers their events. Events are queued
+// in the outbox by the services that cause them and sent by Run.
+type WebhookService struct {
+	repo     *repository.WebhookRepository
+	userRepo *repository.UserRepository
+	client   *http.Client
+	cfg      config.WebhookConfig
+	wake     chan struct{}
+}
+
+// NewWebhookService creates a new webhook service. Deliveries are sent with
+// client, see NewWebhookClient.
+func NewWebhookService(repo *repository.WebhookRepository, userRepo *repository.UserRepository, client *http.Client, cfg config.WebhookConfig) *WebhookService {
+	return &WebhookService{
+		repo:     repo,
+		userRepo: userRepo,
+		client:   client,
+		cfg:      cfg,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// NewWebhookClient returns an HTTP client for deliveries. Redirects are not
+// followed: a redirect counts as a failed attempt. Unless cfg allows private
+// endpoints, the client refuses to connect to addresses that are not public,
+// whatever the endpoint's host resolves to at the time.
+func NewWebhookClient(cfg config.WebhookConfig) *http.Client {
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if !cfg.AllowPrivate {
+		client.Transport = util.NewPublicTransport(cfg.Timeout)
+	}
+
+	return client
+}
+
+// SignWebhookPayload returns the signature of a delivery: the hex HMAC-SHA256,
+// keyed with the webhook secret, of the timestamp header, a dot and the body
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhook registers a webhook for a user. Only admins may register
+// webhooks that receive the events of all users.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID int, req *models.CreateWebhookRequest) (*models.WebhookResponse, error) {
+	if req.AllUsers {
+		user, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+		if user.Role != models.RoleAdmin {
+			return nil, ErrUnauthorized
+		}
+	}
+
+	hook := &models.Webhook{
+		UserID:      userID,
+		URL:         strings.TrimSpace(req.URL),
+		Events:      req.Events,
+		Description: strings.TrimSpace(req.Description),
+		AllUsers:    req.AllUsers,
+		Active:      true,
+	}
+	if err := s.validateWebhook(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	secret, err := newSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	hook.Secret = "whsec_" + secret
+
+	if err := s.repo.Create(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookResponse{Webhook: *hook, Secret: hook.Secret}, nil
+}
+
+// GetUserWebhooks retrieves all webhooks registered by a user
+func (s *WebhookService) GetUserWebhooks(ctx context.Context, userID int) ([]models.Webhook, error) {
+	hooks, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+
+	return hooks, nil
+}
+
+// GetWebhook retrieves a webhook, ensuring it belongs to the user
+func (s *WebhookService) GetWebhook(ctx context.Context, userID, webhookID int) (*models.Webhook, error) {
+	hook, err := s.repo.FindByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify ownership
+	if hook.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	return hook, nil
+}
+
+// UpdateWebhook updates a webhook, ensuring it belongs to the user
+func (s *WebhookService) UpdateWebhook(ctx context.Context, userID, webhookID int, req *models.UpdateWebhookRequest) (*models.Webhook, error) {
+	hook, err := s.GetWebhook(ctx, userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		hook.URL = strings.TrimSpace(*req.URL)
+	}
+	if req.Events != nil {
+		hook.Events = req.Events
+	} else {
+		// Keep webhooks updatable when one of their events was withdrawn
+		hook.Events = slices.DeleteFunc(hook.Events, func(event string) bool {
+			return !slices.Contains(models.WebhookEvents, event)
+		})
+	}
+	if req.Description != nil {
+		hook.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	if err := s.validateWebhook(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	// Deliveries held back while the webhook was inactive are due now
+	s.notify()
+
+	return hook, nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log, ensuring it belongs to the user
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userID, webhookID int) error {
+	if _, err := s.GetWebhook(ctx, userID, webhookID); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, webhookID)
+}
+
+// GetDeliveries retrieves the most recent deliveries of a webhook, optionally
+// limited to one status
+func (s *WebhookService) GetDeliveries(ctx context.Context, userID, webhookID int, status string, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	if status != "" && status != models.DeliveryPending && status != models.DeliveryDelivered && status != models.DeliveryDead {
+		return nil, invalidField(ErrInvalidWebhook, "status", models.FieldInvalidValue, fmt.Sprintf("invalid status %q, use pending, delivered or dead", status))
+	}
+	if limit <= 0 {
+		limit = defaultDeliveryLimit
+	}
+	if limit > maxDeliveryLimit {
+		limit = maxDeliveryLimit
+	}
+
+	deliveries, err := s.repo.FindDeliveries(ctx, webhookID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	return deliveries, nil
+}
+
+// RetryDelivery queues a dead or delivered event again, with a fresh set of attempts
+func (s *WebhookService) RetryDelivery(ctx context.Context, userID, webhookID, deliveryID int) (*models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.repo.FindDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.WebhookID != webhookID {
+		return nil, repository.ErrWebhookDeliveryNotFound
+	}
+	if delivery.Status == models.DeliveryPending {
+		return nil, ErrDeliveryNotRetried
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC().Truncate(time.Second)
+	delivery.DeliveredAt = nil
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	s.notify()
+
+	return delivery, nil
+}
+
+// validateWebhook checks the fields of a webhook before it is stored. The
+// URL's host must resolve to public addresses only, so that webhooks cannot
+// be used to reach the server's own network.
+func (s *WebhookService) validateWebhook(ctx context.Context, hook *models.Webhook) error {
+	v := newValidation(ErrInvalidWebhook)
+	u, err := url.Parse(hook.URL)
+	switch {
+	case err != nil || u.Host == "":
+		v.add("url", models.FieldInvalidFormat, "url must be an absolute URL")
+	case u.Scheme != "https" && !(s.cfg.AllowHTTP && u.Scheme == "http"):
+		v.add("url", models.FieldInvalidValue, "url must use https")
+	case u.User != nil:
+		v.add("url", models.FieldInvalidValue, "url must not contain credentials")
+	case !s.cfg.AllowPrivate:
+		err := util.CheckPublicHost(ctx, u.Hostname())
+		if errors.Is(err, util.ErrNonPublicAddress) {
+			v.add("url", models.FieldInvalidValue, "url must not point at a loopback, private or link-local address")
+		} else if err != nil {
+			v.add("url", models.FieldInvalidValue, "url host cannot be resolved")
+		}
+	}
+
+	if len(hook.Events) == 0 {
+		v.add("events", models.FieldRequired, "events are required")
+	}
+	events := make([]string, 0, len(hook.Events))
+	for i, event := range hook.Events {
+		if !slices.Contains(models.WebhookEvents, event) {
+			v.add(fmt.Sprintf("events[%d]", i), models.FieldInvalidValue, fmt.Sprintf("unknown event %q, use one of %s", event, strings.Join(models.WebhookEvents, ", ")))
+			continue
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	hook.Events = events
+
+	return v.err()
+}
+
+// record queues an event about a user for every webhook subscribed to it.
+// repo decides the transaction the outbox rows are written in; call notify
+// once it is committed.
+func (s *WebhookService) record(ctx context.Context, repo *repository.WebhookRepository, userID int, eventType string, data interface{}) error {
+	hooks, err := repo.FindSubscribed(ctx, userID, eventType)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	eventID, err := newEventID()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(&models.WebhookEvent{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		UserID:    userID,
+		Data:      raw,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	for _, hook := range hooks {
+		delivery := &models.WebhookDelivery{
+			WebhookID: hook.ID,
+			EventID:   eventID,
+			EventType: eventType,
+			Payload:   string(payload),
+			Status:    models.DeliveryPending,
+		}
+		if err := repo.CreateDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notify wakes the dispatcher up to send newly queued deliveries
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends queued deliveries until ctx is cancelled. Delivery is at least
+// once: an attempt interrupted by shutdown is made again on the next start.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches come back, so a backlog drains quickly
+		for {
+			n, err := s.DeliverDue(ctx)
+			if err != nil {
+				slog.Error("Webhook dispatcher failed", "err", err)
+			}
+			if n < webhookBatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// DeliverDue makes one attempt at every delivery that is due and records the
+// outcomes. It returns the number of deliveries attempted.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := s.repo.FindDueDeliveries(ctx, time.Now(), webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	hooks := make(map[int]*models.Webhook)
+	sem := make(chan struct{}, webhookConcurrency)
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		hook, ok := hooks[delivery.WebhookID]
+		if !ok {
+			if hook, err = s.repo.FindByID(ctx, delivery.WebhookID); err != nil {
+				// Deleted since the query; its deliveries went with it
+				continue
+			}
+			hooks[hook.ID] = hook
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			s.deliver(ctx, hook, delivery)
+		}()
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// deliver makes one attempt at a delivery and records the outcome, scheduling
+// the next attempt with exponential backoff or giving up after the last one
+func (s *WebhookService) deliver(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) {
+	statusCode, err := s.send(ctx, hook, delivery)
+	if ctx.Err() != nil {
+		// Shutting down; the attempt does not count
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	delivery.Attempts++
+	delivery.LastStatusCode = nil
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = deliveryError(statusCode, err)
+		slog.Warn("Webhook delivery is dead", "webhook_id", hook.ID, "delivery_id", delivery.ID, "attempts", delivery.Attempts, "err", err)
+	default:
+		delivery.LastError = deliveryError(statusCode, err)
+		slog.Debug("Webhook delivery failed", "webhook_id", hook.ID, "delivery_id", delivery.ID, "attempts", delivery.Attempts, "err", err)
+		delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts)).Truncate(time.Second)
+	}
+
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		slog.Error("Failed to update webhook delivery", "webhook_id", hook.ID, "delivery_id", delivery.ID, "err", err)
+	}
+}
+
+// send posts a delivery's payload, signed with the webhook secret. It returns
+// the response status, or 0 when no response was received.
+func (s *WebhookService) send(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "WorkTrack-Webhooks/1.0")
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderID, delivery.EventID)
+	req.Header.Set(WebhookHeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(hook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain a little of the body so the connection can be reused. The body
+	// itself is never stored: the delivery log must not reveal what an
+	// endpoint responds.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// deliveryError describes a failed attempt for the delivery log. Only the
+// status code or the kind of failure is reported; the details of network
+// errors, such as which addresses refused connections, stay in the server log.
+func deliveryError(statusCode int, err error) string {
+	var netErr net.Error
+	switch {
+	case statusCode != 0:
+		return fmt.Sprintf("unexpected status %d", statusCode)
+	case errors.Is(err, util.ErrNonPublicAddress):
+		return "endpoint address is not public"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return "request failed"
+	}
+}
+
+// webhookBackoff returns the delay after the given number of failed attempts:
+// doubling from webhookBaseBackoff up to webhookMaxBackoff, with 10% jitter so
+// retries of many deliveries to one endpoint spread out
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookMaxBackoff
+	if attempts < 20 {
+		delay = min(webhookBaseBackoff<<(attempts-1), webhookMaxBackoff)
+	}
+	jitter := time.Duration(mrand.Int64N(int64(delay)/10 + 1))
+	return delay + jitter
+}
+
+// newEventID returns a random identifier for an event, the same across all
+// deliveries of the event so receivers can deduplicate
+func newEventID() (string, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate event id: %w", err)
+	}
+	return "evt_" + hex.EncodeToString(id), nil
+}
@@ -0,0 +1,56 @@
+// Package storage keeps blobs, such as database backups, in an S3-compatible
+// bucket or a local directory
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/sergey/work-track-backend/internal/config"
+)
+
+// ErrNotFound is returned when no object exists under a key
+var ErrNotFound = errors.New("object not found")
+
+// Object describes a stored blob
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Store is a flat namespace of blobs addressed by slash-separated keys
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any object
+	// already there
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns the objects whose keys start with prefix, sorted by key
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Delete removes the object stored under key. Deleting a missing object
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+	// Ping verifies the store can be reached
+	Ping(ctx context.Context) error
+	// String describes the store for logs, e.g. "s3://bucket/prefix"
+	String() string
+}
+
+// New returns the S3 store described by s3 when it names a bucket, otherwise
+// a local store in dir. It returns nil when neither is configured.
+func New(s3 config.S3Config, dir string) (Store, error) {
+	if s3.Bucket != "" {
+		store, err := NewS3Store(s3)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	if dir != "" {
+		return NewLocalStore(dir), nil
+	}
+	return nil, nil
+}
@@ -3,6 +3,7 @@ package util
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,18 +12,33 @@ import (
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
-// Claims represents the JWT claims
+// Claims represents the JWT claims. Impersonation tokens also name the
+// admin acting as the user, whose subject is the user. The token versions
+// are those of the accounts when the token was issued; resetting a password
+// increments the version and so revokes the account's tokens.
 type Claims struct {
-	UserID int `json:"user_id"`
+	UserID            int  `json:"user_id"`
+	TokenVersion      int  `json:"token_version,omitempty"`
+	ActorID           int  `json:"actor_id,omitempty"`
+	ActorTokenVersion int  `json:"actor_token_version,omitempty"`
+	ReadOnly          bool `json:"read_only,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for a user
-func GenerateToken(userID int, secret string) (string, error) {
+// Impersonated reports whether the token was issued to an admin acting as the
+// user
+func (c *Claims) Impersonated() bool {
+	return c.ActorID != 0
+}
+
+// GenerateToken creates a new JWT token for a user at the user's token version
+func GenerateToken(userID, tokenVersion int, secret string) (string, error) {
 	claims := &Claims{
-		UserID: userID,
+		UserID:       userID,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -38,6 +54,33 @@ func GenerateToken(userID int, secret string) (string, error) {
 	return tokenString, nil
 }
 
+// GenerateImpersonationToken creates a JWT token that lets an admin act as a
+// user until it expires, or until the password of either is reset
+func GenerateImpersonationToken(actorID, actorTokenVersion, userID, tokenVersion int, readOnly bool, ttl time.Duration, secret string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := &Claims{
+		UserID:            userID,
+		TokenVersion:      tokenVersion,
+		ActorID:           actorID,
+		ActorTokenVersion: actorTokenVersion,
+		ReadOnly:          readOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userID),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenString, expiresAt, nil
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func ValidateToken(tokenString, secret string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
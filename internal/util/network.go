@@ -0,0 +1,84 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicAddress is returned for hosts that resolve to an address on the
+// server's own or a private network
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// nonPublicPrefixes are special-purpose ranges that netip.Addr does not
+// classify: "this network", carrier-grade NAT and benchmarking addresses
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// IsPublicAddr reports whether addr can be reached on the internet, as
+// opposed to loopback, private, link-local, multicast and unspecified
+// addresses, which reach the server itself or its network
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckPublicHost resolves host and returns ErrNonPublicAddress when any of
+// its addresses is not public. It rejects obvious cases early; PublicDialer
+// still checks the address actually connected to, since DNS answers change.
+func CheckPublicHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return fmt.Errorf("%s: %w", host, ErrNonPublicAddress)
+		}
+	}
+	return nil
+}
+
+// PublicDialer returns a dialer that refuses to connect to addresses that
+// are not public, so that URLs supplied by users cannot reach the server's
+// own or internal services
+func PublicDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%s: %w", addrPort.Addr(), ErrNonPublicAddress)
+			}
+			return nil
+		},
+	}
+}
+
+// NewPublicTransport returns an HTTP transport that only connects to public
+// addresses and ignores proxy settings, which would hide the real target
+func NewPublicTransport(dialTimeout time.Duration) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = PublicDialer(dialTimeout).DialContext
+	return transport
+}
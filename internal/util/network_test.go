@@ -0,0 +1,70 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // cloud metadata service
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"198.18.0.1", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
+
+func TestCheckPublicHost(t *testing.T) {
+	for _, host := range []string{"localhost", "127.0.0.1", "::1", "169.254.169.254"} {
+		if err := CheckPublicHost(context.Background(), host); !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("CheckPublicHost(%s) = %v, want %v", host, err, ErrNonPublicAddress)
+		}
+	}
+}
+
+func TestNewPublicTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the transport connected to a loopback address")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewPublicTransport(time.Second)}
+	_, err := client.Get(srv.URL)
+	if !errors.Is(err, ErrNonPublicAddress) {
+		t.Fatalf("got error %v, want %v", err, ErrNonPublicAddress)
+	}
+
+	// A host name that resolves to loopback is refused at dial time too
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	if _, err := client.Get("http://localhost:" + port); !errors.Is(err, ErrNonPublicAddress) {
+		t.Fatalf("got error %v, want %v", err, ErrNonPublicAddress)
+	}
+}
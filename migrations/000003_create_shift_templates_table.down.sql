@@ -0,0 +1,2 @@
+-- Drop shift_templates table
+DROP TABLE IF EXISTS shift_templates;
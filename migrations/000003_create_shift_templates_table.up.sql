@@ -0,0 +1,19 @@
+-- Create shift_templates table
+CREATE TABLE IF NOT EXISTS shift_templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    emergency_call BOOLEAN NOT NULL DEFAULT FALSE,
+    holiday_call BOOLEAN NOT NULL DEFAULT FALSE,
+    working_hours DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    working_shifts DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    start_time VARCHAR(5) NOT NULL DEFAULT '00:00',
+    recurrence VARCHAR(255) NOT NULL DEFAULT '',
+    anchor_date VARCHAR(10) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create index on user_id for faster lookups
+CREATE INDEX IF NOT EXISTS idx_shift_templates_user_id ON shift_templates(user_id);
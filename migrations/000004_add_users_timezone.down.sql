@@ -0,0 +1,2 @@
+-- Remove time zone setting from users
+ALTER TABLE users DROP COLUMN timezone;
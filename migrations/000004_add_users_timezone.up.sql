@@ -0,0 +1,2 @@
+-- Add time zone setting to users
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
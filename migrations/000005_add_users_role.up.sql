@@ -0,0 +1,2 @@
+-- Add role to users: "user", "supervisor" or "admin"
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
@@ -0,0 +1,2 @@
+-- Drop calendar_tokens table
+DROP TABLE IF EXISTS calendar_tokens;
@@ -0,0 +1,2 @@
+-- Remove deletion_scheduled_at from users
+ALTER TABLE users DROP COLUMN deletion_scheduled_at;
@@ -0,0 +1,2 @@
+-- Add deletion_scheduled_at to users: when set, the account is removed at that time
+ALTER TABLE users ADD COLUMN deletion_scheduled_at TIMESTAMP;
@@ -0,0 +1,18 @@
+-- Create webhooks table: HTTPS endpoints notified of events.
+-- The secret signs payloads, so it is stored as is rather than hashed.
+-- all_users webhooks (admin only) receive the events of every user.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url VARCHAR(2000) NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    events TEXT NOT NULL, -- Comma-separated event types
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    all_users BOOLEAN NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create index on user_id for faster lookups
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
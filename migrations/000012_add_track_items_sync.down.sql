@@ -0,0 +1,8 @@
+-- Remove sync tracking from track_items
+DROP TABLE IF EXISTS track_item_tombstones;
+DROP TABLE IF EXISTS sync_state;
+DROP INDEX IF EXISTS idx_track_items_user_version;
+DROP INDEX IF EXISTS idx_track_items_user_client_id;
+ALTER TABLE track_items DROP COLUMN field_clock;
+ALTER TABLE track_items DROP COLUMN version;
+ALTER TABLE track_items DROP COLUMN client_id;
@@ -0,0 +1,34 @@
+-- Track changes to track_items for offline clients. Every create, update and
+-- delete takes the next value of sync_state.version, so the versions of a
+-- user's items and tombstones form a cursor clients can sync from.
+ALTER TABLE track_items ADD COLUMN client_id VARCHAR(64);
+ALTER TABLE track_items ADD COLUMN version INTEGER NOT NULL DEFAULT 0;
+-- JSON object of field name to {"v": version, "at": time} of its last change
+ALTER TABLE track_items ADD COLUMN field_clock TEXT NOT NULL DEFAULT '{}';
+
+-- Give existing rows distinct versions so a full sync can be paginated
+UPDATE track_items SET version = id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_track_items_user_client_id ON track_items(user_id, client_id);
+CREATE INDEX IF NOT EXISTS idx_track_items_user_version ON track_items(user_id, version);
+
+-- Create sync_state table: a single row holding the last version issued
+CREATE TABLE IF NOT EXISTS sync_state (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    version INTEGER NOT NULL
+);
+
+INSERT INTO sync_state (id, version) SELECT 1, COALESCE(MAX(id), 0) FROM track_items;
+
+-- Create track_item_tombstones table: deleted track items, kept so offline
+-- clients learn about the deletion
+CREATE TABLE IF NOT EXISTS track_item_tombstones (
+    item_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id VARCHAR(64),
+    version INTEGER NOT NULL,
+    deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_track_item_tombstones_user_version ON track_item_tombstones(user_id, version);
+CREATE INDEX IF NOT EXISTS idx_track_item_tombstones_user_client_id ON track_item_tombstones(user_id, client_id);
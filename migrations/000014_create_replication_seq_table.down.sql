@@ -0,0 +1,2 @@
+-- Drop replication_seq table
+DROP TABLE IF EXISTS replication_seq;
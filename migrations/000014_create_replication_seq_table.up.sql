@@ -0,0 +1,9 @@
+-- Create replication_seq table: a single row the WAL replicator updates to
+-- write a transaction of its own, which restarts a checkpointed log at a
+-- point it controls
+CREATE TABLE IF NOT EXISTS replication_seq (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    seq INTEGER NOT NULL
+);
+
+INSERT INTO replication_seq (id, seq) VALUES (1, 0);
@@ -0,0 +1,2 @@
+-- Remove disabled_at from users
+ALTER TABLE users DROP COLUMN disabled_at;
@@ -0,0 +1,2 @@
+-- Add disabled_at to users: a disabled account can no longer log in
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Remove token_version and password_change_required from users
+ALTER TABLE users DROP COLUMN password_change_required;
+ALTER TABLE users DROP COLUMN token_version;
@@ -0,0 +1,5 @@
+-- Add token_version to users: resetting a password increments it, which
+-- revokes the tokens issued before. password_change_required is set while
+-- the user has a temporary password.
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN password_change_required BOOLEAN NOT NULL DEFAULT 0;
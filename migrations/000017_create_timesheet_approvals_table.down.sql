@@ -0,0 +1,2 @@
+-- Drop timesheet_approvals table
+DROP TABLE IF EXISTS timesheet_approvals;
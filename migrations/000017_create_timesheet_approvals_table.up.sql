@@ -0,0 +1,18 @@
+-- Create timesheet_approvals table: a supervisor's sign-off of a user's
+-- timesheet for a month, with the totals that were approved
+CREATE TABLE IF NOT EXISTS timesheet_approvals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    month VARCHAR(7) NOT NULL, -- YYYY-MM
+    approved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    items INTEGER NOT NULL DEFAULT 0,
+    working_hours DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    working_shifts DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    emergency_calls INTEGER NOT NULL DEFAULT 0,
+    holiday_calls INTEGER NOT NULL DEFAULT 0,
+    approved_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, month)
+);
+
+-- Create index for listing the approvals of a month across the team
+CREATE INDEX IF NOT EXISTS idx_timesheet_approvals_month ON timesheet_approvals(month);
@@ -0,0 +1,10 @@
+-- Create timers table: the shift a user is clocked into, at most one per
+-- user, which becomes a track item when it is stopped
+CREATE TABLE IF NOT EXISTS timers (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(100) NOT NULL,
+    emergency_call BOOLEAN NOT NULL DEFAULT 0,
+    holiday_call BOOLEAN NOT NULL DEFAULT 0,
+    working_shifts DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
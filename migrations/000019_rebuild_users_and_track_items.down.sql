@@ -0,0 +1,57 @@
+-- Rebuild users and track_items with the SERIAL ids of their first
+-- migrations, keeping the ids of the rows
+
+CREATE TABLE users_new (
+    id SERIAL PRIMARY KEY,
+    first_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    avatar VARCHAR(500),
+    login VARCHAR(100) UNIQUE NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    role VARCHAR(20) NOT NULL DEFAULT 'user',
+    deletion_scheduled_at TIMESTAMP,
+    disabled_at TIMESTAMP,
+    token_version INTEGER NOT NULL DEFAULT 0,
+    password_change_required BOOLEAN NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO users_new (id, first_name, last_name, avatar, login, password_hash, timezone, role, deletion_scheduled_at, disabled_at, token_version, password_change_required, created_at, updated_at)
+SELECT id, first_name, last_name, avatar, login, password_hash, timezone, role, deletion_scheduled_at, disabled_at, token_version, password_change_required, created_at, updated_at
+FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE INDEX IF NOT EXISTS idx_users_login ON users(login);
+
+CREATE TABLE track_items_new (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(100) NOT NULL,
+    emergency_call BOOLEAN NOT NULL DEFAULT FALSE,
+    holiday_call BOOLEAN NOT NULL DEFAULT FALSE,
+    working_hours DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    working_shifts DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    date TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    client_id VARCHAR(64),
+    version INTEGER NOT NULL DEFAULT 0,
+    field_clock TEXT NOT NULL DEFAULT '{}'
+);
+
+INSERT INTO track_items_new (id, user_id, type, emergency_call, holiday_call, working_hours, working_shifts, date, created_at, updated_at, client_id, version, field_clock)
+SELECT id, user_id, type, emergency_call, holiday_call, working_hours, working_shifts, date, created_at, updated_at, client_id, version, field_clock
+FROM track_items;
+
+DROP TABLE track_items;
+ALTER TABLE track_items_new RENAME TO track_items;
+
+CREATE INDEX IF NOT EXISTS idx_track_items_user_id ON track_items(user_id);
+CREATE INDEX IF NOT EXISTS idx_track_items_date ON track_items(date);
+CREATE INDEX IF NOT EXISTS idx_track_items_user_date ON track_items(user_id, date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_track_items_user_client_id ON track_items(user_id, client_id);
+CREATE INDEX IF NOT EXISTS idx_track_items_user_version ON track_items(user_id, version);
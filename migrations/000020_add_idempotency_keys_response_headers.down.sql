@@ -0,0 +1,7 @@
+-- Keep only the content type of stored responses
+ALTER TABLE idempotency_keys ADD COLUMN content_type VARCHAR(255) NOT NULL DEFAULT '';
+
+UPDATE idempotency_keys
+SET content_type = COALESCE(json_extract(response_headers, '$."Content-Type"[0]'), '');
+
+ALTER TABLE idempotency_keys DROP COLUMN response_headers;
@@ -0,0 +1,10 @@
+-- Store the response headers replayed with a stored response, such as the
+-- Location of a created resource, instead of only its content type.
+-- JSON object of header name to values.
+ALTER TABLE idempotency_keys ADD COLUMN response_headers TEXT NOT NULL DEFAULT '{}';
+
+UPDATE idempotency_keys
+SET response_headers = json_object('Content-Type', json_array(content_type))
+WHERE content_type != '';
+
+ALTER TABLE idempotency_keys DROP COLUMN content_type;